@@ -25,6 +25,14 @@ var abacusCmd = &cobra.Command{
 			msg.InputNotFound(errors.New("The combined analysis needs at least 2 result files to work"), "fatal")
 		}
 
+		if len(m.Abacus.Integrate) > 0 && m.Abacus.Integrate != "ratio" && m.Abacus.Integrate != "irs" {
+			msg.InputNotFound(errors.New("Unknown integration method, please use ratio or irs"), "fatal")
+		}
+
+		if m.Abacus.Rollup != "protein" && m.Abacus.Rollup != "gene" {
+			msg.InputNotFound(errors.New("Unknown roll-up level, please use protein or gene"), "fatal")
+		}
+
 		msg.Executing("Abacus", Version)
 		aba.Run(m, args)
 
@@ -54,6 +62,11 @@ func init() {
 		abacusCmd.Flags().BoolVarP(&m.Abacus.Picked, "picked", "", false, "apply the picked FDR algorithm before the protein scoring")
 		abacusCmd.Flags().BoolVarP(&m.Abacus.Unique, "uniqueonly", "", false, "report TMT quantification based on only unique peptides")
 		abacusCmd.Flags().BoolVarP(&m.Abacus.Labels, "labels", "", false, "indicates whether the data sets includes TMT labels or not")
+		abacusCmd.Flags().BoolVarP(&m.Abacus.MaxLFQ, "maxlfq", "", false, "calculate the MaxLFQ intensities from the label-free quantification")
+		abacusCmd.Flags().IntVarP(&m.Abacus.MinRatio, "minratio", "", 2, "minimum number of ion ratios for MaxLFQ")
+		abacusCmd.Flags().StringVarP(&m.Abacus.Integrate, "integrate", "", "", "integrate the isobaric plexes with the bridge ratios or with internal reference scaling (ratio, irs)")
+		abacusCmd.Flags().StringVarP(&m.Abacus.Bridge, "bridge", "", "", "bridge channel of each plex, a virtual reference is used when empty")
+		abacusCmd.Flags().StringVarP(&m.Abacus.Rollup, "rollup", "", "protein", "roll-up level of the integrated plexes (protein, gene)")
 		abacusCmd.Flags().BoolVarP(&m.Abacus.Reprint, "reprint", "", false, "create abacus reports using the Reprint format")
 	}
 
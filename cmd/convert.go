@@ -0,0 +1,67 @@
+// Package cmd Convert top level command
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
+	"philosopher/lib/sys"
+
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert Thermo RAW files to mzML or MGF",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		m.FunctionInitCheckUp()
+
+		msg.Executing("Convert ", Version)
+
+		if len(args) < 1 {
+			msg.Custom(errors.New("No input files were provided"), "fatal")
+		}
+
+		if m.Msconvert.MZBinaryEncoding != "32" && m.Msconvert.MZBinaryEncoding != "64" {
+			msg.Custom(errors.New("The m/z binary encoding must be 32 or 64"), "fatal")
+		}
+
+		if m.Msconvert.IntensityBinaryEncoding != "32" && m.Msconvert.IntensityBinaryEncoding != "64" {
+			msg.Custom(errors.New("The intensity binary encoding must be 32 or 64"), "fatal")
+		}
+
+		for _, i := range args {
+			mzn.Convert(m, i)
+		}
+
+		// store parameters on meta data
+		m.Serialize()
+
+		// clean tmp
+		met.CleanTemp(m.Temp)
+
+		msg.Done()
+		return
+	},
+}
+
+func init() {
+
+	if len(os.Args) > 1 && os.Args[1] == "convert" {
+
+		m.Restore(sys.Meta())
+
+		convertCmd.Flags().StringVarP(&m.Msconvert.Output, "output", "", "", "output directory, defaults to the directory of each input file")
+		convertCmd.Flags().StringVarP(&m.Msconvert.Format, "format", "", "mzML", "output format (mzML, mgf)")
+		convertCmd.Flags().StringVarP(&m.Msconvert.MZBinaryEncoding, "mz", "", "64", "m/z binary encoding precision (32, 64)")
+		convertCmd.Flags().StringVarP(&m.Msconvert.IntensityBinaryEncoding, "inten", "", "32", "intensity binary encoding precision (32, 64)")
+		convertCmd.Flags().BoolVarP(&m.Msconvert.NoIndex, "noindex", "", false, "do not write the mzML index")
+		convertCmd.Flags().BoolVarP(&m.Msconvert.Zlib, "zlib", "", false, "use zlib compression for the binary data")
+	}
+
+	RootCmd.AddCommand(convertCmd)
+}
@@ -48,6 +48,10 @@ var filterCmd = &cobra.Command{
 			m.Filter.Razor = false
 		}
 
+		if len(m.Filter.Score) > 0 && m.Filter.Score != "expectation" && m.Filter.Score != "hyperscore" && m.Filter.Score != "xcorr" && m.Filter.Score != "discriminant" {
+			msg.InputNotFound(errors.New("Unknown score, please use expectation, hyperscore, xcorr or discriminant"), "fatal")
+		}
+
 		m := fil.Run(m)
 
 		m.Serialize()
@@ -69,6 +73,7 @@ func init() {
 		filterCmd.Flags().StringVarP(&m.Filter.Pex, "pepxml", "", "", "pepXML file or directory containing a set of pepXML files")
 		filterCmd.Flags().StringVarP(&m.Filter.Pox, "protxml", "", "", "protXML file path")
 		filterCmd.Flags().StringVarP(&m.Filter.Tag, "tag", "", "rev_", "decoy tag")
+		filterCmd.Flags().StringVarP(&m.Filter.Score, "score", "", "", "rank the identifications by a search engine score instead of the probability (expectation, hyperscore, xcorr, discriminant)")
 		filterCmd.Flags().StringVarP(&m.Filter.Mods, "mods", "", "", "list of modifications for a stratified FDR filtering")
 		filterCmd.Flags().Float64VarP(&m.Filter.IonFDR, "ion", "", 0.01, "peptide ion FDR level")
 		filterCmd.Flags().Float64VarP(&m.Filter.PepFDR, "pep", "", 0.01, "peptide FDR level")
@@ -22,18 +22,28 @@ var freequant = &cobra.Command{
 
 		m.FunctionInitCheckUp()
 
-		m.Quantify.Format = "mzML"
 		if len(m.Quantify.Dir) < 1 {
 			msg.InputNotFound(errors.New("You need to provide the path to the mz files and the correct extension"), "fatal")
 		}
 
+		if m.Quantify.MBR == true && len(args) < 1 {
+			msg.InputNotFound(errors.New("You need to provide the donor workspaces for match-between-runs"), "fatal")
+		}
+
+		if (len(m.Quantify.Light) > 0 || len(m.Quantify.Medium) > 0) && len(m.Quantify.Heavy) == 0 {
+			msg.InputNotFound(errors.New("The MS1 labelled quantification needs the heavy label definition"), "fatal")
+		}
+
 		msg.Executing("Label-free quantification ", Version)
 
 		if strings.EqualFold(m.Quantify.Format, "mzml") {
 			m.Quantify.Format = "mzML"
 		} else if strings.EqualFold(m.Quantify.Format, "mzxml") {
-			msg.InputNotFound(errors.New("Only the mzML format is supported"), "fatal")
 			m.Quantify.Format = "mzXML"
+		} else if strings.EqualFold(m.Quantify.Format, "mgf") {
+			msg.InputNotFound(errors.New("MGF files have no MS1 scans, label-free quantification needs mzML, mzXML or raw files"), "fatal")
+		} else if strings.EqualFold(m.Quantify.Format, "raw") {
+			m.Quantify.Format = "raw"
 		} else {
 			msg.InputNotFound(errors.New("Unknown file format"), "fatal")
 		}
@@ -43,7 +53,7 @@ var freequant = &cobra.Command{
 		m.Quantify.RTWin = m.Quantify.PTWin
 
 		// run label-free quantification
-		qua.RunLabelFreeQuantification(m.Quantify)
+		qua.RunLabelFreeQuantification(m.Quantify, args, m.Database.Enz)
 
 		// store parameters on meta data
 		m.Serialize()
@@ -63,8 +73,16 @@ func init() {
 		m.Restore(sys.Meta())
 
 		freequant.Flags().StringVarP(&m.Quantify.Dir, "dir", "", "", "folder path containing the raw files")
+		freequant.Flags().StringVarP(&m.Quantify.Format, "format", "", "mzML", "spectra file format (mzML, mzXML, mgf, raw)")
+		freequant.Flags().IntVarP(&m.Quantify.Threads, "threads", "", 1, "number of runs processed in parallel")
 		freequant.Flags().Float64VarP(&m.Quantify.Tol, "tol", "", 10, "m/z tolerance in ppm")
 		freequant.Flags().Float64VarP(&m.Quantify.PTWin, "ptw", "", 0.4, "specify the time windows for the peak (minute)")
+		freequant.Flags().BoolVarP(&m.Quantify.Feature, "feature", "", false, "trace the isotope envelope and use the integrated peak area as intensity")
+		freequant.Flags().BoolVarP(&m.Quantify.MBR, "mbr", "", false, "transfer the ions identified in the workspaces given as arguments (match-between-runs)")
+		freequant.Flags().Float64VarP(&m.Quantify.MBRFDR, "mbrfdr", "", 0.01, "FDR threshold for the transferred ions")
+		freequant.Flags().StringVarP(&m.Quantify.Light, "light", "", "", "light label definition for MS1 labelled quantification, like K+0,R+0 (default unlabelled)")
+		freequant.Flags().StringVarP(&m.Quantify.Medium, "medium", "", "", "medium label definition for MS1 labelled quantification, like K+4.0251,R+6.0201")
+		freequant.Flags().StringVarP(&m.Quantify.Heavy, "heavy", "", "", "heavy label definition for MS1 labelled quantification, like K+8.0142,R+10.0083, n+ for the N-terminus or 15N")
 		freequant.Flags().BoolVarP(&m.Quantify.Isolated, "isolated", "", true, "use the isolated ion instead of the selected ion for quantification")
 		filterCmd.Flags().MarkHidden("isolated")
 	}
@@ -22,27 +22,57 @@ var labelquantCmd = &cobra.Command{
 
 		m.FunctionInitCheckUp()
 
-		m.Quantify.Format = "mzML"
-
 		if len(m.Quantify.Format) < 1 || len(m.Quantify.Dir) < 1 {
 			msg.InputNotFound(errors.New("You need to provide the path to the mz files and the correct extension"), "fatal")
 		}
 
-		if len(m.Quantify.Plex) < 1 {
+		if len(m.Quantify.Plex) < 1 && len(m.Quantify.Reagents) < 1 {
 			msg.InputNotFound(errors.New("You need to specify the experiment Plex"), "fatal")
 		}
 
+		if m.Quantify.ChanNorm != "total" && m.Quantify.ChanNorm != "median" && m.Quantify.ChanNorm != "sl" && m.Quantify.ChanNorm != "quantile" && m.Quantify.ChanNorm != "none" {
+			msg.InputNotFound(errors.New("Unknown normalization method, please use total, median, sl, quantile or none"), "fatal")
+		}
+
+		if m.Quantify.SPSMatch > 0 && m.Quantify.Level != 3 {
+			msg.Custom(errors.New("The SPS ions are only available for MS3 quantification, the SPS match filter will be disabled"), "warning")
+			m.Quantify.SPSMatch = 0
+		}
+
 		msg.Executing("Isobaric-label quantification ", Version)
 
 		if strings.EqualFold(strings.ToLower(m.Quantify.Format), "mzml") {
 			m.Quantify.Format = "mzML"
 		} else if strings.EqualFold(m.Quantify.Format, "mzxml") {
-			msg.InputNotFound(errors.New("Only the mzML format is supported"), "fatal")
 			m.Quantify.Format = "mzXML"
+		} else if strings.EqualFold(m.Quantify.Format, "mgf") {
+			m.Quantify.Format = "mgf"
+			if m.Quantify.Purity > 0 {
+				msg.Custom(errors.New("MGF files have no MS1 scans, the ion purity filter will be disabled"), "warning")
+				m.Quantify.Purity = 0
+			}
+			if m.Quantify.Interfere == true {
+				msg.Custom(errors.New("MGF files have no MS1 scans, the interference correction will be disabled"), "warning")
+				m.Quantify.Interfere = false
+			}
+		} else if strings.EqualFold(m.Quantify.Format, "raw") {
+			m.Quantify.Format = "raw"
 		} else {
 			msg.InputNotFound(errors.New("Unknown file format"), "fatal")
 		}
 
+		if (m.Quantify.SN == true || m.Quantify.MinSN > 0) && m.Quantify.Format != "raw" {
+			msg.InputNotFound(errors.New("The reporter signal-to-noise is only available from Thermo RAW files, please use --format raw"), "fatal")
+		}
+
+		if m.Quantify.SN == true || m.Quantify.MinSN > 0 {
+			msg.Custom(errors.New("The noise levels are read from an undocumented RAW layout not yet verified against the vendor values, the signal-to-noise values may differ from other tools"), "warning")
+		}
+
+		if len(m.Quantify.RefChan) > 0 && !qua.HasLabelChannel(m.Quantify, m.Quantify.RefChan) {
+			msg.InputNotFound(errors.New("The reference channel "+m.Quantify.RefChan+" is not a channel of the plex or a name on the annotation file"), "fatal")
+		}
+
 		m.Quantify = qua.RunIsobaricLabelQuantification(m.Quantify, m.Filter.Mapmods)
 
 		// store parameters on meta data
@@ -62,17 +92,30 @@ func init() {
 
 		m.Restore(sys.Meta())
 
-		labelquantCmd.Flags().StringVarP(&m.Quantify.Annot, "annot", "", "", "annotation file with custom names for the TMT channels")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.Annot, "annot", "", "", "annotation file with custom names for the TMT channels, and the optional carrier, empty or reference role of each channel")
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Plex, "plex", "", "", "number of reporter ion channels")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.Reagents, "reagents", "", "", "reagent table with the channel names and reporter ion m/z, replaces the plex definition")
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Dir, "dir", "", "", "folder path containing the raw files")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.Format, "format", "", "mzML", "spectra file format (mzML, mzXML, mgf, raw)")
+		labelquantCmd.Flags().IntVarP(&m.Quantify.Threads, "threads", "", 1, "number of runs processed in parallel")
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Brand, "brand", "", "", "isobaric labeling brand (tmt, itraq)")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.Tol, "tol", "", 20, "m/z tolerance in ppm")
 		labelquantCmd.Flags().IntVarP(&m.Quantify.Level, "level", "", 2, "ms level for the quantification")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.Purity, "purity", "", 0.5, "ion purity threshold")
+		labelquantCmd.Flags().Float64VarP(&m.Quantify.SPSMatch, "spsmatch", "", 0, "minimum fraction of SPS ions matching the peptide fragments, only for MS3 quantification")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.MinProb, "minprob", "", 0.7, "only use PSMs with the specified minimum probability score")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.RemoveLow, "removelow", "", 0.0, "ignore the lower % of PSMs based on their summed abundances. 0 means no removal, entry value must be a decimal")
 		labelquantCmd.Flags().BoolVarP(&m.Quantify.Unique, "uniqueonly", "", false, "report quantification based only on unique peptides")
 		labelquantCmd.Flags().BoolVarP(&m.Quantify.BestPSM, "bestpsm", "", false, "select the best PSMs for protein quantification")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.ChanNorm, "norm", "", "none", "channel normalization method (total, median, sl, quantile, none)")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.RefChan, "ref", "", "", "reference channel for the log2 ratios, the channel average is used when empty")
+		labelquantCmd.Flags().BoolVarP(&m.Quantify.Correct, "correct", "", false, "correct the reporter ions for the isotopic impurities of the reagents")
+		labelquantCmd.Flags().BoolVarP(&m.Quantify.Interfere, "interference", "", false, "correct the reporter ions for the co-isolated precursors using the ion purity")
+		labelquantCmd.Flags().BoolVarP(&m.Quantify.SN, "sn", "", false, "quantify the reporter ions with their signal-to-noise instead of the intensities, only for RAW files")
+		labelquantCmd.Flags().Float64VarP(&m.Quantify.CarrierCap, "carriercap", "", 0, "flag and ignore the PSMs with a sample to carrier ratio above the cap, 0 means no cap")
+		labelquantCmd.Flags().Float64VarP(&m.Quantify.MinSN, "minsn", "", 0, "minimum summed reporter signal-to-noise of the PSMs used for quantification, only for RAW files")
+		labelquantCmd.Flags().BoolVarP(&m.Quantify.QC, "qc", "", false, "report the labelling efficiency, over-labelling and channel statistics of a search with the tag as variable modification")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.Impurity, "impurity", "", "", "impurity table with the lot correction factors, replaces the default impurities")
 
 	}
 
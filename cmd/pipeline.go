@@ -71,6 +71,11 @@ var pipelineCmd = &cobra.Command{
 		meta = pip.InitializeWorkspaces(meta, p, dir, Version, Build, args)
 		//}
 
+		// Thermo RAW conversion
+		if p.Steps.RawConversion == "yes" {
+			meta = pip.Convert(meta, p, dir, args)
+		}
+
 		// Comet - MSFragger
 		if p.Steps.DatabaseSearch == "yes" {
 			meta = pip.DBSearch(meta, p, dir, args)
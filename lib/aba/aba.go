@@ -4,11 +4,14 @@ package aba
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 
+	"philosopher/lib/iso"
 	"philosopher/lib/met"
 	"philosopher/lib/msg"
+	"philosopher/lib/rep"
 )
 
 // DataSetLabelNames maps all custom names to each TMT tags
@@ -59,3 +62,48 @@ func getLabelNames(annot string) map[string]string {
 
 	return labels
 }
+
+// errorRateColumns prints the q-value and the PEP of a data set, data sets without the entry or filtered with an
+// older version are left empty
+func errorRateColumns(qValues, peps map[string]float64, dataset string, errorRates map[string]bool) string {
+
+	q, ok := qValues[dataset]
+	if !ok || errorRates[dataset] == false {
+		return "\t\t"
+	}
+
+	return fmt.Sprintf("%.6f\t%.6f\t", q, peps[dataset])
+}
+
+// datasetChannels returns the isobaric channels quantified on each data set
+func datasetChannels(datasets map[string]rep.Evidence) map[string][]iso.Channel {
+
+	var channels = make(map[string][]iso.Channel)
+
+	for k, v := range datasets {
+		for _, i := range v.PSM {
+			if len(i.Labels.Channels) > 0 {
+				channels[k] = i.Labels.Channels
+				break
+			}
+		}
+	}
+
+	return channels
+}
+
+// channelColumns formats the channel intensities, missing channels are reported as zero
+func channelColumns(labels iso.Labels, channels int) string {
+
+	var line string
+
+	for i := 0; i < channels; i++ {
+		if i < len(labels.Channels) {
+			line += fmt.Sprintf("%.4f\t", labels.Channels[i].Intensity)
+		} else {
+			line += fmt.Sprintf("%.4f\t", 0.0)
+		}
+	}
+
+	return line
+}
@@ -0,0 +1,337 @@
+// Package aba (Abacus), MaxLFQ quantification
+package aba
+
+import (
+	"math"
+	"sort"
+
+	"philosopher/lib/rep"
+	"philosopher/lib/uti"
+)
+
+// ratioEdge is the median log ratio between the intensities of two samples, a and b are the sample indexes
+type ratioEdge struct {
+	a      int
+	b      int
+	ratio  float64
+	weight float64
+}
+
+// ionIntensities collects the target ion intensities from every data set
+func ionIntensities(datasets map[string]rep.Evidence) map[string]map[string]float64 {
+
+	var ions = make(map[string]map[string]float64)
+
+	for k, v := range datasets {
+		ions[k] = make(map[string]float64)
+		for _, i := range v.Ions {
+			if i.IsDecoy == false && i.Intensity > 0 {
+				ions[k][i.IonForm] = i.Intensity
+			}
+		}
+	}
+
+	return ions
+}
+
+// normalizationFactors calculates the MaxLFQ delayed normalization. The factors minimize the overall
+// changes of the ion intensities between every pair of samples, so most ions keep their abundance
+func normalizationFactors(ions map[string]map[string]float64, names []string) map[string]float64 {
+
+	var edges []ratioEdge
+
+	for a := range names {
+		for b := a + 1; b < len(names); b++ {
+
+			var sum, count float64
+			for k, v := range ions[names[a]] {
+				if w, ok := ions[names[b]][k]; ok {
+					sum += math.Log(v) - math.Log(w)
+					count++
+				}
+			}
+
+			// the factors move the samples in the opposite direction of the ratios
+			if count > 0 {
+				edges = append(edges, ratioEdge{a, b, -sum / count, count})
+			}
+		}
+	}
+
+	x, _ := solveRatios(len(names), edges)
+
+	var factors = make(map[string]float64)
+	for i := range names {
+		factors[names[i]] = math.Exp(x[i])
+	}
+
+	return factors
+}
+
+// maxLFQ calculates the intensities of a protein, or peptide, from the normalized intensities of its ions in
+// every sample. The pairwise ratios between samples are the medians of the ion ratios, requiring at least
+// minRatio shared ions, and the intensities that best fit all ratios are scaled to keep the summed intensity.
+// Samples without a valid ratio get no intensity
+func maxLFQ(features map[string]map[string]float64, names []string, minRatio int) map[string]float64 {
+
+	var lfq = make(map[string]float64)
+	var edges []ratioEdge
+
+	if minRatio < 1 {
+		minRatio = 1
+	}
+
+	for a := range names {
+		for b := a + 1; b < len(names); b++ {
+
+			var ratios []float64
+			for k, v := range features[names[a]] {
+				if w, ok := features[names[b]][k]; ok {
+					ratios = append(ratios, math.Log(v)-math.Log(w))
+				}
+			}
+
+			if len(ratios) >= minRatio {
+				edges = append(edges, ratioEdge{a, b, uti.Median(ratios), 1})
+			}
+		}
+	}
+
+	x, groups := solveRatios(len(names), edges)
+
+	var summed = make(map[int]float64)
+	var fitted = make(map[int]float64)
+
+	for i := range names {
+		if groups[i] < 0 {
+			continue
+		}
+		for _, v := range features[names[i]] {
+			summed[groups[i]] += v
+		}
+		fitted[groups[i]] += math.Exp(x[i])
+	}
+
+	for i := range names {
+		if groups[i] < 0 {
+			continue
+		}
+		lfq[names[i]] = math.Exp(x[i]) * summed[groups[i]] / fitted[groups[i]]
+	}
+
+	return lfq
+}
+
+// solveRatios finds the log intensities best explaining the pairwise log ratios, x[a] - x[b] = ratio, by
+// weighted least squares. Each group of samples connected by ratios is solved on its own with its first
+// sample fixed at zero. The group index of the samples without any ratio is -1
+func solveRatios(n int, edges []ratioEdge) ([]float64, []int) {
+
+	var x = make([]float64, n)
+	var groups = make([]int, n)
+
+	var parent = make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	var connected = make([]bool, n)
+	for _, e := range edges {
+		parent[find(e.a)] = find(e.b)
+		connected[e.a] = true
+		connected[e.b] = true
+	}
+
+	var members = make(map[int][]int)
+	for i := 0; i < n; i++ {
+		if connected[i] {
+			members[find(i)] = append(members[find(i)], i)
+		} else {
+			groups[i] = -1
+		}
+	}
+
+	var roots []int
+	for k := range members {
+		roots = append(roots, k)
+	}
+	sort.Ints(roots)
+
+	for g, root := range roots {
+
+		group := members[root]
+
+		var pos = make(map[int]int)
+		for i, j := range group {
+			pos[j] = i
+			groups[j] = g
+		}
+
+		// normal equations of the group, the first sample is the reference
+		size := len(group) - 1
+		var matrix = make([][]float64, size)
+		for i := range matrix {
+			matrix[i] = make([]float64, size+1)
+		}
+
+		for _, e := range edges {
+
+			a, okA := pos[e.a]
+			b, okB := pos[e.b]
+			if !okA || !okB {
+				continue
+			}
+
+			if a > 0 {
+				matrix[a-1][a-1] += e.weight
+				matrix[a-1][size] += e.weight * e.ratio
+			}
+			if b > 0 {
+				matrix[b-1][b-1] += e.weight
+				matrix[b-1][size] -= e.weight * e.ratio
+			}
+			if a > 0 && b > 0 {
+				matrix[a-1][b-1] -= e.weight
+				matrix[b-1][a-1] -= e.weight
+			}
+		}
+
+		solution := gaussianElimination(matrix)
+		for i := range solution {
+			x[group[i+1]] = solution[i]
+		}
+	}
+
+	return x, groups
+}
+
+// gaussianElimination solves the linear system given as an augmented matrix, using partial pivoting
+func gaussianElimination(m [][]float64) []float64 {
+
+	n := len(m)
+
+	for c := 0; c < n; c++ {
+
+		pivot := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[pivot][c]) {
+				pivot = r
+			}
+		}
+		m[c], m[pivot] = m[pivot], m[c]
+
+		if m[c][c] == 0 {
+			continue
+		}
+
+		for r := c + 1; r < n; r++ {
+			f := m[r][c] / m[c][c]
+			for k := c; k <= n; k++ {
+				m[r][k] -= f * m[c][k]
+			}
+		}
+	}
+
+	var x = make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		if m[r][r] == 0 {
+			continue
+		}
+		sum := m[r][n]
+		for k := r + 1; k < n; k++ {
+			sum -= m[r][k] * x[k]
+		}
+		x[r] = sum / m[r][r]
+	}
+
+	return x
+}
+
+// proteinMaxLFQ calculates the MaxLFQ intensities of the combined proteins from their unique and razor
+// ions, including the ions transferred with match-between-runs
+func proteinMaxLFQ(combined rep.CombinedProteinEvidenceList, datasets map[string]rep.Evidence, names []string, minRatio int) rep.CombinedProteinEvidenceList {
+
+	ions := ionIntensities(datasets)
+	factors := normalizationFactors(ions, names)
+
+	var features = make(map[string]map[string]map[string]float64)
+
+	var add = func(protein, dataset, ion string) {
+		intensity, ok := ions[dataset][ion]
+		if !ok {
+			return
+		}
+		if _, ok := features[protein]; !ok {
+			features[protein] = make(map[string]map[string]float64)
+		}
+		if _, ok := features[protein][dataset]; !ok {
+			features[protein][dataset] = make(map[string]float64)
+		}
+		features[protein][dataset][ion] = intensity * factors[dataset]
+	}
+
+	for k, v := range datasets {
+
+		for _, i := range v.Proteins {
+			for ion, j := range i.TotalPeptideIons {
+				if j.IsUnique == true || j.IsURazor == true {
+					add(i.ProteinID, k, ion)
+				}
+			}
+		}
+
+		for _, i := range v.Ions {
+			if i.IsTransferred == true && (i.IsUnique == true || i.IsURazor == true) {
+				add(i.ProteinID, k, i.IonForm)
+			}
+		}
+	}
+
+	for i := range combined {
+		combined[i].MaxLFQ = maxLFQ(features[combined[i].ProteinID], names, minRatio)
+	}
+
+	return combined
+}
+
+// peptideMaxLFQ calculates the MaxLFQ intensities of the combined peptides from their ions
+func peptideMaxLFQ(evidences rep.CombinedPeptideEvidenceList, datasets map[string]rep.Evidence, names []string, minRatio int) rep.CombinedPeptideEvidenceList {
+
+	ions := ionIntensities(datasets)
+	factors := normalizationFactors(ions, names)
+
+	var features = make(map[string]map[string]map[string]float64)
+
+	for k, v := range datasets {
+		for _, i := range v.Ions {
+
+			intensity, ok := ions[k][i.IonForm]
+			if !ok {
+				continue
+			}
+
+			if _, ok := features[i.Sequence]; !ok {
+				features[i.Sequence] = make(map[string]map[string]float64)
+			}
+			if _, ok := features[i.Sequence][k]; !ok {
+				features[i.Sequence][k] = make(map[string]float64)
+			}
+
+			features[i.Sequence][k][i.IonForm] = intensity * factors[k]
+		}
+	}
+
+	for i := range evidences {
+		evidences[i].MaxLFQ = maxLFQ(features[evidences[i].Sequence], names, minRatio)
+	}
+
+	return evidences
+}
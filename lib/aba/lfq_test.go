@@ -0,0 +1,48 @@
+package aba
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_maxLFQ(t *testing.T) {
+
+	names := []string{"A", "B", "C", "D"}
+
+	// B has twice the abundance of A, C is missing one ion, and D shares a single ion
+	features := map[string]map[string]float64{
+		"A": {"p1": 100, "p2": 1000, "p3": 50},
+		"B": {"p1": 200, "p2": 2000, "p3": 100},
+		"C": {"p1": 100, "p2": 1000},
+		"D": {"p4": 500},
+	}
+
+	lfq := maxLFQ(features, names, 2)
+
+	if math.Abs(lfq["B"]/lfq["A"]-2) > 1e-9 || math.Abs(lfq["C"]/lfq["A"]-1) > 1e-9 {
+		t.Errorf("Unexpected ratios: %v", lfq)
+	}
+
+	if _, ok := lfq["D"]; ok {
+		t.Errorf("Sample without ratios should not be quantified: %v", lfq)
+	}
+
+	summed := 1150.0 + 2300.0 + 1100.0
+	if math.Abs(lfq["A"]+lfq["B"]+lfq["C"]-summed) > 1e-6 {
+		t.Errorf("Total intensity is %f, want %f", lfq["A"]+lfq["B"]+lfq["C"], summed)
+	}
+}
+
+func Test_normalizationFactors(t *testing.T) {
+
+	ions := map[string]map[string]float64{
+		"A": {"p1": 100, "p2": 1000, "p3": 10},
+		"B": {"p1": 300, "p2": 3000, "p3": 30},
+	}
+
+	factors := normalizationFactors(ions, []string{"A", "B"})
+
+	if math.Abs(factors["A"]/factors["B"]-3) > 1e-9 {
+		t.Errorf("Unexpected factors: %v", factors)
+	}
+}
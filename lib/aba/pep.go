@@ -40,14 +40,25 @@ func peptideLevelAbacus(m met.Data, args []string) {
 	local, _ := os.Getwd()
 	local, _ = filepath.Abs(local)
 
+	// the data sets filtered with this version store the q-values and PEPs
+	var errorRates = make(map[string]bool)
+
 	for _, i := range args {
 
+		var meta met.Data
+		meta.RestoreWithPath(i)
+
 		os.Chdir(i)
 
 		// restoring the PSMs
 		var psm rep.Evidence
 		rep.RestoreEVPSM(&psm)
 
+		// MaxLFQ works on the ion intensities
+		if m.Abacus.MaxLFQ == true {
+			rep.RestoreEVIon(&psm)
+		}
+
 		var labels DataSetLabelNames
 		labels.LabelName = make(map[string]string)
 
@@ -80,6 +91,10 @@ func peptideLevelAbacus(m met.Data, args []string) {
 
 		labelList = append(labelList, labels)
 
+		if meta.Filter.ErrorRates == true {
+			errorRates[prjName] = true
+		}
+
 		// unique list and map of datasets
 		datasets[prjName] = psm
 		names = append(names, prjName)
@@ -97,7 +112,15 @@ func peptideLevelAbacus(m met.Data, args []string) {
 
 	os.Chdir(local)
 
-	savePeptideAbacusResult(m.Temp, evidences, datasets, names, m.Abacus.Unique, false, labelList)
+	if m.Abacus.MaxLFQ == true {
+		logrus.Info("Calculating MaxLFQ intensities")
+		evidences = peptideMaxLFQ(evidences, datasets, names, m.Abacus.MinRatio)
+	}
+
+	// the retention times are placed on one scale with the stored alignment models
+	evidences = peptideRetention(evidences, datasets, commonAlignment(args))
+
+	savePeptideAbacusResult(m.Temp, evidences, datasets, names, m.Abacus.Unique, false, m.Abacus.MaxLFQ, labelList, errorRates)
 
 	return
 }
@@ -144,6 +167,11 @@ func collectPeptideDatafromExperiments(datasets map[string]rep.Evidence, decoyTa
 			var e rep.CombinedPeptideEvidence
 			e.Spc = make(map[string]int)
 			e.Intensity = make(map[string]float64)
+			e.TransferQValue = make(map[string]float64)
+			e.QValue = make(map[string]float64)
+			e.PEP = make(map[string]float64)
+			e.RetentionTime = make(map[string]float64)
+			e.MS1Labels = make(map[string]rep.MS1Labels)
 			e.AssignedMassDiffs = make(map[string]uint8)
 			e.ChargeStates = make(map[uint8]uint8)
 
@@ -172,6 +200,9 @@ func SummarizeAttributes(evidences rep.CombinedPeptideEvidenceList, datasets map
 
 		SpcMap := make(map[string]int)
 		IntMap := make(map[string]float64)
+		TransferMap := make(map[string]float64)
+		ErrorMap := make(map[string][2]float64)
+		LabelMap := make(map[string]rep.MS1Labels)
 		ModsMap := make(map[string][]string)
 
 		protIDMap := make(map[string]string)
@@ -189,6 +220,15 @@ func SummarizeAttributes(evidences rep.CombinedPeptideEvidenceList, datasets map
 
 			SpcMap[j.Sequence] = j.Spc
 			IntMap[j.Sequence] = j.Intensity
+			ErrorMap[j.Sequence] = [2]float64{j.QValue, j.PEP}
+
+			if j.IsTransferred == true {
+				TransferMap[j.Sequence] = j.TransferQValue
+			}
+
+			if j.MS1Labels.Light > 0 || j.MS1Labels.Medium > 0 || j.MS1Labels.Heavy > 0 {
+				LabelMap[j.Sequence] = j.MS1Labels
+			}
 
 			protIDMap[j.Sequence] = j.ProteinID
 			protDescMap[j.Sequence] = j.ProteinDescription
@@ -214,6 +254,19 @@ func SummarizeAttributes(evidences rep.CombinedPeptideEvidenceList, datasets map
 			if ok {
 				evidences[i].Intensity[k] = it
 			}
+			r, ok := ErrorMap[evidences[i].Sequence]
+			if ok {
+				evidences[i].QValue[k] = r[0]
+				evidences[i].PEP[k] = r[1]
+			}
+			q, ok := TransferMap[evidences[i].Sequence]
+			if ok {
+				evidences[i].TransferQValue[k] = q
+			}
+			l, ok := LabelMap[evidences[i].Sequence]
+			if ok {
+				evidences[i].MS1Labels[k] = l
+			}
 			m, ok := ModsMap[evidences[i].Sequence]
 			if ok {
 				for _, l := range m {
@@ -253,7 +306,7 @@ func SummarizeAttributes(evidences rep.CombinedPeptideEvidenceList, datasets map
 }
 
 // savePeptideAbacusResult creates a single report using 1 or more philosopher result files
-func savePeptideAbacusResult(session string, evidences rep.CombinedPeptideEvidenceList, datasets map[string]rep.Evidence, namesList []string, uniqueOnly, hasTMT bool, labelsList []DataSetLabelNames) {
+func savePeptideAbacusResult(session string, evidences rep.CombinedPeptideEvidenceList, datasets map[string]rep.Evidence, namesList []string, uniqueOnly, hasTMT, hasMaxLFQ bool, labelsList []DataSetLabelNames, errorRates map[string]bool) {
 
 	// create result file
 	output := fmt.Sprintf("%s%scombined_peptide.tsv", session, string(filepath.Separator))
@@ -265,11 +318,61 @@ func savePeptideAbacusResult(session string, evidences rep.CombinedPeptideEviden
 	}
 	defer file.Close()
 
+	// data sets quantified with match-between-runs report the q-values of the transferred peptides
+	var hasTransfers bool
+	for _, i := range evidences {
+		if len(i.TransferQValue) > 0 {
+			hasTransfers = true
+			break
+		}
+	}
+
+	// data sets quantified with MS1 labels report the labelled pair ratios
+	var hasMS1Labels bool
+	for _, i := range evidences {
+		if len(i.MS1Labels) > 0 {
+			hasMS1Labels = true
+			break
+		}
+	}
+
+	// data sets filtered with this version carry the peptide q-values and PEPs
+	var hasErrorRates bool
+	if len(errorRates) > 0 {
+		hasErrorRates = true
+	}
+
+	// data sets aligned to a common reference run report the aligned peptide retention times
+	var hasRetention bool
+	for _, i := range evidences {
+		if len(i.RetentionTime) > 0 {
+			hasRetention = true
+			break
+		}
+	}
+
 	line := "Sequence\tCharge States\tProbability\tAssigned Modifications\tGene\tProtein\tProtein ID\tProtein Description\t"
 
 	for _, i := range namesList {
 		line += fmt.Sprintf("%s Spectral Count\t", i)
 		line += fmt.Sprintf("%s Intensity\t", i)
+		if hasErrorRates == true {
+			line += fmt.Sprintf("%s q-value\t", i)
+			line += fmt.Sprintf("%s PEP\t", i)
+		}
+		if hasTransfers == true {
+			line += fmt.Sprintf("%s Transfer q-value\t", i)
+		}
+		if hasMaxLFQ == true {
+			line += fmt.Sprintf("%s MaxLFQ Intensity\t", i)
+		}
+		if hasRetention == true {
+			line += fmt.Sprintf("%s Aligned Retention\t", i)
+		}
+		if hasMS1Labels == true {
+			line += fmt.Sprintf("%s Medium/Light Ratio\t", i)
+			line += fmt.Sprintf("%s Heavy/Light Ratio\t", i)
+		}
 	}
 
 	line += "\n"
@@ -312,6 +415,29 @@ func savePeptideAbacusResult(session string, evidences rep.CombinedPeptideEviden
 
 		for _, j := range namesList {
 			line += fmt.Sprintf("%d\t%.4f\t", i.Spc[j], i.Intensity[j])
+			if hasErrorRates == true {
+				line += errorRateColumns(i.QValue, i.PEP, j, errorRates)
+			}
+			if hasTransfers == true {
+				if q, ok := i.TransferQValue[j]; ok {
+					line += fmt.Sprintf("%.4f\t", q)
+				} else {
+					line += "\t"
+				}
+			}
+			if hasMaxLFQ == true {
+				line += fmt.Sprintf("%.4f\t", i.MaxLFQ[j])
+			}
+			if hasRetention == true {
+				if t, ok := i.RetentionTime[j]; ok {
+					line += fmt.Sprintf("%.4f\t", t)
+				} else {
+					line += "\t"
+				}
+			}
+			if hasMS1Labels == true {
+				line += fmt.Sprintf("%.4f\t%.4f\t", i.MS1Labels[j].MediumRatio, i.MS1Labels[j].HeavyRatio)
+			}
 		}
 
 		line += "\n"
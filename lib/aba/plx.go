@@ -0,0 +1,372 @@
+// Package aba (Abacus), multi-plex isobaric integration
+package aba
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+	"philosopher/lib/sys"
+	"philosopher/lib/uti"
+)
+
+// integratePlexes combines the isobaric channels of every plex into one matrix of log2 values per protein, or
+// gene. The ratio method divides each PSM by its bridge channel, or by the average of its channels when
+// there is no bridge, and the irs method keeps the PSM intensities and scales the plexes to the average of
+// their references (internal reference scaling). The PSMs of each plex are rolled up with median polish
+func integratePlexes(combined rep.CombinedProteinEvidenceList, datasets map[string]rep.Evidence, names []string, method, bridge, rollup string, uniqueOnly bool) map[string]map[string][]float64 {
+
+	var integrated = make(map[string]map[string][]float64)
+
+	// only the combined proteins are integrated, the genes are the ones of the combined proteins
+	var features = make(map[string]string)
+	for _, i := range combined {
+		if rollup == "gene" {
+			if len(i.GeneNames) > 0 {
+				features[i.ProteinID] = i.GeneNames
+			}
+		} else {
+			features[i.ProteinID] = i.ProteinID
+		}
+	}
+
+	channels := datasetChannels(datasets)
+
+	var rows = make(map[string]map[string][][]float64)
+	var reference = make(map[string]int)
+
+	for _, k := range names {
+
+		reference[k] = bridgeChannel(channels[k], bridge, k)
+
+		for _, i := range datasets[k].PSM {
+
+			if i.IsDecoy == true || i.Labels.IsUsed == false || (uniqueOnly == true && i.IsUnique == false) {
+				continue
+			}
+
+			feature, ok := features[i.ProteinID]
+			if !ok {
+				continue
+			}
+
+			row := log2Channels(i.Labels, len(channels[k]))
+
+			if method == "ratio" {
+				ref := referenceValue(row, reference[k])
+				if math.IsNaN(ref) {
+					continue
+				}
+				for j := range row {
+					row[j] -= ref
+				}
+			}
+
+			if _, ok := rows[feature]; !ok {
+				rows[feature] = make(map[string][][]float64)
+			}
+			rows[feature][k] = append(rows[feature][k], row)
+		}
+	}
+
+	for feature, v := range rows {
+
+		integrated[feature] = make(map[string][]float64)
+		for k, r := range v {
+			integrated[feature][k] = medianPolish(r)
+		}
+
+		if method == "irs" {
+			scaleReferences(integrated[feature], reference)
+		}
+	}
+
+	return integrated
+}
+
+// bridgeChannel returns the index of the bridge channel, matching exactly the channel or the custom name, or
+// -1 when no bridge was given and the virtual reference is used. A plex without the given bridge is fatal
+func bridgeChannel(channels []iso.Channel, bridge, plex string) int {
+
+	if len(bridge) == 0 {
+		return -1
+	}
+
+	for i, c := range channels {
+		if c.Name == bridge || c.CustomName == bridge {
+			return i
+		}
+	}
+
+	msg.Custom(errors.New("The bridge channel "+bridge+" was not found on "+plex+", please check the annotation file"), "fatal")
+
+	return -1
+}
+
+// log2Channels returns the log2 channel intensities, the channels without signal are not a number
+func log2Channels(labels iso.Labels, channels int) []float64 {
+
+	var row = make([]float64, channels)
+
+	for i := range row {
+		row[i] = math.NaN()
+		if i < len(labels.Channels) && labels.Channels[i].Intensity > 0 {
+			row[i] = math.Log2(labels.Channels[i].Intensity)
+		}
+	}
+
+	return row
+}
+
+// referenceValue returns the value of the bridge channel, or the average of the channels when there is no
+// bridge (virtual reference)
+func referenceValue(row []float64, bridge int) float64 {
+
+	if bridge >= 0 {
+		if bridge < len(row) {
+			return row[bridge]
+		}
+		return math.NaN()
+	}
+
+	var sum, count float64
+	for _, i := range row {
+		if !math.IsNaN(i) {
+			sum += i
+			count++
+		}
+	}
+
+	if count == 0 {
+		return math.NaN()
+	}
+
+	return sum / count
+}
+
+// scaleReferences shifts the log2 values of each plex so its reference matches the average reference of all
+// plexes
+func scaleReferences(plexes map[string][]float64, reference map[string]int) {
+
+	var refs = make(map[string]float64)
+	var sum, count float64
+
+	for k, v := range plexes {
+		ref := referenceValue(v, reference[k])
+		if !math.IsNaN(ref) {
+			refs[k] = ref
+			sum += ref
+			count++
+		}
+	}
+
+	if count == 0 {
+		return
+	}
+
+	for k, ref := range refs {
+		for i := range plexes[k] {
+			plexes[k][i] += (sum / count) - ref
+		}
+	}
+
+	return
+}
+
+// finiteMedian returns the median of the values that are a number
+func finiteMedian(values []float64) float64 {
+
+	var finite []float64
+	for _, i := range values {
+		if !math.IsNaN(i) {
+			finite = append(finite, i)
+		}
+	}
+
+	if len(finite) == 0 {
+		return math.NaN()
+	}
+
+	return uti.Median(finite)
+}
+
+// medianPolish fits the rows, PSMs, and the columns, channels, of a log2 matrix with Tukey's median polish and
+// returns the overall effect plus the effect of each channel. Channels with no values are not a number
+func medianPolish(rows [][]float64) []float64 {
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	nc := len(rows[0])
+
+	var z = make([][]float64, len(rows))
+	for i := range rows {
+		z[i] = make([]float64, nc)
+		copy(z[i], rows[i])
+	}
+
+	var overall float64
+	var rowEffect = make([]float64, len(z))
+	var colEffect = make([]float64, nc)
+	var previous float64
+
+	for iteration := 0; iteration < 10; iteration++ {
+
+		for i := range z {
+			m := finiteMedian(z[i])
+			if math.IsNaN(m) {
+				continue
+			}
+			for j := range z[i] {
+				z[i][j] -= m
+			}
+			rowEffect[i] += m
+		}
+
+		if m := finiteMedian(colEffect); !math.IsNaN(m) {
+			for j := range colEffect {
+				colEffect[j] -= m
+			}
+			overall += m
+		}
+
+		for j := 0; j < nc; j++ {
+			var column = make([]float64, len(z))
+			for i := range z {
+				column[i] = z[i][j]
+			}
+			m := finiteMedian(column)
+			if math.IsNaN(m) {
+				continue
+			}
+			for i := range z {
+				z[i][j] -= m
+			}
+			colEffect[j] += m
+		}
+
+		if m := finiteMedian(rowEffect); !math.IsNaN(m) {
+			for i := range rowEffect {
+				rowEffect[i] -= m
+			}
+			overall += m
+		}
+
+		var residual float64
+		for i := range z {
+			for j := range z[i] {
+				if !math.IsNaN(z[i][j]) {
+					residual += math.Abs(z[i][j])
+				}
+			}
+		}
+
+		if math.Abs(residual-previous) <= 0.01*residual {
+			break
+		}
+		previous = residual
+	}
+
+	var effects = make([]float64, nc)
+	for j := range effects {
+		effects[j] = math.NaN()
+		for i := range rows {
+			if !math.IsNaN(rows[i][j]) {
+				effects[j] = overall + colEffect[j]
+				break
+			}
+		}
+	}
+
+	return effects
+}
+
+// saveIntegratedResult creates the integrated report with the log2 values of every plex channel
+func saveIntegratedResult(session string, integrated map[string]map[string][]float64, datasets map[string]rep.Evidence, namesList []string, method, rollup string, labelsList []DataSetLabelNames) {
+
+	output := fmt.Sprintf("%s%scombined_integrated_%s.tsv", session, string(filepath.Separator), rollup)
+
+	// create result file
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(e, "error")
+	}
+	defer file.Close()
+
+	channels := datasetChannels(datasets)
+
+	suffix := "Log2 Abundance"
+	if method == "ratio" {
+		suffix = "Log2 Ratio"
+	}
+
+	line := "Protein ID\t"
+	if rollup == "gene" {
+		line = "Gene\t"
+	}
+
+	for _, i := range namesList {
+
+		for _, c := range channels[i] {
+			line += fmt.Sprintf("%s %s %s\t", i, c.Name, suffix)
+		}
+
+		for _, j := range labelsList {
+			if j.Name == i {
+				for k, v := range j.LabelName {
+					before := fmt.Sprintf("%s %s %s", i, k, suffix)
+					after := fmt.Sprintf("%s %s", v, suffix)
+					line = strings.Replace(line, before, after, -1)
+				}
+			}
+		}
+	}
+
+	line += "\n"
+	_, e = io.WriteString(file, line)
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	var features []string
+	for k := range integrated {
+		features = append(features, k)
+	}
+	sort.Strings(features)
+
+	for _, i := range features {
+
+		line = fmt.Sprintf("%s\t", i)
+
+		for _, j := range namesList {
+			v := integrated[i][j]
+			for k := range channels[j] {
+				if k < len(v) && !math.IsNaN(v[k]) {
+					line += fmt.Sprintf("%.4f\t", v[k])
+				} else {
+					line += "NA\t"
+				}
+			}
+		}
+
+		line += "\n"
+		_, e := io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
@@ -0,0 +1,59 @@
+package aba
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/iso"
+)
+
+func Test_medianPolish(t *testing.T) {
+
+	// two PSMs with the same channel profile and a missing value
+	rows := [][]float64{
+		{10, 11, 12},
+		{8, 9, math.NaN()},
+	}
+
+	got := medianPolish(rows)
+	if math.Abs((got[1]-got[0])-1) > 1e-9 || math.Abs((got[2]-got[1])-1) > 1e-9 {
+		t.Errorf("channel effects are %v, want steps of 1", got)
+	}
+
+	got = medianPolish([][]float64{{1, math.NaN()}})
+	if !math.IsNaN(got[1]) {
+		t.Errorf("channel without values is %f, want NaN", got[1])
+	}
+}
+
+func Test_scaleReferences(t *testing.T) {
+
+	plexes := map[string][]float64{
+		"a": {10, 12},
+		"b": {14, 15},
+	}
+
+	// the first channel is the bridge of both plexes
+	scaleReferences(plexes, map[string]int{"a": 0, "b": 0})
+
+	if plexes["a"][0] != 12 || plexes["b"][0] != 12 || plexes["a"][1] != 14 || plexes["b"][1] != 13 {
+		t.Errorf("scaled plexes are %v", plexes)
+	}
+}
+
+func Test_bridgeChannel(t *testing.T) {
+
+	channels := []iso.Channel{{Name: "126", CustomName: "pool_1"}, {Name: "127N", CustomName: "pool"}}
+
+	if i := bridgeChannel(channels, "pool", "a"); i != 1 {
+		t.Errorf("bridge channel is %d, want the exact custom name match 1", i)
+	}
+
+	if i := bridgeChannel(channels, "126", "a"); i != 0 {
+		t.Errorf("bridge channel is %d, want the channel name match 0", i)
+	}
+
+	if i := bridgeChannel(channels, "", "a"); i != -1 {
+		t.Errorf("bridge channel is %d without a bridge, want -1", i)
+	}
+}
@@ -46,6 +46,9 @@ func proteinLevelAbacus(m met.Data, args []string) {
 	// recover all files
 	logrus.Info("Restoring protein results")
 
+	// the data sets filtered with this version store the q-values and PEPs
+	var errorRates = make(map[string]bool)
+
 	for _, i := range args {
 
 		// restoring the database
@@ -93,6 +96,13 @@ func proteinLevelAbacus(m met.Data, args []string) {
 
 		labelList = append(labelList, labels)
 
+		var meta met.Data
+		meta.RestoreWithPath(i)
+
+		if meta.Filter.ErrorRates == true {
+			errorRates[prjName] = true
+		}
+
 		// unique list and map of datasets
 		datasets[prjName] = e
 		names = append(names, prjName)
@@ -134,17 +144,37 @@ func proteinLevelAbacus(m met.Data, args []string) {
 	logrus.Info("Processing intensities")
 	evidences = sumProteinIntensities(evidences, datasets)
 
+	if m.Abacus.MaxLFQ == true {
+		logrus.Info("Calculating MaxLFQ intensities")
+		evidences = proteinMaxLFQ(evidences, datasets, names, m.Abacus.MinRatio)
+	}
+
 	// collect TMT labels
 	if m.Abacus.Labels == true {
 		evidences = getProteinLabelIntensities(evidences, datasets)
 	}
 
+	if m.Abacus.Labels == true && len(m.Abacus.Integrate) > 0 {
+		logrus.Info("Integrating the isobaric plexes")
+
+		rollup := m.Abacus.Rollup
+		if rollup != "gene" {
+			rollup = "protein"
+		}
+
+		integrated := integratePlexes(evidences, datasets, names, m.Abacus.Integrate, m.Abacus.Bridge, rollup, m.Abacus.Unique)
+		saveIntegratedResult(m.Temp, integrated, datasets, names, m.Abacus.Integrate, rollup, labelList)
+	}
+
 	if m.Abacus.Labels == true {
-		saveProteinAbacusResult(m.Temp, evidences, datasets, names, m.Abacus.Unique, true, labelList)
+		saveProteinAbacusResult(m.Temp, evidences, datasets, names, m.Abacus.Unique, true, m.Abacus.MaxLFQ, labelList, errorRates)
 	} else {
-		saveProteinAbacusResult(m.Temp, evidences, datasets, names, m.Abacus.Unique, false, labelList)
+		saveProteinAbacusResult(m.Temp, evidences, datasets, names, m.Abacus.Unique, false, m.Abacus.MaxLFQ, labelList, errorRates)
 	}
 
+	// the localized modification sites are combined when the data sets have PTMProphet results
+	saveSiteAbacusResult(m.Temp, datasets, names, m.Abacus.Labels, labelList)
+
 	if m.Abacus.Reprint == true {
 		logrus.Info("Creating Reprint reports")
 		saveReprintSpCResults(m.Temp, evidences, datasets, names, reprintLabels, m.Abacus.Unique, false, labelList)
@@ -197,6 +227,14 @@ func processProteinCombinedFile(a met.Abacus, database dat.Base) rep.CombinedPro
 				ce.TotalIntensity = make(map[string]float64)
 				ce.UniqueIntensity = make(map[string]float64)
 				ce.UrazorIntensity = make(map[string]float64)
+				ce.TransferredIons = make(map[string]int)
+				ce.Top3Intensity = make(map[string]float64)
+				ce.IBAQ = make(map[string]float64)
+				ce.NSAF = make(map[string]float64)
+				ce.EmPAI = make(map[string]float64)
+				ce.QValue = make(map[string]float64)
+				ce.PEP = make(map[string]float64)
+				ce.MS1Labels = make(map[string]rep.MS1Labels)
 
 				ce.TotalLabels = make(map[string]iso.Labels)
 				ce.UniqueLabels = make(map[string]iso.Labels)
@@ -249,6 +287,10 @@ func getProteinSpectralCounts(combined rep.CombinedProteinEvidenceList, datasets
 					combined[i].UniqueSpc[k] = j.UniqueSpC
 					combined[i].TotalSpc[k] = j.TotalSpC
 					combined[i].UrazorSpc[k] = j.URazorSpC
+					combined[i].NSAF[k] = j.NSAF
+					combined[i].EmPAI[k] = j.EmPAI
+					combined[i].QValue[k] = j.QValue
+					combined[i].PEP[k] = j.PEP
 					break
 				}
 			}
@@ -286,8 +328,12 @@ func sumProteinIntensities(combined rep.CombinedProteinEvidenceList, datasets ma
 	for k, v := range datasets {
 
 		var ions = make(map[string]float64)
+		var transferred = make(map[string]int)
 		for _, i := range v.Ions {
 			ions[i.IonForm] = i.Intensity
+			if i.IsTransferred == true {
+				transferred[i.ProteinID]++
+			}
 		}
 
 		for _, i := range combined {
@@ -296,6 +342,10 @@ func sumProteinIntensities(combined rep.CombinedProteinEvidenceList, datasets ma
 					i.TotalIntensity[k] = v.Proteins[j].TotalIntensity
 					i.UniqueIntensity[k] = v.Proteins[j].UniqueIntensity
 					i.UrazorIntensity[k] = v.Proteins[j].URazorIntensity
+					i.TransferredIons[k] = transferred[i.ProteinID]
+					i.Top3Intensity[k] = v.Proteins[j].Top3Intensity
+					i.IBAQ[k] = v.Proteins[j].IBAQ
+					i.MS1Labels[k] = v.Proteins[j].MS1Labels
 					break
 				}
 			}
@@ -307,7 +357,7 @@ func sumProteinIntensities(combined rep.CombinedProteinEvidenceList, datasets ma
 }
 
 // saveProteinAbacusResult creates a single report using 1 or more philosopher result files
-func saveProteinAbacusResult(session string, evidences rep.CombinedProteinEvidenceList, datasets map[string]rep.Evidence, namesList []string, uniqueOnly, hasTMT bool, labelsList []DataSetLabelNames) {
+func saveProteinAbacusResult(session string, evidences rep.CombinedProteinEvidenceList, datasets map[string]rep.Evidence, namesList []string, uniqueOnly, hasTMT, hasMaxLFQ bool, labelsList []DataSetLabelNames, errorRates map[string]bool) {
 
 	// create result file
 	output := fmt.Sprintf("%s%scombined_protein.tsv", session, string(filepath.Separator))
@@ -319,6 +369,45 @@ func saveProteinAbacusResult(session string, evidences rep.CombinedProteinEviden
 	}
 	defer file.Close()
 
+	// data sets quantified with match-between-runs report the number of transferred ions
+	var hasTransfers bool
+	for _, i := range evidences {
+		for _, j := range i.TransferredIons {
+			if j > 0 {
+				hasTransfers = true
+			}
+		}
+	}
+
+	// each data set reports the channels of the plex it was quantified with
+	channels := datasetChannels(datasets)
+
+	// data sets processed with this version carry the absolute protein abundances
+	var hasAbundances bool
+	for _, i := range evidences {
+		for _, j := range i.NSAF {
+			if j > 0 {
+				hasAbundances = true
+			}
+		}
+	}
+
+	// data sets quantified with MS1 labels report the labelled pair ratios
+	var hasMS1Labels bool
+	for _, i := range evidences {
+		for _, j := range i.MS1Labels {
+			if j.MediumRatio > 0 || j.HeavyRatio > 0 {
+				hasMS1Labels = true
+			}
+		}
+	}
+
+	// data sets filtered with this version carry the protein q-values and PEPs
+	var hasErrorRates bool
+	if len(errorRates) > 0 {
+		hasErrorRates = true
+	}
+
 	line := "Protein Group\tSubGroup\tProtein\tProtein ID\tEntry Name\tGene Names\tProtein Length\tCoverage\tOrganism\tProtein Existence\tDescription\tProtein Probability\tTop Peptide Probability\tUnique Stripped Peptides\tSummarized Total Spectral Count\tSummarized Unique Spectral Count\tSummarized Razor Spectral Count\t"
 
 	for _, i := range namesList {
@@ -328,20 +417,33 @@ func saveProteinAbacusResult(session string, evidences rep.CombinedProteinEviden
 		line += fmt.Sprintf("%s Total Intensity\t", i)
 		line += fmt.Sprintf("%s Unique Intensity\t", i)
 		line += fmt.Sprintf("%s Razor Intensity\t", i)
+		if hasErrorRates == true {
+			line += fmt.Sprintf("%s q-value\t", i)
+			line += fmt.Sprintf("%s PEP\t", i)
+		}
+		if hasTransfers == true {
+			line += fmt.Sprintf("%s Transferred Ions\t", i)
+		}
+		if hasMaxLFQ == true {
+			line += fmt.Sprintf("%s MaxLFQ Intensity\t", i)
+		}
+		if hasAbundances == true {
+			line += fmt.Sprintf("%s Top3 Intensity\t", i)
+			line += fmt.Sprintf("%s iBAQ\t", i)
+			line += fmt.Sprintf("%s NSAF\t", i)
+			line += fmt.Sprintf("%s emPAI\t", i)
+		}
+		if hasMS1Labels == true {
+			line += fmt.Sprintf("%s Medium/Light Ratio\t", i)
+			line += fmt.Sprintf("%s Heavy/Light Ratio\t", i)
+		}
 	}
 
 	if hasTMT == true {
 		for _, i := range namesList {
-			line += fmt.Sprintf("%s 126 Abundance\t", i)
-			line += fmt.Sprintf("%s 127N Abundance\t", i)
-			line += fmt.Sprintf("%s 127C Abundance\t", i)
-			line += fmt.Sprintf("%s 128N Abundance\t", i)
-			line += fmt.Sprintf("%s 128C Abundance\t", i)
-			line += fmt.Sprintf("%s 129N Abundance\t", i)
-			line += fmt.Sprintf("%s 129C Abundance\t", i)
-			line += fmt.Sprintf("%s 130N Abundance\t", i)
-			line += fmt.Sprintf("%s 130C Abundance\t", i)
-			line += fmt.Sprintf("%s 131N Abundance\t", i)
+			for _, c := range channels[i] {
+				line += fmt.Sprintf("%s %s Abundance\t", i, c.Name)
+			}
 
 			for _, j := range labelsList {
 				if j.Name == i {
@@ -420,38 +522,29 @@ func saveProteinAbacusResult(session string, evidences rep.CombinedProteinEviden
 
 		for _, j := range namesList {
 			line += fmt.Sprintf("%d\t%d\t%d\t%6.f\t%6.f\t%6.f\t", i.TotalSpc[j], i.UniqueSpc[j], i.UrazorSpc[j], i.TotalIntensity[j], i.UniqueIntensity[j], i.UrazorIntensity[j])
+			if hasErrorRates == true {
+				line += errorRateColumns(i.QValue, i.PEP, j, errorRates)
+			}
+			if hasTransfers == true {
+				line += fmt.Sprintf("%d\t", i.TransferredIons[j])
+			}
+			if hasMaxLFQ == true {
+				line += fmt.Sprintf("%6.f\t", i.MaxLFQ[j])
+			}
+			if hasAbundances == true {
+				line += fmt.Sprintf("%6.f\t%6.f\t%.6f\t%.4f\t", i.Top3Intensity[j], i.IBAQ[j], i.NSAF[j], i.EmPAI[j])
+			}
+			if hasMS1Labels == true {
+				line += fmt.Sprintf("%.4f\t%.4f\t", i.MS1Labels[j].MediumRatio, i.MS1Labels[j].HeavyRatio)
+			}
 		}
 
 		if hasTMT == true {
-			if uniqueOnly == true {
-				for _, j := range namesList {
-					line += fmt.Sprintf("%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t",
-						i.UniqueLabels[j].Channel1.Intensity,
-						i.UniqueLabels[j].Channel2.Intensity,
-						i.UniqueLabels[j].Channel3.Intensity,
-						i.UniqueLabels[j].Channel4.Intensity,
-						i.UniqueLabels[j].Channel5.Intensity,
-						i.UniqueLabels[j].Channel6.Intensity,
-						i.UniqueLabels[j].Channel7.Intensity,
-						i.UniqueLabels[j].Channel8.Intensity,
-						i.UniqueLabels[j].Channel9.Intensity,
-						i.UniqueLabels[j].Channel10.Intensity,
-					)
-				}
-			} else {
-				for _, j := range namesList {
-					line += fmt.Sprintf("%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t",
-						i.URazorLabels[j].Channel1.Intensity,
-						i.URazorLabels[j].Channel2.Intensity,
-						i.URazorLabels[j].Channel3.Intensity,
-						i.URazorLabels[j].Channel4.Intensity,
-						i.URazorLabels[j].Channel5.Intensity,
-						i.URazorLabels[j].Channel6.Intensity,
-						i.URazorLabels[j].Channel7.Intensity,
-						i.URazorLabels[j].Channel8.Intensity,
-						i.URazorLabels[j].Channel9.Intensity,
-						i.URazorLabels[j].Channel10.Intensity,
-					)
+			for _, j := range namesList {
+				if uniqueOnly == true {
+					line += channelColumns(i.UniqueLabels[j], len(channels[j]))
+				} else {
+					line += channelColumns(i.URazorLabels[j], len(channels[j]))
 				}
 			}
 		}
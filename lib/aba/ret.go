@@ -0,0 +1,65 @@
+package aba
+
+import (
+	"strings"
+
+	"philosopher/lib/aln"
+	"philosopher/lib/rep"
+	"philosopher/lib/uti"
+)
+
+// commonAlignment restores the alignment models of every data set and keeps the one placing the most runs on
+// its reference, the data sets quantified with match-between-runs store the models of their donor runs
+func commonAlignment(paths []string) aln.Alignment {
+
+	var common = aln.New()
+
+	for _, i := range paths {
+
+		var a = aln.New()
+		a.RestoreWithPath(i)
+
+		if len(a.Models) > len(common.Models) {
+			common = a
+		}
+	}
+
+	return common
+}
+
+// peptideRetention sets the median retention time of the target PSMs of each peptide and data set on the
+// common reference run, the PSMs from runs without a model are left out
+func peptideRetention(evidences rep.CombinedPeptideEvidenceList, datasets map[string]rep.Evidence, alignment aln.Alignment) rep.CombinedPeptideEvidenceList {
+
+	// a single run has nothing to be aligned to
+	if len(alignment.Models) < 2 {
+		return evidences
+	}
+
+	for k, v := range datasets {
+
+		var times = make(map[string][]float64)
+
+		for _, i := range v.PSM {
+
+			if i.IsDecoy == true {
+				continue
+			}
+
+			model, ok := alignment.Models[strings.Split(i.Spectrum, ".")[0]]
+			if !ok {
+				continue
+			}
+
+			times[i.Peptide] = append(times[i.Peptide], model.Align(i.RetentionTime))
+		}
+
+		for i := range evidences {
+			if t, ok := times[evidences[i].Sequence]; ok {
+				evidences[i].RetentionTime[k] = uti.Median(t)
+			}
+		}
+	}
+
+	return evidences
+}
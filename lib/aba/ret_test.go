@@ -0,0 +1,36 @@
+package aba
+
+import (
+	"testing"
+
+	"philosopher/lib/aln"
+	"philosopher/lib/rep"
+)
+
+func Test_peptideRetention(t *testing.T) {
+
+	alignment := aln.Alignment{Reference: "a", Models: map[string]aln.Model{"a": {}, "b": {X: []float64{100}, Y: []float64{110}}}}
+
+	datasets := map[string]rep.Evidence{
+		"x": {PSM: rep.PSMEvidenceList{{Spectrum: "a.1.1.2", Peptide: "PEPTIDE", RetentionTime: 100}}},
+		"y": {PSM: rep.PSMEvidenceList{
+			{Spectrum: "b.1.1.2", Peptide: "PEPTIDE", RetentionTime: 90},
+			{Spectrum: "b.2.2.2", Peptide: "PEPTIDE", RetentionTime: 94},
+			{Spectrum: "c.1.1.2", Peptide: "PEPTIDE", RetentionTime: 500},
+		}},
+	}
+
+	evidences := rep.CombinedPeptideEvidenceList{{Sequence: "PEPTIDE", RetentionTime: make(map[string]float64)}}
+	evidences = peptideRetention(evidences, datasets, alignment)
+
+	// the second data set is shifted by 10 seconds, and the run without a model is left out
+	if evidences[0].RetentionTime["x"] != 100 || evidences[0].RetentionTime["y"] != 102 {
+		t.Errorf("aligned retention times are %v, want 100 and 102", evidences[0].RetentionTime)
+	}
+
+	evidences = rep.CombinedPeptideEvidenceList{{Sequence: "PEPTIDE", RetentionTime: make(map[string]float64)}}
+	evidences = peptideRetention(evidences, datasets, aln.Alignment{Models: map[string]aln.Model{"a": {}}})
+	if len(evidences[0].RetentionTime) != 0 {
+		t.Errorf("a single run has aligned retention times %v", evidences[0].RetentionTime)
+	}
+}
@@ -0,0 +1,133 @@
+// Package aba (Abacus), site level
+package aba
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+	"philosopher/lib/sys"
+)
+
+// combineSites collects the site tables of every data set, keyed by modification and by protein site
+func combineSites(datasets map[string]rep.Evidence) map[string]map[string]map[string]rep.SiteEvidence {
+
+	var combined = make(map[string]map[string]map[string]rep.SiteEvidence)
+
+	for k, v := range datasets {
+		for name, sites := range v.Sites() {
+
+			if _, ok := combined[name]; !ok {
+				combined[name] = make(map[string]map[string]rep.SiteEvidence)
+			}
+
+			for _, i := range sites {
+				key := fmt.Sprintf("%s#%d", i.ProteinID, i.Position)
+				if _, ok := combined[name][key]; !ok {
+					combined[name][key] = make(map[string]rep.SiteEvidence)
+				}
+				combined[name][key][k] = i
+			}
+		}
+	}
+
+	return combined
+}
+
+// saveSiteAbacusResult creates the combined site table of each localized modification, with the localization
+// probability, spectral count, intensity and isobaric channels of each data set
+func saveSiteAbacusResult(session string, datasets map[string]rep.Evidence, namesList []string, hasTMT bool, labelsList []DataSetLabelNames) {
+
+	channels := datasetChannels(datasets)
+
+	for name, sites := range combineSites(datasets) {
+
+		output := fmt.Sprintf("%s%scombined_site_%s.tsv", session, string(filepath.Separator), name)
+
+		file, e := os.Create(output)
+		if e != nil {
+			msg.WriteFile(e, "error")
+		}
+
+		line := "Protein ID\tProtein\tGene\tPosition\tAmino Acid\tSequence Window\tBest Localization Probability\t"
+
+		for _, i := range namesList {
+			line += fmt.Sprintf("%s Localization Probability\t", i)
+			line += fmt.Sprintf("%s Spectral Count\t", i)
+			line += fmt.Sprintf("%s Intensity\t", i)
+		}
+
+		if hasTMT == true {
+			for _, i := range namesList {
+				for _, c := range channels[i] {
+					line += fmt.Sprintf("%s %s Abundance\t", i, c.Name)
+				}
+
+				for _, j := range labelsList {
+					if j.Name == i {
+						for k, v := range j.LabelName {
+							before := fmt.Sprintf("%s %s Abundance", i, k)
+							after := fmt.Sprintf("%s Abundance", v)
+							line = strings.Replace(line, before, after, -1)
+						}
+					}
+				}
+			}
+		}
+
+		line += "\n"
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+
+		// each site is described by its best localized data set
+		var order rep.SiteEvidenceList
+		for _, v := range sites {
+			var site rep.SiteEvidence
+			for _, i := range v {
+				if i.Probability > site.Probability || len(site.ProteinID) == 0 {
+					site = i
+				}
+			}
+			order = append(order, site)
+		}
+		sort.Sort(order)
+
+		for _, site := range order {
+
+			k := fmt.Sprintf("%s#%d", site.ProteinID, site.Position)
+
+			line := fmt.Sprintf("%s\t%s\t%s\t%d\t%s\t%s\t%.4f\t", site.ProteinID, site.Protein, site.Gene, site.Position, site.AminoAcid, site.Window, site.Probability)
+
+			for _, i := range namesList {
+				v := sites[k][i]
+				line += fmt.Sprintf("%.4f\t%d\t%.4f\t", v.Probability, v.Spc, v.Intensity)
+			}
+
+			if hasTMT == true {
+				for _, i := range namesList {
+					line += channelColumns(sites[k][i].Labels, len(channels[i]))
+				}
+			}
+
+			line += "\n"
+			_, e = io.WriteString(file, line)
+			if e != nil {
+				msg.WriteToFile(e, "fatal")
+			}
+		}
+
+		file.Close()
+
+		// copy to work directory
+		sys.CopyFile(output, filepath.Base(output))
+	}
+
+	return
+}
@@ -0,0 +1,47 @@
+package aba
+
+import (
+	"testing"
+
+	"philosopher/lib/rep"
+)
+
+func Test_combineSites(t *testing.T) {
+
+	var a, b rep.Evidence
+
+	a.Proteins = rep.ProteinEvidenceList{{ProteinID: "P1", Sequence: "MAAPEPSTIDEKGG"}}
+	a.PSM = rep.PSMEvidenceList{
+		{Spectrum: "a.1.1.2", Peptide: "PEPSTIDEK", IonForm: "PEPSTIDEK#2", ProteinID: "P1", Probability: 0.99, Intensity: 100, LocalizedPTMMassDiff: map[string]string{"PTMProphet_STY79.9663": "PEPS(0.900)T(0.100)IDEK"}},
+		{Spectrum: "a.2.2.2", Peptide: "PEPSTIDEK", IonForm: "PEPSTIDEK#2", ProteinID: "P1", Probability: 0.95, Intensity: 50, LocalizedPTMMassDiff: map[string]string{"PTMProphet_STY79.9663": "PEPS(0.700)T(0.300)IDEK"}},
+	}
+
+	b.Proteins = a.Proteins
+	b.PSM = rep.PSMEvidenceList{
+		{Spectrum: "b.1.1.2", Peptide: "PEPSTIDEK", IonForm: "PEPSTIDEK#2", ProteinID: "P1", Probability: 0.99, Intensity: 10, LocalizedPTMMassDiff: map[string]string{"PTMProphet_STY79.9663": "PEPS(0.200)T(0.800)IDEK"}},
+	}
+
+	combined := combineSites(map[string]rep.Evidence{"a": a, "b": b})
+
+	sites, ok := combined["STY79.9663"]
+	if !ok || len(sites) != 2 {
+		t.Fatalf("got %d phospho sites, want 2", len(sites))
+	}
+
+	s := sites["P1#7"]["a"]
+	if s.Spc != 2 || s.Probability != 0.9 || s.BestPSM != "a.1.1.2" || s.Intensity != 100 || s.AminoAcid != "S" {
+		t.Errorf("site S7 of data set a is %+v", s)
+	}
+
+	if s.Window != "_MAAPEPSTIDEKGG" {
+		t.Errorf("sequence window is %s, want _MAAPEPSTIDEKGG", s.Window)
+	}
+
+	if _, ok := sites["P1#8"]["a"]; ok {
+		t.Errorf("site T8 is not localized on data set a")
+	}
+
+	if s := sites["P1#8"]["b"]; s.Spc != 1 || s.AminoAcid != "T" {
+		t.Errorf("site T8 of data set b is %+v", s)
+	}
+}
@@ -0,0 +1,363 @@
+// Package aln (Alignment), retention time alignment between runs
+package aln
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+	"philosopher/lib/sys"
+	"philosopher/lib/uti"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack"
+)
+
+const (
+	// minShared is the number of shared ions needed for the nonlinear fit, below it a linear model is used
+	minShared = 10
+	// loessSpan is the fraction of the shared ions used by each local regression
+	loessSpan = 0.3
+	// knots is the number of points describing the piecewise-linear model
+	knots = 50
+)
+
+// Alignment keeps the models mapping the retention times of every run to the reference run
+type Alignment struct {
+	Reference string
+	Models    map[string]Model
+}
+
+// Model is a monotonic piecewise-linear mapping between two retention time scales, in seconds.
+// An empty model is the identity, and a single knot is a constant shift
+type Model struct {
+	X []float64
+	Y []float64
+}
+
+// New constructor
+func New() Alignment {
+
+	var self Alignment
+
+	self.Models = make(map[string]Model)
+
+	return self
+}
+
+// Run aligns the runs in the workspace to the run with the largest number of identified ions. The models
+// are fitted on the most probable target PSM of the ions shared with the reference, and stored in the
+// workspace. The aligned retention times are set on the PSMs, and on the ions as the median of their PSMs,
+// when the workspace has more than one run. The models of the runs from other workspaces, placed with
+// match-between-runs, are kept while the reference does not change
+func (a *Alignment) Run(evi rep.Evidence) rep.Evidence {
+
+	logrus.Info("Aligning retention times")
+
+	var runs = make(map[string]map[string]rep.PSMEvidence)
+
+	for _, i := range evi.PSM {
+
+		if i.IsDecoy == true {
+			continue
+		}
+
+		run := strings.Split(i.Spectrum, ".")[0]
+		if _, ok := runs[run]; !ok {
+			runs[run] = make(map[string]rep.PSMEvidence)
+		}
+
+		if b, ok := runs[run][i.IonForm]; !ok || i.Probability > b.Probability {
+			runs[run][i.IonForm] = i
+		}
+	}
+
+	var names []string
+	for k := range runs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	previous := a.Reference
+	a.Reference = ""
+
+	for _, i := range names {
+		if len(a.Reference) == 0 || len(runs[i]) > len(runs[a.Reference]) {
+			a.Reference = i
+		}
+	}
+
+	if a.Reference != previous {
+		a.Models = make(map[string]Model)
+	}
+
+	for _, i := range names {
+
+		if i == a.Reference {
+			a.Models[i] = Model{}
+			continue
+		}
+
+		var x, y []float64
+		for k, v := range runs[i] {
+			if ref, ok := runs[a.Reference][k]; ok {
+				x = append(x, v.RetentionTime)
+				y = append(y, ref.RetentionTime)
+			}
+		}
+
+		a.Models[i] = Fit(x, y)
+
+		logrus.Info("Aligned ", i, " to ", a.Reference, " using ", len(x), " shared ions")
+	}
+
+	// a single run is its own reference, there is nothing to align
+	aligned := len(names) > 1
+
+	var ions = make(map[string][]float64)
+
+	for i := range evi.PSM {
+		evi.PSM[i].AlignedRetentionTime = 0
+		if aligned == true {
+			run := strings.Split(evi.PSM[i].Spectrum, ".")[0]
+			evi.PSM[i].AlignedRetentionTime = a.Models[run].Align(evi.PSM[i].RetentionTime)
+			ions[evi.PSM[i].IonForm] = append(ions[evi.PSM[i].IonForm], evi.PSM[i].AlignedRetentionTime)
+		}
+	}
+
+	for i := range evi.Ions {
+		evi.Ions[i].AlignedRetentionTime = 0
+		if v, ok := ions[evi.Ions[i].IonForm]; ok {
+			evi.Ions[i].AlignedRetentionTime = uti.Median(v)
+		}
+	}
+
+	a.Serialize()
+
+	return evi
+}
+
+// Fit builds the model mapping the x retention times to the y ones. The curve is estimated with a local
+// linear regression (LOESS) evaluated on evenly spaced knots, and forced to be monotonic
+func Fit(x, y []float64) Model {
+
+	var m Model
+
+	if len(x) == 0 {
+		return m
+	}
+
+	var pairs = make([][2]float64, len(x))
+	for i := range x {
+		pairs[i] = [2]float64{x[i], y[i]}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+	var xs = make([]float64, len(pairs))
+	var ys = make([]float64, len(pairs))
+	for i := range pairs {
+		xs[i] = pairs[i][0]
+		ys[i] = pairs[i][1]
+	}
+
+	low, high := xs[0], xs[len(xs)-1]
+
+	// a constant shift when the times cannot support a slope
+	if len(xs) < 2 || high == low {
+		var shifts = make([]float64, len(xs))
+		for i := range xs {
+			shifts[i] = ys[i] - xs[i]
+		}
+		m.X = []float64{xs[0]}
+		m.Y = []float64{xs[0] + uti.Median(shifts)}
+		return m
+	}
+
+	if len(xs) < minShared {
+		slope, intercept := linear(xs, ys, nil)
+		m.X = []float64{low, high}
+		m.Y = []float64{slope*low + intercept, slope*high + intercept}
+		return m
+	}
+
+	span := int(math.Ceil(loessSpan * float64(len(xs))))
+	if span < minShared {
+		span = minShared
+	}
+
+	for k := 0; k < knots; k++ {
+		at := low + (high-low)*float64(k)/float64(knots-1)
+		m.X = append(m.X, at)
+		m.Y = append(m.Y, loess(xs, ys, at, span))
+	}
+
+	for k := 1; k < len(m.Y); k++ {
+		if m.Y[k] < m.Y[k-1] {
+			m.Y[k] = m.Y[k-1]
+		}
+	}
+
+	return m
+}
+
+// Align maps a retention time to the reference scale
+func (m Model) Align(rt float64) float64 {
+	return interpolate(m.X, m.Y, rt)
+}
+
+// Inverse maps a retention time from the reference scale back to the run
+func (m Model) Inverse(rt float64) float64 {
+	return interpolate(m.Y, m.X, rt)
+}
+
+// interpolate evaluates the piecewise-linear function, outside of the knots the closest offset is kept
+func interpolate(xs, ys []float64, v float64) float64 {
+
+	if len(xs) == 0 {
+		return v
+	}
+
+	if v <= xs[0] {
+		return v + ys[0] - xs[0]
+	}
+
+	n := len(xs) - 1
+	if v >= xs[n] {
+		return v + ys[n] - xs[n]
+	}
+
+	i := sort.SearchFloat64s(xs, v)
+	if xs[i] == xs[i-1] {
+		return ys[i]
+	}
+
+	return ys[i-1] + (v-xs[i-1])*(ys[i]-ys[i-1])/(xs[i]-xs[i-1])
+}
+
+// loess estimates the curve at the given point with a weighted linear regression over the closest
+// points, using tricube weights
+func loess(xs, ys []float64, at float64, span int) float64 {
+
+	// the window of closest points around the position
+	left := sort.SearchFloat64s(xs, at)
+	right := left
+	for right-left < span {
+		if left > 0 && (right >= len(xs) || at-xs[left-1] <= xs[right]-at) {
+			left--
+		} else if right < len(xs) {
+			right++
+		} else {
+			break
+		}
+	}
+
+	var maxDist float64
+	for i := left; i < right; i++ {
+		maxDist = math.Max(maxDist, math.Abs(xs[i]-at))
+	}
+	maxDist *= 1.0001
+
+	var w = make([]float64, len(xs))
+	for i := left; i < right; i++ {
+		if maxDist == 0 {
+			w[i] = 1
+		} else {
+			d := math.Abs(xs[i]-at) / maxDist
+			w[i] = math.Pow(1-d*d*d, 3)
+		}
+	}
+
+	slope, intercept := linear(xs[left:right], ys[left:right], w[left:right])
+
+	return slope*at + intercept
+}
+
+// linear fits a weighted least squares line, a nil weight list gives every point the same weight
+func linear(xs, ys, w []float64) (float64, float64) {
+
+	var sw, mx, my float64
+	for i := range xs {
+		wi := 1.0
+		if w != nil {
+			wi = w[i]
+		}
+		sw += wi
+		mx += wi * xs[i]
+		my += wi * ys[i]
+	}
+
+	if sw == 0 {
+		return 1, 0
+	}
+
+	mx /= sw
+	my /= sw
+
+	var sxy, sxx float64
+	for i := range xs {
+		wi := 1.0
+		if w != nil {
+			wi = w[i]
+		}
+		sxy += wi * (xs[i] - mx) * (ys[i] - my)
+		sxx += wi * (xs[i] - mx) * (xs[i] - mx)
+	}
+
+	if sxx == 0 {
+		return 1, my - mx
+	}
+
+	slope := sxy / sxx
+
+	return slope, my - slope*mx
+}
+
+// Serialize saves to disk a msgpack version of the alignment models
+func (a *Alignment) Serialize() {
+
+	b, e := msgpack.Marshal(&a)
+	if e != nil {
+		msg.MarshalFile(e, "fatal")
+	}
+
+	e = ioutil.WriteFile(sys.AlignmentBin(), b, sys.FilePermission())
+	if e != nil {
+		msg.SerializeFile(e, "fatal")
+	}
+
+	return
+}
+
+// Restore reads the alignment models from the workspace
+func (a *Alignment) Restore() {
+	a.RestoreWithPath(".")
+}
+
+// RestoreWithPath reads the alignment models from the given workspace, a workspace that was never aligned
+// has no models
+func (a *Alignment) RestoreWithPath(p string) {
+
+	path := fmt.Sprintf("%s%s%s", p, string(filepath.Separator), sys.AlignmentBin())
+
+	if _, e := os.Stat(path); os.IsNotExist(e) {
+		return
+	}
+
+	b, e := ioutil.ReadFile(path)
+	if e != nil {
+		msg.MarshalFile(e, "warning")
+	}
+
+	e = msgpack.Unmarshal(b, &a)
+	if e != nil {
+		msg.SerializeFile(e, "warning")
+	}
+
+	return
+}
@@ -0,0 +1,53 @@
+package aln
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+
+	// a nonlinear drift, the run elutes late at the beginning and early at the end of the gradient
+	var x, y []float64
+	for i := 0; i < 200; i++ {
+		rt := 600 + float64(i)*30
+		x = append(x, rt)
+		y = append(y, rt+120*math.Sin(rt/2000))
+	}
+
+	m := Fit(x, y)
+
+	for _, rt := range []float64{900, 2500, 4000, 6000} {
+		want := rt + 120*math.Sin(rt/2000)
+		if v := m.Align(rt); math.Abs(v-want) > 5 {
+			t.Errorf("Aligned time for %.0f is %f, want %f", rt, v, want)
+		}
+		if v := m.Inverse(m.Align(rt)); math.Abs(v-rt) > 1e-6 {
+			t.Errorf("Inverse of %.0f is %f", rt, v)
+		}
+	}
+
+	for k := 1; k < len(m.Y); k++ {
+		if m.Y[k] < m.Y[k-1] {
+			t.Errorf("Model is not monotonic at knot %d", k)
+		}
+	}
+}
+
+func TestFitFewIons(t *testing.T) {
+
+	linear := Fit([]float64{100, 200, 300, 400}, []float64{130, 250, 370, 490})
+	if v := linear.Align(250); math.Abs(v-310) > 1e-9 {
+		t.Errorf("Aligned time is %f, want 310", v)
+	}
+
+	shift := Fit([]float64{100}, []float64{160})
+	if v := shift.Align(300); v != 360 {
+		t.Errorf("Aligned time is %f, want 360", v)
+	}
+
+	identity := Fit(nil, nil)
+	if v := identity.Align(300); v != 300 {
+		t.Errorf("Aligned time is %f, want 300", v)
+	}
+}
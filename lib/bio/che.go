@@ -3,4 +3,7 @@ package bio
 const (
 	// Proton mass
 	Proton = 1.007276467
+
+	// Water monoisotopic mass
+	Water = 18.010564684
 )
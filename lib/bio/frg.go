@@ -0,0 +1,36 @@
+package bio
+
+// residueNames are the amino acids used to build the fragment ions
+var residueNames = []string{"Alanine", "Arginine", "Asparagine", "Aspartic Acid", "Cysteine", "Glutamine", "Glutamic Acid", "Glycine", "Histidine", "Isoleucine", "Leucine", "Lysine", "Methionine", "Phenylalanine", "Proline", "Serine", "Threonine", "Tryptophan", "Tyrosine", "Valine"}
+
+// FragmentIons returns the b and y ion m/z of a peptide for the given charge. The mass shifts are
+// added to the residue on the same position, so terminal modifications go on the first or last residue
+func FragmentIons(sequence string, shifts []float64, charge int) ([]float64, []float64) {
+
+	var masses = make(map[string]float64)
+	for _, i := range residueNames {
+		aa := New(i)
+		masses[aa.Code] = aa.MonoIsotopeMass
+	}
+
+	var residues = make([]float64, len(sequence))
+	for i := range sequence {
+		residues[i] = masses[string(sequence[i])]
+		if i < len(shifts) {
+			residues[i] += shifts[i]
+		}
+	}
+
+	var b, y []float64
+	var nTerm, cTerm float64
+	z := float64(charge)
+
+	for i := 0; i < len(residues)-1; i++ {
+		nTerm += residues[i]
+		cTerm += residues[len(residues)-1-i]
+		b = append(b, (nTerm+z*Proton)/z)
+		y = append(y, (cTerm+Water+z*Proton)/z)
+	}
+
+	return b, y
+}
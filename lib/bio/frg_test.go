@@ -0,0 +1,24 @@
+package bio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFragmentIons(t *testing.T) {
+
+	b, y := FragmentIons("GAK", []float64{0, 0, 229.162932}, 1)
+
+	if len(b) != 2 || len(y) != 2 {
+		t.Fatalf("FragmentIons() returned %d b and %d y ions, want 2", len(b), len(y))
+	}
+
+	if math.Abs(b[0]-58.028740) > 1e-4 || math.Abs(b[1]-129.065854) > 1e-4 {
+		t.Errorf("b ions are %v", b)
+	}
+
+	// the y1 ion carries the lysine label
+	if math.Abs(y[0]-376.275736) > 1e-4 {
+		t.Errorf("y1 ion is %f, want 376.275736", y[0])
+	}
+}
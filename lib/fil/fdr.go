@@ -3,6 +3,7 @@ package fil
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
@@ -14,6 +15,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// pepWindow is the number of neighbouring entries used for the local decoy to target ratio of the PEP
+const pepWindow float64 = 100
+
 // RazorCandidate is a peptide sequence to be evaluated as a razor
 type RazorCandidate struct {
 	Sequence          string
@@ -85,13 +89,27 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 
 	sort.Sort(list)
 
+	// the q-value and the PEP of the level are kept on every entry
+	var scores = make([]float64, len(list))
+	var isDecoy = make([]bool, len(list))
+	for i := range list {
+		scores[i] = list[i].Rank()
+		isDecoy[i] = cla.IsDecoyPSM(list[i], decoyTag)
+	}
+
+	qValues, peps := errorRates(scores, isDecoy)
+	for i := range list {
+		list[i].QValue = qValues[i]
+		list[i].PEP = peps[i]
+	}
+
 	var scoreMap = make(map[float64]float64)
 	limit := (len(list) - 1)
 
 	for j := limit; j >= 0; j-- {
-		_, ok := scoreMap[list[j].Probability]
+		_, ok := scoreMap[list[j].Rank()]
 		if !ok {
-			scoreMap[list[j].Probability] = (decoys / targets)
+			scoreMap[list[j].Rank()] = (decoys / targets)
 		}
 		if cla.IsDecoyPSM(list[j], decoyTag) {
 			decoys--
@@ -127,7 +145,7 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 	targets = 0
 
 	for i := range list {
-		_, ok := probList[list[i].Probability]
+		_, ok := probList[list[i].Rank()]
 		if ok {
 			cleanlist = append(cleanlist, list[i])
 			if cla.IsDecoyPSM(list[i], decoyTag) {
@@ -148,6 +166,201 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 	return cleanlist, minProb
 }
 
+// scoreValue returns the search engine score of a PSM oriented so that higher values are better matches
+func scoreValue(p id.PeptideIdentification, score string) float64 {
+
+	switch score {
+	case "expectation":
+		return -p.Expectation
+	case "hyperscore":
+		return p.Hyperscore
+	case "xcorr":
+		return p.Xcorr
+	case "discriminant":
+		return p.DiscriminantValue
+	default:
+		msg.Custom(errors.New("Unknown score, please use expectation, hyperscore, xcorr or discriminant"), "fatal")
+	}
+
+	return 0
+}
+
+// scoreRanks ranks the PSMs by the given search engine score instead of their probability, so search results
+// that never went through PeptideProphet are filtered by their score. The probabilities are kept
+func scoreRanks(p id.PepIDList, score string) id.PepIDList {
+
+	for i := range p {
+		p[i].ScoreRank = scoreValue(p[i], score)
+		p[i].IsScoreRanked = true
+	}
+
+	return p
+}
+
+// peptideRanks returns the best score rank of each peptide sequence
+func peptideRanks(p id.PepIDList) map[string]float64 {
+
+	var ranks = make(map[string]float64)
+
+	for _, i := range p {
+		if v, ok := ranks[i.Peptide]; !ok || i.ScoreRank > v {
+			ranks[i.Peptide] = i.ScoreRank
+		}
+	}
+
+	return ranks
+}
+
+// scoreRankProteins ranks the proteins by the best score rank of their peptides, the razor and unique ones when
+// the razor peptides were assigned, the same peptides the top peptide probability comes from
+func scoreRankProteins(p id.ProtXML, ranks map[string]float64, isRazor bool) id.ProtXML {
+
+	for i := range p.Groups {
+		for j := range p.Groups[i].Proteins {
+
+			pro := &p.Groups[i].Proteins[j]
+			pro.IsScoreRanked = true
+			pro.TopScoreRank = math.Inf(-1)
+
+			for _, k := range pro.PeptideIons {
+
+				if isRazor == true && k.Razor != 1 && k.IsUnique == false {
+					continue
+				}
+
+				if v, ok := ranks[k.PeptideSequence]; ok && v > pro.TopScoreRank {
+					pro.TopScoreRank = v
+				}
+			}
+		}
+	}
+
+	return p
+}
+
+// errorRates calculates the q-value and the posterior error probability of each entry, higher scores are better.
+// The q-value is the lowest decoy to target ratio reached including the entry, and the PEP is the local decoy to
+// target ratio of the neighbouring scores, made monotone with the score
+func errorRates(scores []float64, decoys []bool) ([]float64, []float64) {
+
+	var order = make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	// entries with the same score are grouped, they share their error rates
+	type group struct {
+		targets, decoys float64
+		entries         []int
+	}
+
+	var groups []group
+	for n, i := range order {
+		if n == 0 || scores[i] != scores[order[n-1]] {
+			groups = append(groups, group{})
+		}
+		g := &groups[len(groups)-1]
+		if decoys[i] {
+			g.decoys++
+		} else {
+			g.targets++
+		}
+		g.entries = append(g.entries, i)
+	}
+
+	var qValues = make([]float64, len(scores))
+	var peps = make([]float64, len(scores))
+
+	var fdr = make([]float64, len(groups))
+	var targets, decoyCount float64
+	for i, g := range groups {
+		targets += g.targets
+		decoyCount += g.decoys
+		fdr[i] = 1
+		if targets > 0 {
+			fdr[i] = decoyCount / targets
+		}
+	}
+
+	q := 1.0
+	for i := len(groups) - 1; i >= 0; i-- {
+		if fdr[i] < q {
+			q = fdr[i]
+		}
+		for _, j := range groups[i].entries {
+			qValues[j] = q
+		}
+	}
+
+	// the local ratio is taken on the groups around each score until the window is filled
+	var local = make([]float64, len(groups))
+	var weights = make([]float64, len(groups))
+	for i, g := range groups {
+
+		t, d := g.targets, g.decoys
+		for l, r := i-1, i+1; t+d < pepWindow && (l >= 0 || r < len(groups)); l, r = l-1, r+1 {
+			if l >= 0 {
+				t += groups[l].targets
+				d += groups[l].decoys
+			}
+			if r < len(groups) {
+				t += groups[r].targets
+				d += groups[r].decoys
+			}
+		}
+
+		local[i] = 1
+		if t > 0 && d/t < 1 {
+			local[i] = d / t
+		}
+		weights[i] = g.targets + g.decoys
+	}
+
+	for i, v := range isotonic(local, weights) {
+		for _, j := range groups[i].entries {
+			peps[j] = v
+		}
+	}
+
+	return qValues, peps
+}
+
+// isotonic fits a non-decreasing sequence to the values with the pool adjacent violators algorithm
+func isotonic(values, weights []float64) []float64 {
+
+	var means, sizes []float64
+	var counts []int
+
+	for i := range values {
+
+		means = append(means, values[i])
+		sizes = append(sizes, weights[i])
+		counts = append(counts, 1)
+
+		for len(means) > 1 && means[len(means)-2] > means[len(means)-1] {
+			n := len(means) - 1
+			w := sizes[n-1] + sizes[n]
+			means[n-1] = (means[n-1]*sizes[n-1] + means[n]*sizes[n]) / w
+			sizes[n-1] = w
+			counts[n-1] += counts[n]
+			means, sizes, counts = means[:n], sizes[:n], counts[:n]
+		}
+	}
+
+	var fit []float64
+	for i := range means {
+		for j := 0; j < counts[i]; j++ {
+			fit = append(fit, means[i])
+		}
+	}
+
+	return fit
+}
+
 // PickedFDR employs the picked FDR strategy
 func PickedFDR(p id.ProtXML) id.ProtXML {
 
@@ -503,14 +716,28 @@ func ProtXMLFilter(p id.ProtXML, targetFDR, pepProb, protProb float64, isPicked,
 
 	sort.Sort(&list)
 
+	// proteins are ranked by their top peptide probability, or score, like on the FDR estimation
+	var scores = make([]float64, len(list))
+	var isDecoy = make([]bool, len(list))
+	for i := range list {
+		scores[i] = list[i].Rank()
+		isDecoy[i] = cla.IsDecoyProtein(list[i], p.DecoyTag)
+	}
+
+	qValues, peps := errorRates(scores, isDecoy)
+	for i := range list {
+		list[i].QValue = qValues[i]
+		list[i].PEP = peps[i]
+	}
+
 	// from botttom to top, classify every protein block with a given fdr score
 	// the score is only calculates to the first (last) protein in each block
 	// proteins with the same score, get the same fdr value.
 	var scoreMap = make(map[float64]float64)
 	for j := (len(list) - 1); j >= 0; j-- {
-		_, ok := scoreMap[list[j].TopPepProb]
+		_, ok := scoreMap[list[j].Rank()]
 		if !ok {
-			scoreMap[list[j].TopPepProb] = (decoys / targets)
+			scoreMap[list[j].Rank()] = (decoys / targets)
 		}
 
 		if cla.IsDecoyProtein(list[j], p.DecoyTag) {
@@ -590,7 +817,7 @@ func ProtXMLFilter(p id.ProtXML, targetFDR, pepProb, protProb float64, isPicked,
 
 	var cleanlist id.ProtIDList
 	for i := range list {
-		_, ok := probList[list[i].TopPepProb]
+		_, ok := probList[list[i].Rank()]
 		if ok {
 			cleanlist = append(cleanlist, list[i])
 			if cla.IsDecoyProtein(list[i], p.DecoyTag) {
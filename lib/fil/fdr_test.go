@@ -1,5 +1,86 @@
 package fil
 
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"philosopher/lib/id"
+)
+
+func Test_scoreRanks(t *testing.T) {
+
+	p := id.PepIDList{
+		{Spectrum: "a", Peptide: "PEPA", Protein: "P1", Probability: 0.9, Expectation: 1e-10, Hyperscore: 40},
+		{Spectrum: "b", Peptide: "PEPB", Protein: "rev_P2", Probability: 0.1, Expectation: 1e-6, Hyperscore: 30},
+		{Spectrum: "c", Peptide: "PEPA", Protein: "P1", Probability: 0.95, Expectation: 1e-5, Hyperscore: 20},
+	}
+
+	// lower expectations are better, the probabilities are not touched
+	p = scoreRanks(p, "expectation")
+	sort.Sort(p)
+
+	if p[0].Spectrum != "a" || p[2].Spectrum != "c" || p[0].Probability != 0.9 || p[2].Probability != 0.95 {
+		t.Errorf("ranked PSMs are %s, %s and %s", p[0].Spectrum, p[1].Spectrum, p[2].Spectrum)
+	}
+
+	// higher hyperscores are better
+	p = scoreRanks(p, "hyperscore")
+	if p[0].Rank() != 40 || p[2].Rank() != 20 {
+		t.Errorf("hyperscore ranks are %f and %f, want 40 and 20", p[0].Rank(), p[2].Rank())
+	}
+
+	ranks := peptideRanks(p)
+	if ranks["PEPA"] != 40 || ranks["PEPB"] != 30 {
+		t.Errorf("peptide ranks are %v", ranks)
+	}
+
+	var x id.ProtXML
+	x.Groups = id.GroupList{{Proteins: id.ProtIDList{
+		{ProteinName: "P1", TopPepProb: 0.95, PeptideIons: []id.PeptideIonIdentification{{PeptideSequence: "PEPA", Razor: 1}}},
+		{ProteinName: "P3", TopPepProb: 0.99, PeptideIons: []id.PeptideIonIdentification{{PeptideSequence: "PEPB", Razor: 0}}},
+	}}}
+
+	// the shared peptide of P3 is not razor, so it does not rank the protein
+	x = scoreRankProteins(x, ranks, true)
+	if x.Groups[0].Proteins[0].Rank() != 40 || !math.IsInf(x.Groups[0].Proteins[1].Rank(), -1) {
+		t.Errorf("protein ranks are %f and %f", x.Groups[0].Proteins[0].Rank(), x.Groups[0].Proteins[1].Rank())
+	}
+}
+
+func Test_errorRates(t *testing.T) {
+
+	// the tied scores share their q-value, the FDR of 2/3 at score 2 is lowered by the targets below
+	q, _ := errorRates([]float64{5, 4, 4, 3, 2, 1}, []bool{false, true, false, false, true, false})
+
+	want := []float64{0, 1.0 / 3, 1.0 / 3, 1.0 / 3, 0.5, 0.5}
+	for i := range want {
+		if math.Abs(q[i]-want[i]) > 1e-9 {
+			t.Errorf("q-value %d is %f, want %f", i, q[i], want[i])
+		}
+	}
+
+	// confident targets on the top, then as many decoys as targets
+	var scores []float64
+	var decoys []bool
+	for i := 0; i < 300; i++ {
+		scores = append(scores, float64(300-i))
+		decoys = append(decoys, i >= 150 && i%2 == 1)
+	}
+
+	q, pep := errorRates(scores, decoys)
+
+	if pep[0] != 0 || pep[299] != 1 || math.Abs(q[299]-1.0/3) > 1e-9 {
+		t.Errorf("first PEP is %f, last PEP is %f and last q-value is %f, want 0, 1 and 1/3", pep[0], pep[299], q[299])
+	}
+
+	for i := 1; i < len(pep); i++ {
+		if pep[i] < pep[i-1] || q[i] < q[i-1] {
+			t.Fatalf("error rates decrease at %d", i)
+		}
+	}
+}
+
 // func TestPepXMLFDRFilter(t *testing.T) {
 
 // 	tes.SetupTestEnv()
@@ -47,6 +47,19 @@ func Run(f met.Data) met.Data {
 
 	f.SearchEngine = searchEngine
 
+	// the search engine score ranks the identifications on every FDR level, the probabilities are kept
+	var ranks map[string]float64
+	if len(f.Filter.Score) > 0 {
+		logrus.Info("Ranking identifications by ", f.Filter.Score)
+		pepxml.Restore()
+		pepxml.PeptideIdentification = scoreRanks(pepxml.PeptideIdentification, f.Filter.Score)
+		sort.Sort(pepxml.PeptideIdentification)
+		pepxml.Serialize()
+		pepid = pepxml.PeptideIdentification
+		pepxml = id.PepXML{}
+		ranks = peptideRanks(pepid)
+	}
+
 	psmT, pepT, ionT := processPeptideIdentifications(pepid, f.Filter.Tag, f.Filter.Mods, f.Filter.PsmFDR, f.Filter.PepFDR, f.Filter.IonFDR)
 	_ = psmT
 	_ = pepT
@@ -55,7 +68,7 @@ func Run(f met.Data) met.Data {
 	if len(f.Filter.Pox) > 0 {
 
 		protXML := readProtXMLInput(f.Filter.Pox, f.Filter.Tag, f.Filter.Weight)
-		processProteinIdentifications(protXML, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Razor, f.Filter.Fo, f.Filter.Tag)
+		processProteinIdentifications(protXML, ranks, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Razor, f.Filter.Fo, f.Filter.Tag)
 
 	} else {
 
@@ -71,7 +84,7 @@ func Run(f met.Data) met.Data {
 			pepid.Serialize("pep")
 			pepid.Serialize("ion")
 
-			processProteinInferenceIdentifications(pepid, razorMap, coverMap, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Tag)
+			processProteinInferenceIdentifications(pepid, razorMap, coverMap, ranks, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Tag)
 		}
 
 	}
@@ -178,11 +191,14 @@ func Run(f met.Data) met.Data {
 	}
 
 	logrus.Info("Calculating spectral counts")
-	e = qua.CalculateSpectralCounts(e)
+	e = qua.CalculateSpectralCounts(e, f.Database.Enz)
 
 	logrus.Info("Saving")
 	e.SerializeGranular()
 
+	// the reports print the q-values and PEPs stored on every level
+	f.Filter.ErrorRates = true
+
 	return f
 }
 
@@ -438,7 +454,7 @@ func readProtXMLInput(xmlFile, decoyTag string, weight float64) id.ProtXML {
 
 // processProteinIdentifications checks if pickedFDR ar razor options should be applied to given data set, if they do,
 // the inputed protXML data is processed before filtered.
-func processProteinIdentifications(p id.ProtXML, ptFDR, pepProb, protProb float64, isPicked, isRazor, fo bool, decoyTag string) {
+func processProteinIdentifications(p id.ProtXML, ranks map[string]float64, ptFDR, pepProb, protProb float64, isPicked, isRazor, fo bool, decoyTag string) {
 
 	var pid id.ProtIDList
 
@@ -459,6 +475,11 @@ func processProteinIdentifications(p id.ProtXML, ptFDR, pepProb, protProb float6
 		p = RazorFilter(p)
 	}
 
+	// the proteins are ranked by the score of their peptides instead of the ProteinProphet probabilities
+	if ranks != nil {
+		p = scoreRankProteins(p, ranks, isRazor)
+	}
+
 	// run the FDR filter for proteins
 	pid = ProtXMLFilter(p, ptFDR, pepProb, protProb, isPicked, isRazor, decoyTag)
 
@@ -502,7 +523,7 @@ func processProteinIdentifications(p id.ProtXML, ptFDR, pepProb, protProb float6
 
 // processProteinInferenceIdentifications checks if pickedFDR ar razor options should be applied to given data set, if they do,
 // the inputed Philosopher inference data is processed before filtered.
-func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[string]string, coverMap map[string]float64, ptFDR, pepProb, protProb float64, isPicked bool, decoyTag string) {
+func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[string]string, coverMap map[string]float64, ranks map[string]float64, ptFDR, pepProb, protProb float64, isPicked bool, decoyTag string) {
 
 	var t int
 	var d int
@@ -696,6 +717,10 @@ func processProteinInferenceIdentifications(psm id.PepIDList, razorMap map[strin
 		"decoy":  d,
 	}).Info("Protein inference results")
 
+	if ranks != nil {
+		proXML = scoreRankProteins(proXML, ranks, true)
+	}
+
 	// run the FDR filter for proteins
 	pid := ProtXMLFilter(proXML, ptFDR, pepProb, protProb, false, true, decoyTag)
 
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"unicode/utf16"
 
@@ -438,10 +439,12 @@ type CDataPacket struct { //16 bytes
 // CDataPackets ...
 type CDataPackets []CDataPacket
 
-// Peak represents an ion peak
+// Peak represents an ion peak, the noise and baseline are only available for centroided FT scans
 type Peak struct {
-	Mz float64
-	I  float32
+	Mz       float64
+	I        float32
+	Noise    float32
+	Baseline float32
 }
 
 // A Spectrum is a collection of peaks
@@ -505,6 +508,7 @@ type RawData struct {
 	Detector        []string
 	Scanevents      ScanEvents
 	Scanindex       ScanIndex
+	Trailer         Trailer
 }
 
 // ProcessRaw calls other low level functions and fill out RawData struct
@@ -558,6 +562,11 @@ func (rd *RawData) ProcessRaw(f string) {
 	rd.Scanevents = scanevents
 	rd.Scanindex = scanindex
 
+	// the trailer extra header is written with the other headers, between the instrument ID and the scan data
+	if stat, e := file.Stat(); e == nil {
+		rd.Trailer = locateTrailer(file, inst.Address, rh.DataAddr, rh.ScanparamsAddr, nScans, uint64(stat.Size()))
+	}
+
 	return
 }
 
@@ -1285,10 +1294,13 @@ func (rd *RawData) spectrum(sn int, centroided ...bool) (s Spectrum) {
 		//Save the Centroided Peaks, they also occur in profile scans but
 		//overlap with profiles, Thermo always does centroiding just for fun
 		for i := uint32(0); i < scn.PeakList.Count; i++ {
+			noise, baseline := scn.noise(float64(scn.PeakList.Peaks[i].Mz))
 			s = append(s,
 				Peak{
-					Mz: float64(scn.PeakList.Peaks[i].Mz),
-					I:  scn.PeakList.Peaks[i].Abundance,
+					Mz:       float64(scn.PeakList.Peaks[i].Mz),
+					I:        scn.PeakList.Peaks[i].Abundance,
+					Noise:    noise,
+					Baseline: baseline,
 				})
 		}
 	} else {
@@ -1306,6 +1318,35 @@ func (rd *RawData) spectrum(sn int, centroided ...bool) (s Spectrum) {
 	return
 }
 
+// noise interpolates the noise and the baseline at the m/z from the triplet stream, where the packet stores
+// the mass, noise and baseline of sampled points across the scan range. The layout is not documented and has
+// not been compared with the vendor noise values yet
+func (data *ScanDataPacket) noise(mz float64) (float32, float32) {
+
+	n := len(data.Triplets) / 3
+	if n == 0 {
+		return 0, 0
+	}
+
+	mass := func(i int) float64 { return float64(data.Triplets[3*i]) }
+
+	i := sort.Search(n, func(i int) bool { return mass(i) >= mz })
+
+	if i == 0 {
+		return data.Triplets[1], data.Triplets[2]
+	}
+
+	if i == n {
+		return data.Triplets[3*(n-1)+1], data.Triplets[3*(n-1)+2]
+	}
+
+	w := float32((mz - mass(i-1)) / (mass(i) - mass(i-1)))
+	noise := data.Triplets[3*(i-1)+1] + w*(data.Triplets[3*i+1]-data.Triplets[3*(i-1)+1])
+	baseline := data.Triplets[3*(i-1)+2] + w*(data.Triplets[3*i+2]-data.Triplets[3*(i-1)+2])
+
+	return noise, baseline
+}
+
 // Chromatography Experimental: read out chromatography data from a connected instrument
 func (rd *RawData) Chromatography(instr int) (cdata CDataPackets) {
 	info, ver := readHeaders(rd.File)
@@ -0,0 +1,274 @@
+package fin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// the generic data types used by the trailer extra fields
+const (
+	typeGap        uint32 = 0x0
+	typeChar       uint32 = 0x1
+	typeTrueFalse  uint32 = 0x2
+	typeYesNo      uint32 = 0x3
+	typeOnOff      uint32 = 0x4
+	typeUChar      uint32 = 0x5
+	typeShort      uint32 = 0x6
+	typeUShort     uint32 = 0x7
+	typeLong       uint32 = 0x8
+	typeULong      uint32 = 0x9
+	typeFloat      uint32 = 0xA
+	typeDouble     uint32 = 0xB
+	typeString     uint32 = 0xC
+	typeWideString uint32 = 0xD
+)
+
+// trailerAnchor is a field present on the trailer extra header of every Thermo MS instrument, used to find it
+const trailerAnchor = "Charge State:"
+
+// the header is searched on the first bytes between the instrument ID and the scan data, where the instrument
+// writes its headers, and is expected to start close to its anchor field
+const (
+	maxTrailerSearch = 64 << 20
+	maxTrailerHeader = 64 << 10
+	maxTrailerFields = 1000
+	maxTrailerLabel  = 256
+)
+
+// GenericDataDescriptor describes a labelled field of the generic records
+type GenericDataDescriptor struct {
+	Type   uint32
+	Length uint32
+	Label  string
+	offset uint64
+}
+
+// Trailer is the layout of the trailer extra records, the labelled values written by the instrument for every
+// scan, like the charge state and the isolation width. The records follow each other from the scan parameters
+// address, one per scan
+type Trailer struct {
+	Fields  []GenericDataDescriptor
+	Address uint64
+	Size    uint64
+}
+
+// size returns the number of bytes taken by the field on a record
+func (d GenericDataDescriptor) size() uint64 {
+
+	switch d.Type {
+	case typeGap:
+		return 0
+	case typeChar, typeTrueFalse, typeYesNo, typeOnOff, typeUChar:
+		return 1
+	case typeShort, typeUShort:
+		return 2
+	case typeLong, typeULong, typeFloat:
+		return 4
+	case typeDouble:
+		return 8
+	case typeString:
+		return uint64(d.Length)
+	case typeWideString:
+		return 2 * uint64(d.Length)
+	}
+
+	return 0
+}
+
+// value converts the field bytes to a number, strings are parsed
+func (d GenericDataDescriptor) value(b []byte) (float64, bool) {
+
+	switch d.Type {
+	case typeChar:
+		return float64(int8(b[0])), true
+	case typeTrueFalse, typeYesNo, typeOnOff, typeUChar:
+		return float64(b[0]), true
+	case typeShort:
+		return float64(int16(binary.LittleEndian.Uint16(b))), true
+	case typeUShort:
+		return float64(binary.LittleEndian.Uint16(b)), true
+	case typeLong:
+		return float64(int32(binary.LittleEndian.Uint32(b))), true
+	case typeULong:
+		return float64(binary.LittleEndian.Uint32(b)), true
+	case typeFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), true
+	case typeDouble:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), true
+	case typeString, typeWideString:
+		var s string
+		if d.Type == typeString {
+			s = string(b)
+		} else {
+			var text = make([]uint16, len(b)/2)
+			for i := range text {
+				text[i] = binary.LittleEndian.Uint16(b[2*i:])
+			}
+			s = string(utf16.Decode(text))
+		}
+		v, e := strconv.ParseFloat(strings.TrimSpace(strings.Trim(s, "\x00")), 64)
+		return v, e == nil
+	}
+
+	return 0, false
+}
+
+// Field returns the descriptor with the given label, the trailing colon is optional
+func (t Trailer) Field(label string) (GenericDataDescriptor, bool) {
+
+	for _, i := range t.Fields {
+		if trimLabel(i.Label) == trimLabel(label) {
+			return i, true
+		}
+	}
+
+	return GenericDataDescriptor{}, false
+}
+
+// TrailerValue returns the value of the labelled trailer extra field for the scan number in argument
+func (rd *RawData) TrailerValue(sn int, label string) (float64, bool) {
+
+	f, ok := rd.Trailer.Field(label)
+	if !ok || f.size() == 0 || sn < 1 || sn > rd.NScans() {
+		return 0, false
+	}
+
+	b := make([]byte, f.size())
+	if _, e := rd.File.ReadAt(b, int64(rd.Trailer.Address+uint64(sn-1)*rd.Trailer.Size+f.offset)); e != nil {
+		return 0, false
+	}
+
+	return f.value(b)
+}
+
+// trimLabel removes the spaces and the colon closing the trailer labels
+func trimLabel(s string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), ":"))
+}
+
+// locateTrailer finds the trailer extra header between begin and end. The header is not referenced by the run
+// header, so it is found by its anchor field and parsed back from the farthest position giving a consistent list
+// of fields, whose records must fit between the scan parameters address and the end of the file
+func locateTrailer(rs io.ReaderAt, begin, end, address, nScans, fileSize uint64) Trailer {
+
+	var t Trailer
+
+	if end <= begin {
+		return t
+	}
+
+	if end-begin > maxTrailerSearch {
+		end = begin + maxTrailerSearch
+	}
+
+	b := make([]byte, end-begin)
+	n, _ := rs.ReadAt(b, int64(begin))
+	b = b[:n]
+
+	var anchor bytes.Buffer
+	for _, i := range utf16.Encode([]rune(trailerAnchor)) {
+		binary.Write(&anchor, binary.LittleEndian, i)
+	}
+
+	for from := 0; ; {
+
+		i := bytes.Index(b[from:], anchor.Bytes())
+		if i < 0 {
+			break
+		}
+		i += from
+		from = i + 1
+
+		// the label length, the field length and the field type come before the label text
+		d := i - 12
+		if d < 4 || binary.LittleEndian.Uint32(b[i-4:]) != uint32(len(trailerAnchor)) {
+			continue
+		}
+
+		var fields []GenericDataDescriptor
+		for p := d - 4; p >= 0 && d-p <= maxTrailerHeader; p -= 2 {
+			if f, ok := parseTrailerHeader(b[p:], d-p); ok {
+				fields = f
+			}
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		var size uint64
+		for j := range fields {
+			fields[j].offset = size
+			size += fields[j].size()
+		}
+
+		if size == 0 || address+size*nScans > fileSize {
+			continue
+		}
+
+		t.Fields = fields
+		t.Address = address
+		t.Size = size
+
+		return t
+	}
+
+	return t
+}
+
+// parseTrailerHeader reads the number of fields followed by their descriptors, requiring printable labels and
+// a descriptor starting at the anchor position
+func parseTrailerHeader(b []byte, anchor int) ([]GenericDataDescriptor, bool) {
+
+	if len(b) < 4 {
+		return nil, false
+	}
+
+	n := binary.LittleEndian.Uint32(b)
+	if n == 0 || n > maxTrailerFields {
+		return nil, false
+	}
+
+	var fields []GenericDataDescriptor
+	var anchored bool
+
+	pos := 4
+	for i := uint32(0); i < n; i++ {
+
+		if pos+12 > len(b) {
+			return nil, false
+		}
+
+		var d GenericDataDescriptor
+		d.Type = binary.LittleEndian.Uint32(b[pos:])
+		d.Length = binary.LittleEndian.Uint32(b[pos+4:])
+		chars := int(binary.LittleEndian.Uint32(b[pos+8:]))
+
+		if d.Type > typeWideString || chars > maxTrailerLabel || pos+12+2*chars > len(b) {
+			return nil, false
+		}
+
+		if pos == anchor {
+			anchored = true
+		}
+
+		var text = make([]uint16, chars)
+		for j := range text {
+			text[j] = binary.LittleEndian.Uint16(b[pos+12+2*j:])
+			if text[j] != 0 && (text[j] < 0x20 || text[j] > 0x7e) {
+				return nil, false
+			}
+		}
+		d.Label = strings.TrimRight(string(utf16.Decode(text)), "\x00")
+
+		fields = append(fields, d)
+		pos += 12 + 2*chars
+	}
+
+	return fields, anchored
+}
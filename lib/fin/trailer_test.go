@@ -0,0 +1,88 @@
+package fin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"unicode/utf16"
+)
+
+func Test_locateTrailer(t *testing.T) {
+
+	var b bytes.Buffer
+
+	descriptor := func(kind, length uint32, label string) {
+		text := utf16.Encode([]rune(label))
+		binary.Write(&b, binary.LittleEndian, kind)
+		binary.Write(&b, binary.LittleEndian, length)
+		binary.Write(&b, binary.LittleEndian, uint32(len(text)))
+		binary.Write(&b, binary.LittleEndian, text)
+	}
+
+	// unrelated bytes come before the header
+	b.Write([]byte{0xff, 0xfe, 0x10, 0x00, 0x00, 0x80})
+
+	binary.Write(&b, binary.LittleEndian, uint32(4))
+	descriptor(typeGap, 0, "Trailer Extra Information:")
+	descriptor(typeShort, 0, "Charge State:")
+	descriptor(typeDouble, 0, "MS2 Isolation Width:")
+	descriptor(typeString, 4, "Scan Description:")
+
+	end := uint64(b.Len())
+
+	// two scans of 14 bytes each
+	address := end
+	binary.Write(&b, binary.LittleEndian, int16(0))
+	binary.Write(&b, binary.LittleEndian, math.Float64bits(0))
+	b.WriteString("1.5\x00")
+	binary.Write(&b, binary.LittleEndian, int16(3))
+	binary.Write(&b, binary.LittleEndian, math.Float64bits(0.7))
+	b.WriteString("2\x00\x00\x00")
+
+	f, e := ioutil.TempFile("", "*.raw")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	f.Write(b.Bytes())
+
+	var rd RawData
+	rd.File = f
+	rd.Scanindex = make(ScanIndex, 2)
+	rd.Trailer = locateTrailer(f, 0, end, address, 2, uint64(b.Len()))
+
+	if len(rd.Trailer.Fields) != 4 || rd.Trailer.Size != 14 || rd.Trailer.Address != address {
+		t.Fatalf("locateTrailer() = %+v", rd.Trailer)
+	}
+
+	tests := []struct {
+		scan  int
+		label string
+		want  float64
+	}{
+		{1, "Charge State:", 0},
+		{2, "Charge State", 3},
+		{2, "MS2 Isolation Width:", 0.7},
+		{1, "Scan Description:", 1.5},
+		{2, "Scan Description:", 2},
+	}
+	for _, tt := range tests {
+		if got, ok := rd.TrailerValue(tt.scan, tt.label); !ok || got != tt.want {
+			t.Errorf("TrailerValue(%d, %s) = %v, want %v", tt.scan, tt.label, got, tt.want)
+		}
+	}
+
+	if _, ok := rd.TrailerValue(1, "Monoisotopic M/Z:"); ok {
+		t.Errorf("TrailerValue() read a field missing from the header")
+	}
+
+	// records that do not fit in the file are not from this header
+	if tr := locateTrailer(f, 0, end, address, 3, uint64(b.Len())); len(tr.Fields) > 0 {
+		t.Errorf("locateTrailer() = %+v for records past the end of the file", tr)
+	}
+}
@@ -73,6 +73,10 @@ type PeptideIdentification struct {
 	LocalizedPTMSites                map[string]int
 	LocalizedPTMMassDiff             map[string]string
 	Probability                      float64
+	QValue                           float64
+	PEP                              float64
+	ScoreRank                        float64
+	IsScoreRanked                    bool
 	IsoMassD                         int
 	Expectation                      float64
 	Xcorr                            float64
@@ -90,6 +94,17 @@ type PeptideIdentification struct {
 	Modifications                    mod.Modifications
 }
 
+// Rank returns the value the identification is ranked and filtered on, the search engine score when the filter
+// ranks by score, or the probability
+func (p PeptideIdentification) Rank() float64 {
+
+	if p.IsScoreRanked == true {
+		return p.ScoreRank
+	}
+
+	return p.Probability
+}
+
 // PepIDList is a list of PeptideSpectrumMatch
 type PepIDList []PeptideIdentification
 
@@ -100,7 +115,7 @@ func (p PepIDList) Len() int {
 
 // Less function for Sort
 func (p PepIDList) Less(i, j int) bool {
-	return p[i].Probability > p[j].Probability
+	return p[i].Rank() > p[j].Rank()
 }
 
 // Swap function for Sort
@@ -390,6 +405,10 @@ func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.
 						psm.IsoMassD, _ = strconv.Atoi(k.Value)
 					}
 
+					if k.Name == "fval" {
+						psm.DiscriminantValue, _ = strconv.ParseFloat(k.Value, 64)
+					}
+
 					// if k.Name == "ntt" {
 					// 	psm.NumberOfEnzymaticTermini, _ = strconv.Atoi(k.Value)
 					// }
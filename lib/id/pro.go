@@ -43,6 +43,10 @@ type ProteinIdentification struct {
 	Probability              float64
 	Confidence               float64
 	TopPepProb               float64
+	TopScoreRank             float64
+	IsScoreRanked            bool
+	QValue                   float64
+	PEP                      float64
 	IndistinguishableProtein []string
 	TotalNumberPeptides      int
 	PeptideIons              []PeptideIonIdentification
@@ -70,6 +74,17 @@ type PeptideIonIdentification struct {
 	Modifications            mod.Modifications
 }
 
+// Rank returns the value the protein is ranked and filtered on, the best search engine score of its peptides
+// when the filter ranks by score, or the top peptide probability
+func (p ProteinIdentification) Rank() float64 {
+
+	if p.IsScoreRanked == true {
+		return p.TopScoreRank
+	}
+
+	return p.TopPepProb
+}
+
 // GroupList represents a protein group list
 type GroupList []GroupIdentification
 
@@ -83,7 +98,7 @@ func (p ProtIDList) Len() int {
 
 // Less function for sorting
 func (p ProtIDList) Less(i, j int) bool {
-	return p[i].TopPepProb > p[j].TopPepProb
+	return p[i].Rank() > p[j].Rank()
 }
 
 // Swap function for sorting
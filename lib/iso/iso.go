@@ -1,5 +1,15 @@
 package iso
 
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+)
+
 // Labels main struct
 type Labels struct {
 	Spectrum      string
@@ -8,151 +18,188 @@ type Labels struct {
 	RetentionTime float64
 	ChargeState   int
 	IsUsed        bool
-	Channel1      Channel1
-	Channel2      Channel2
-	Channel3      Channel3
-	Channel4      Channel4
-	Channel5      Channel5
-	Channel6      Channel6
-	Channel7      Channel7
-	Channel8      Channel8
-	Channel9      Channel9
-	Channel10     Channel10
-	Channel11     Channel11
-	Channel12     Channel12
-	Channel13     Channel13
-	Channel14     Channel14
-	Channel15     Channel15
-	Channel16     Channel16
+	IsCorrected   bool
+	Channels      []Channel
 }
 
 // LabeledSpectra is a list of spectra lables
 type LabeledSpectra map[string]Labels
 
-// Channel1 TMT
-type Channel1 struct {
+// Channel is a reporter ion of an isobaric label
+type Channel struct {
 	Name       string
 	CustomName string
 	Mz         float64
 	Intensity  float64
+	SN         float64
+	Ratio      float64
 }
 
-// Channel2 TMT
-type Channel2 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// Reagent is the name and the reporter ion m/z of a plex channel
+type Reagent struct {
+	Name string
+	Mz   float64
 }
 
-// Channel3 TMT
-type Channel3 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
-}
+// New builds the label channels for the given reagents
+func New(reagents []Reagent) Labels {
 
-// Channel4 TMT
-type Channel4 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
-}
+	var o Labels
 
-// Channel5 TMT
-type Channel5 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
-}
+	o.Channels = make([]Channel, len(reagents))
+	for i := range reagents {
+		o.Channels[i].Name = reagents[i].Name
+		o.Channels[i].Mz = reagents[i].Mz
+	}
 
-// Channel6 TMT
-type Channel6 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+	return o
 }
 
-// Channel7 TMT
-type Channel7 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
-}
+// ReadReagents parses a reagent table, with the channel name and the reporter ion m/z on each line
+func ReadReagents(f string) []Reagent {
 
-// Channel8 TMT
-type Channel8 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
-}
+	var reagents []Reagent
 
-// Channel9 TMT
-type Channel9 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
-}
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(errors.New("Cannot open the reagent table"), "fatal")
+	}
+	defer file.Close()
 
-// Channel10 TMT
-type Channel10 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			msg.Custom(errors.New("The reagent table lines need a channel name and a reporter m/z"), "fatal")
+		}
+
+		mz, e := strconv.ParseFloat(parts[1], 64)
+		if e != nil {
+			msg.Custom(errors.New("Cannot parse the reporter m/z of channel "+parts[0]), "fatal")
+		}
+
+		reagents = append(reagents, Reagent{parts[0], mz})
+	}
+
+	if len(reagents) == 0 {
+		msg.Custom(errors.New("The reagent table has no channels"), "fatal")
+	}
+
+	return reagents
 }
 
-// Channel11 TMT
-type Channel11 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// Impurities are the isotopic impurities of each reagent, as the percentage of the reporter signal found at
+// the -2, -1, +1 and +2 13C isotope positions. TMTpro lot sheets separate the 13C and 15N isotopes, with the
+// -2x13C, -13C-15N, -13C, -15N, +15N, +13C, +15N+13C and +2x13C percentages
+type Impurities map[string][]float64
+
+// ReadImpurities parses the lot correction factors, with the channel name followed by the -2, -1, +1 and +2
+// impurity percentages on each line, or by the eight columns of the TMTpro sheets
+func ReadImpurities(f string) Impurities {
+
+	var impurities = make(Impurities)
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(errors.New("Cannot open the impurity table"), "fatal")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) != 5 && len(parts) != 9 {
+			msg.Custom(errors.New("The impurity table lines need a channel name and the -2, -1, +1 and +2 percentages, or the -2x13C, -13C-15N, -13C, -15N, +15N, +13C, +15N+13C and +2x13C percentages"), "fatal")
+		}
+
+		var values = make([]float64, len(parts)-1)
+		for i := range values {
+			v, e := strconv.ParseFloat(parts[i+1], 64)
+			if e != nil {
+				msg.Custom(errors.New("Cannot parse the impurities of channel "+parts[0]), "fatal")
+			}
+			values[i] = v
+		}
+
+		impurities[parts[0]] = values
+	}
+
+	return impurities
 }
 
-// Channel12 TMT
-type Channel12 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// Copy returns the labels with their own channel list
+func (l Labels) Copy() Labels {
+
+	var o = l
+
+	if l.Channels != nil {
+		o.Channels = make([]Channel, len(l.Channels))
+		copy(o.Channels, l.Channels)
+	}
+
+	return o
 }
 
-// Channel13 TMT
-type Channel13 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// Add sums the channel intensities of another label set, the channels are created when missing
+func (l *Labels) Add(o Labels) {
+
+	if len(l.Channels) < len(o.Channels) {
+		l.Channels = append(l.Channels, make([]Channel, len(o.Channels)-len(l.Channels))...)
+	}
+
+	for i := range o.Channels {
+		l.Channels[i].Name = o.Channels[i].Name
+		l.Channels[i].CustomName = o.Channels[i].CustomName
+		l.Channels[i].Mz = o.Channels[i].Mz
+		l.Channels[i].Intensity += o.Channels[i].Intensity
+	}
+
+	return
 }
 
-// Channel14 TMT
-type Channel14 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// Sum returns the summed intensity of all channels
+func (l Labels) Sum() float64 {
+
+	var sum float64
+	for _, i := range l.Channels {
+		sum += i.Intensity
+	}
+
+	return sum
 }
 
-// Channel15 TMT
-type Channel15 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// SignalToNoise returns the summed signal-to-noise of all channels
+func (l Labels) SignalToNoise() float64 {
+
+	var sum float64
+	for _, i := range l.Channels {
+		sum += i.SN
+	}
+
+	return sum
 }
 
-// Channel16 TMT
-type Channel16 struct {
-	Name       string
-	CustomName string
-	Mz         float64
-	Intensity  float64
+// Reset sets all channel intensities and signal-to-noise values to zero, removing the impurity correction flag
+func (l *Labels) Reset() {
+
+	l.IsCorrected = false
+
+	for i := range l.Channels {
+		l.Channels[i].Intensity = 0
+		l.Channels[i].SN = 0
+	}
+
+	return
 }
@@ -54,12 +54,12 @@ type Data struct {
 
 // Msconvert options and parameters
 type Msconvert struct {
-	Output                  string
-	Format                  string
-	MZBinaryEncoding        string
-	IntensityBinaryEncoding string
-	NoIndex                 bool
-	Zlib                    bool
+	Output                  string `yaml:"output"`
+	Format                  string `yaml:"format"`
+	MZBinaryEncoding        string `yaml:"mz"`
+	IntensityBinaryEncoding string `yaml:"inten"`
+	NoIndex                 bool   `yaml:"noindex"`
+	Zlib                    bool   `yaml:"zlib"`
 }
 
 // Idconvert optioons and parameters
@@ -321,25 +321,27 @@ type PTMProphet struct {
 
 // Filter options and parameters
 type Filter struct {
-	Pex       string  `yaml:"pepxml"`
-	Pox       string  `yaml:"protxml"`
-	Tag       string  `yaml:"tag"`
-	Mods      string  `yaml:"mods"`
-	PsmFDR    float64 `yaml:"psmFDR"`
-	PepFDR    float64 `yaml:"peptideFDR"`
-	IonFDR    float64 `yaml:"ionFDR"`
-	PtFDR     float64 `yaml:"proteinFDR"`
-	ProtProb  float64 `yaml:"proteinProbability"`
-	PepProb   float64 `yaml:"peptideProbability"`
-	Weight    float64 `yaml:"peptideWeight"`
-	Model     bool    `yaml:"models"`
-	Razor     bool    `yaml:"razor"`
-	Picked    bool    `yaml:"picked"`
-	Seq       bool    `yaml:"sequential"`
-	TwoD      bool    `yaml:"two-dimensional"`
-	Mapmods   bool    `yaml:"mapMods"`
-	Fo        bool
-	Inference bool
+	Pex        string  `yaml:"pepxml"`
+	Pox        string  `yaml:"protxml"`
+	Tag        string  `yaml:"tag"`
+	Mods       string  `yaml:"mods"`
+	Score      string  `yaml:"score"`
+	PsmFDR     float64 `yaml:"psmFDR"`
+	PepFDR     float64 `yaml:"peptideFDR"`
+	IonFDR     float64 `yaml:"ionFDR"`
+	PtFDR      float64 `yaml:"proteinFDR"`
+	ProtProb   float64 `yaml:"proteinProbability"`
+	PepProb    float64 `yaml:"peptideProbability"`
+	Weight     float64 `yaml:"peptideWeight"`
+	Model      bool    `yaml:"models"`
+	Razor      bool    `yaml:"razor"`
+	Picked     bool    `yaml:"picked"`
+	Seq        bool    `yaml:"sequential"`
+	TwoD       bool    `yaml:"two-dimensional"`
+	Mapmods    bool    `yaml:"mapMods"`
+	Fo         bool
+	Inference  bool
+	ErrorRates bool
 }
 
 // Quantify options and parameters
@@ -351,33 +353,56 @@ type Quantify struct {
 	Brand      string  `yaml:"brand"`
 	Plex       string  `yaml:"plex"`
 	ChanNorm   string  `yaml:"chanNorm"`
+	RefChan    string  `yaml:"refChannel"`
 	Annot      string  `yaml:"annotation"`
+	Reagents   string  `yaml:"reagents"`
+	Impurity   string  `yaml:"impurity"`
+	Light      string  `yaml:"light"`
+	Medium     string  `yaml:"medium"`
+	Heavy      string  `yaml:"heavy"`
 	Level      int     `yaml:"level"`
 	RTWin      float64 `yaml:"retentionTimeWindow"`
 	PTWin      float64 `yaml:"peakTimeWindow"`
 	Tol        float64 `yaml:"tolerance"`
 	Purity     float64 `yaml:"purity"`
+	SPSMatch   float64 `yaml:"spsMatch"`
+	MBRFDR     float64 `yaml:"mbrFDR"`
+	Threads    int     `yaml:"threads"`
 	MinProb    float64 `yaml:"minprob"`
 	RemoveLow  float64 `yaml:"removeLow"`
 	Isolated   bool    `yaml:"isolated"`
+	Feature    bool    `yaml:"feature"`
+	MBR        bool    `yaml:"mbr"`
 	IntNorm    bool    `yaml:"intNorm"`
 	Unique     bool    `yaml:"uniqueOnly"`
 	BestPSM    bool    `yaml:"bestPSM"`
+	Correct    bool    `yaml:"correct"`
+	Interfere  bool    `yaml:"interference"`
+	QC         bool    `yaml:"qc"`
+	SN         bool    `yaml:"signalToNoise"`
+	MinSN      float64 `yaml:"minSN"`
+	CarrierCap float64 `yaml:"carrierCap"`
 	LabelNames map[string]string
+	LabelRoles map[string]string
 }
 
 // Abacus options ad parameters
 type Abacus struct {
-	Tag      string  `yaml:"tag"`
-	ProtProb float64 `yaml:"proteinProbability"`
-	PepProb  float64 `yaml:"peptideProbability"`
-	Peptide  bool    `yaml:"peptide"`
-	Protein  bool    `yaml:"protein"`
-	Razor    bool    `yaml:"razor"`
-	Picked   bool    `yaml:"picked"`
-	Labels   bool    `yaml:"labels"`
-	Unique   bool    `yaml:"uniqueOnly"`
-	Reprint  bool    `yaml:"reprint"`
+	Tag       string  `yaml:"tag"`
+	ProtProb  float64 `yaml:"proteinProbability"`
+	PepProb   float64 `yaml:"peptideProbability"`
+	Peptide   bool    `yaml:"peptide"`
+	Protein   bool    `yaml:"protein"`
+	Razor     bool    `yaml:"razor"`
+	Picked    bool    `yaml:"picked"`
+	Labels    bool    `yaml:"labels"`
+	Unique    bool    `yaml:"uniqueOnly"`
+	Reprint   bool    `yaml:"reprint"`
+	MaxLFQ    bool    `yaml:"maxLFQ"`
+	MinRatio  int     `yaml:"minRatioCount"`
+	Integrate string  `yaml:"integrate"`
+	Bridge    string  `yaml:"bridge"`
+	Rollup    string  `yaml:"rollup"`
 }
 
 // BioQuant options and parameters
@@ -496,6 +521,29 @@ func (d *Data) Restore(f string) {
 	return
 }
 
+// RestoreWithPath reads the meta data of the workspace on the given directory, nothing is read when the
+// directory has no workspace
+func (d *Data) RestoreWithPath(p string) {
+
+	path := fmt.Sprintf("%s%s%s", p, string(filepath.Separator), sys.Meta())
+
+	if _, e := os.Stat(path); os.IsNotExist(e) {
+		return
+	}
+
+	b, e := ioutil.ReadFile(path)
+	if e != nil {
+		msg.ReadFile(e, "warning")
+	}
+
+	e = msgpack.Unmarshal(b, &d)
+	if e != nil {
+		msg.DecodeMsgPck(e, "warning")
+	}
+
+	return
+}
+
 // FunctionInitCheckUp does initilization checkup and verification if meta and temp folders are up.
 // In case not, meta trows an error and folder is created.
 func (d Data) FunctionInitCheckUp() {
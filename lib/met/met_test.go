@@ -63,3 +63,34 @@ func TestMetaData(t *testing.T) {
 	}
 
 }
+
+func TestData_RestoreWithPath(t *testing.T) {
+
+	local, _ := os.Getwd()
+	ws := t.TempDir()
+
+	os.Chdir(ws)
+	os.Mkdir(sys.MetaDir(), sys.FilePermission())
+
+	var w met.Data
+	w.UUID = "workspace"
+	w.Filter.ErrorRates = true
+	w.Serialize()
+
+	os.Chdir(local)
+
+	var r met.Data
+	r.RestoreWithPath(ws)
+
+	if r.UUID != "workspace" || r.Filter.ErrorRates == false {
+		t.Errorf("restored meta data is %s, %v", r.UUID, r.Filter.ErrorRates)
+	}
+
+	// a directory without a workspace leaves the meta data empty
+	var empty met.Data
+	empty.RestoreWithPath(t.TempDir())
+
+	if len(empty.UUID) > 0 {
+		t.Errorf("meta data restored from an empty directory, %s", empty.UUID)
+	}
+}
@@ -0,0 +1,336 @@
+package mzn
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack"
+)
+
+// the m/z range kept from MSn spectra, covering the iTRAQ and TMT reporter ions
+const (
+	reporterRegionLow  = 100.0
+	reporterRegionHigh = 140.0
+)
+
+// cacheVersion changes when the cached spectrum structure does, so older caches are parsed again
+const cacheVersion = "5"
+
+// peakSize is the memory taken by a decoded peak, its m/z and intensity as 64-bit floats
+const peakSize = 16
+
+// encodedExpansion is the expected ratio between the decoded peak arrays and the spectrum file when no cache
+// is available, base64 encoded and zlib compressed 32-bit arrays take up to six times their file size
+const encodedExpansion = 6
+
+// cacheDir is the directory keeping the spectra cache
+var cacheDir = sys.SpectraCacheDir
+
+// checksums keeps the checksum of the files already read, identified by their path, size and modification time
+var checksums sync.Map
+
+// cacheHeader describes the cached run, written after the spectra and located by the offset closing the file.
+// Peaks counts the decoded peaks held by the cache
+type cacheHeader struct {
+	Checksum string
+	Levels   []string
+	Scans    map[int]int
+	Offsets  []int64
+	Peaks    int
+}
+
+// cacheSource reads the decoded spectra from the workspace cache, one spectrum at a time. MS1 spectra are
+// complete, MSn spectra hold the precursor information and the peaks from the reporter region
+type cacheSource struct {
+	cacheHeader
+	file *os.File
+	size int64
+}
+
+func (s *cacheSource) Len() int     { return len(s.Levels) }
+func (s *cacheSource) Close() error { return s.file.Close() }
+
+// Spectrum decodes the spectrum at the given index from the cache
+func (s *cacheSource) Spectrum(i int) Spectrum {
+
+	var spec Spectrum
+
+	dec := msgpack.NewDecoder(bufio.NewReader(io.NewSectionReader(s.file, s.Offsets[i], s.size-s.Offsets[i])))
+	if e := dec.Decode(&spec); e != nil {
+		msg.DecodeMsgPck(e, "fatal")
+	}
+
+	return spec
+}
+
+// locate returns the position of the scan number in the cache
+func (s *cacheSource) locate(scan int) (int, bool) {
+	i, ok := s.Scans[scan]
+	return i, ok
+}
+
+// OpenCached opens the spectrum file through the workspace cache, identified by the file checksum. The cache
+// is created on the first use, so later quantifications skip the parsing and decoding steps. Only the reporter
+// region is available from MSn spectra, use Open for the full peak lists
+func (r *Reader) OpenCached(f string) {
+
+	sum := fileChecksum(f)
+	bin := filepath.Join(cacheDir(), cacheName(sum))
+
+	var c cacheSource
+
+	if e := c.open(bin, sum); e != nil {
+
+		logrus.Info("Caching spectra from ", filepath.Base(f))
+
+		r.Open(f)
+		e = writeCache(r, bin, sum)
+		r.Close()
+
+		if e == nil {
+			e = c.open(bin, sum)
+		}
+
+		// the spectra are read from the file when the cache is not available
+		if e != nil {
+			msg.SerializeFile(e, "warning")
+			r.Open(f)
+			return
+		}
+	}
+
+	r.FileName = f
+	r.src = &c
+	r.levels = make([]string, c.Len())
+	copy(r.levels, c.Levels)
+
+	return
+}
+
+// reporterRegion discards the MSn peaks outside of the reporter ion range
+func (s *Spectrum) reporterRegion() {
+
+	var mz, intensity, noise []float64
+
+	for i := range s.Mz.DecodedStream {
+		if s.Mz.DecodedStream[i] >= reporterRegionLow && s.Mz.DecodedStream[i] <= reporterRegionHigh {
+			mz = append(mz, s.Mz.DecodedStream[i])
+			intensity = append(intensity, s.Intensity.DecodedStream[i])
+			if i < len(s.Noise.DecodedStream) {
+				noise = append(noise, s.Noise.DecodedStream[i])
+			}
+		}
+	}
+
+	s.Mz.DecodedStream = mz
+	s.Intensity.DecodedStream = intensity
+	s.Noise.DecodedStream = noise
+	s.IonMobility.DecodedStream = nil
+
+	return
+}
+
+// offsetWriter keeps track of the number of bytes written to the cache
+type offsetWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (o *offsetWriter) Write(b []byte) (int, error) {
+	n, e := o.w.Write(b)
+	o.n += int64(n)
+	return n, e
+}
+
+// writeCache decodes the spectra from the reader and writes them one at a time to a temporary file, followed by
+// the header and its offset. The file is renamed once complete so concurrent runs never read a partial cache
+func writeCache(r *Reader, bin, sum string) error {
+
+	if e := os.MkdirAll(filepath.Dir(bin), sys.FilePermission()); e != nil {
+		return e
+	}
+
+	file, e := ioutil.TempFile(filepath.Dir(bin), filepath.Base(bin)+".*.tmp")
+	if e != nil {
+		return e
+	}
+	defer os.Remove(file.Name())
+
+	buffer := bufio.NewWriter(file)
+	w := &offsetWriter{w: buffer}
+	enc := msgpack.NewEncoder(w)
+
+	var h cacheHeader
+	h.Checksum = sum
+	h.Levels = make([]string, r.Len())
+	h.Scans = make(map[int]int)
+	h.Offsets = make([]int64, r.Len())
+
+	for i := 0; e == nil && i < r.Len(); i++ {
+
+		spec := r.src.Spectrum(i)
+		spec.Decode()
+
+		if spec.Level != "1" {
+			spec.reporterRegion()
+		}
+
+		h.Levels[i] = spec.Level
+		h.Scans[atoi(spec.Scan)] = i
+		h.Offsets[i] = w.n
+		h.Peaks += len(spec.Mz.DecodedStream)
+
+		e = enc.Encode(&spec)
+	}
+
+	pos := w.n
+
+	if e == nil {
+		e = enc.Encode(&h)
+	}
+
+	if e == nil {
+		e = binary.Write(w, binary.LittleEndian, pos)
+	}
+
+	if e == nil {
+		e = buffer.Flush()
+	}
+
+	if c := file.Close(); e == nil {
+		e = c
+	}
+
+	if e != nil {
+		return e
+	}
+
+	return os.Rename(file.Name(), bin)
+}
+
+// open reads the cache header, the cache must belong to the file with the given checksum
+func (s *cacheSource) open(bin, sum string) error {
+
+	file, e := os.Open(bin)
+	if e != nil {
+		return e
+	}
+
+	h, size, e := readCacheHeader(file)
+	if e == nil && h.Checksum != sum {
+		e = errors.New("the spectra cache does not match " + sum)
+	}
+
+	if e != nil {
+		file.Close()
+		return e
+	}
+
+	s.cacheHeader = h
+	s.file = file
+	s.size = size
+
+	return nil
+}
+
+// readCacheHeader decodes the header from the offset written at the end of the cache
+func readCacheHeader(file *os.File) (cacheHeader, int64, error) {
+
+	var h cacheHeader
+
+	info, e := file.Stat()
+	if e != nil {
+		return h, 0, e
+	}
+
+	size := info.Size()
+	if size < 8 {
+		return h, size, errors.New("the spectra cache is incomplete")
+	}
+
+	var pos int64
+	if e := binary.Read(io.NewSectionReader(file, size-8, 8), binary.LittleEndian, &pos); e != nil {
+		return h, size, e
+	}
+
+	if pos < 0 || pos >= size-8 {
+		return h, size, errors.New("the spectra cache is incomplete")
+	}
+
+	e = msgpack.NewDecoder(bufio.NewReader(io.NewSectionReader(file, pos, size-8-pos))).Decode(&h)
+
+	return h, size, e
+}
+
+// DecodedSize estimates the memory taken by the decoded spectra of the file, from the peak count on the
+// spectra cache, or from the file size before the cache is created
+func DecodedSize(f string) uint64 {
+
+	info, e := os.Stat(f)
+	if e != nil {
+		return 0
+	}
+
+	sum := fileChecksum(f)
+
+	file, e := os.Open(filepath.Join(cacheDir(), cacheName(sum)))
+	if e == nil {
+		defer file.Close()
+		if h, _, e := readCacheHeader(file); e == nil && h.Checksum == sum {
+			return uint64(h.Peaks) * peakSize
+		}
+	}
+
+	return uint64(info.Size()) * encodedExpansion
+}
+
+// fileChecksum calculates the SHA-1 checksum from the file content. Checksums are kept while the file
+// path, size and modification time do not change, so a file is read once
+func fileChecksum(f string) string {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	info, e := file.Stat()
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	path, _ := filepath.Abs(f)
+	key := fmt.Sprintf("%s|%d|%d", path, info.Size(), info.ModTime().UnixNano())
+
+	if v, ok := checksums.Load(key); ok {
+		return v.(string)
+	}
+
+	h := sha1.New()
+	if _, e := io.Copy(h, file); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	checksums.Store(key, sum)
+
+	return sum
+}
+
+// cacheName returns the cache file name of the spectrum file checksum
+func cacheName(sum string) string {
+	return sum + ".v" + cacheVersion + ".bin"
+}
@@ -0,0 +1,132 @@
+package mzn
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"philosopher/lib/met"
+	"philosopher/lib/psi"
+)
+
+func TestSpectrum_reporterRegion(t *testing.T) {
+
+	var s Spectrum
+	s.Mz.DecodedStream = []float64{85.1, 126.1277, 131.1382, 140.5, 500.2}
+	s.Intensity.DecodedStream = []float64{1, 2, 3, 4, 5}
+
+	s.reporterRegion()
+
+	if !reflect.DeepEqual(s.Mz.DecodedStream, []float64{126.1277, 131.1382}) || !reflect.DeepEqual(s.Intensity.DecodedStream, []float64{2, 3}) {
+		t.Errorf("reporterRegion() = %v %v", s.Mz.DecodedStream, s.Intensity.DecodedStream)
+	}
+
+	// the noise levels of RAW spectra follow their peaks
+	s.Mz.DecodedStream = []float64{85.1, 126.1277, 131.1382, 140.5}
+	s.Intensity.DecodedStream = []float64{1, 2, 3, 4}
+	s.Noise.DecodedStream = []float64{0.1, 0.2, 0.3, 0.4}
+
+	s.reporterRegion()
+
+	if !reflect.DeepEqual(s.Noise.DecodedStream, []float64{0.2, 0.3}) {
+		t.Errorf("reporterRegion() noise = %v", s.Noise.DecodedStream)
+	}
+}
+
+func TestReader_OpenCached(t *testing.T) {
+
+	dir := t.TempDir()
+
+	defer func(f func() string) { cacheDir = f }(cacheDir)
+	cacheDir = func() string { return filepath.Join(dir, "spectra") }
+
+	var ms1, ms2 Spectrum
+	ms1.Scan, ms1.Level, ms1.ScanStartTime = "1", "1", 10
+	ms1.Mz.DecodedStream = []float64{400.5, 600.25, 800.125}
+	ms1.Intensity.DecodedStream = []float64{10, 20, 30}
+	ms2.Scan, ms2.Level, ms2.ScanStartTime = "2", "2", 10.1
+	ms2.Precursor = Precursor{ParentScan: "1", ChargeState: 2, SelectedIon: 600.25, TargetIon: 600.25}
+	ms2.Mz.DecodedStream = []float64{126.1277, 127.1248, 500.2}
+	ms2.Intensity.DecodedStream = []float64{200, 300, 50}
+
+	f := filepath.Join(dir, "run.mzML")
+
+	var w psi.MzMLWriter
+	header := mzMLHeader(f, "run", "test")
+	header.Run.SpectrumList.Count = 2
+	w.Create(f, header, true)
+	w.Write(toPsiSpectrum(ms1, 0, met.Msconvert{MZBinaryEncoding: "64", IntensityBinaryEncoding: "64"}))
+	w.Write(toPsiSpectrum(ms2, 1, met.Msconvert{MZBinaryEncoding: "64", IntensityBinaryEncoding: "64"}))
+	w.Close()
+
+	for _, cached := range []bool{false, true} {
+
+		var r Reader
+		r.OpenCached(f)
+
+		if _, e := os.Stat(filepath.Join(cacheDir(), cacheName(fileChecksum(f)))); e != nil {
+			t.Fatalf("OpenCached() did not write the cache: %v", e)
+		}
+
+		if _, ok := r.src.(*cacheSource); !ok {
+			t.Fatalf("OpenCached() reads from %T, cached %v", r.src, cached)
+		}
+
+		// MS1 spectra are complete, MSn spectra keep the precursor and the reporter region
+		got := r.Spectrum(0)
+		if got.Level != "1" || !reflect.DeepEqual(got.Mz.DecodedStream, ms1.Mz.DecodedStream) || !reflect.DeepEqual(got.Intensity.DecodedStream, ms1.Intensity.DecodedStream) {
+			t.Errorf("cached MS1 spectrum = %+v", got)
+		}
+
+		got, ok := r.Scan("2")
+		if !ok || got.Precursor.ChargeState != 2 || got.Precursor.SelectedIon != 600.25 || !reflect.DeepEqual(got.Mz.DecodedStream, []float64{126.1277, 127.1248}) {
+			t.Errorf("cached MS2 spectrum = %+v", got)
+		}
+
+		r.Close()
+	}
+
+	if got := DecodedSize(f); got != 5*peakSize {
+		t.Errorf("DecodedSize() = %d, want %d", got, 5*peakSize)
+	}
+}
+
+func Test_cacheSource_open(t *testing.T) {
+
+	dir := t.TempDir()
+
+	bin := filepath.Join(dir, cacheName("abc"))
+	if e := ioutil.WriteFile(bin, []byte{1, 2, 3}, 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	// an incomplete cache is parsed again
+	var c cacheSource
+	if e := c.open(bin, "abc"); e == nil {
+		t.Errorf("open() read an incomplete cache")
+	}
+}
+
+func TestDecodedSize(t *testing.T) {
+
+	dir := t.TempDir()
+
+	defer func(f func() string) { cacheDir = f }(cacheDir)
+	cacheDir = func() string { return filepath.Join(dir, "spectra") }
+
+	f := filepath.Join(dir, "run.mzML")
+	if e := ioutil.WriteFile(f, make([]byte, 100), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	// files without a cache are estimated from their size
+	if got := DecodedSize(f); got != 100*encodedExpansion {
+		t.Errorf("DecodedSize() = %d, want %d", got, 100*encodedExpansion)
+	}
+
+	if got := DecodedSize(filepath.Join(dir, "missing.mzML")); got != 0 {
+		t.Errorf("DecodedSize() = %d for a missing file", got)
+	}
+}
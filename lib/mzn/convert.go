@@ -0,0 +1,378 @@
+package mzn
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Convert writes the spectra from the given file to mzML or MGF, following the msconvert options.
+// The output file is created in the output directory, or next to the input file, and its path is returned
+func Convert(m met.Data, f string) string {
+
+	p := m.Msconvert
+
+	var r Reader
+	r.Open(f)
+	defer r.Close()
+
+	dir := p.Output
+	if len(dir) == 0 {
+		dir = filepath.Dir(f)
+	}
+
+	base := RunName(f)
+
+	var output string
+
+	switch strings.ToLower(p.Format) {
+	case "mzml":
+		output = filepath.Join(dir, base+".mzML")
+	case "mgf":
+		output = filepath.Join(dir, base+".mgf")
+	default:
+		msg.Custom(errors.New("Unsupported output format, choose between mzML and mgf"), "fatal")
+	}
+
+	// the input file is never replaced by its own conversion
+	in, _ := filepath.Abs(f)
+	out, _ := filepath.Abs(output)
+	if strings.EqualFold(in, out) {
+		msg.Custom(errors.New("The converted file would replace "+filepath.Base(f)+", choose another output directory"), "fatal")
+	}
+
+	logrus.Info("Converting ", filepath.Base(f), " to ", filepath.Base(output))
+
+	if strings.EqualFold(p.Format, "mgf") {
+		writeMGF(&r, base, output)
+	} else {
+		writeMzML(&r, base, output, m.Version, p)
+	}
+
+	return output
+}
+
+// nativeID builds the Thermo style identifier from the scan number
+func nativeID(scan string) string {
+	return "controllerType=0 controllerNumber=1 scan=" + scan
+}
+
+// writeMzML streams the spectra to an indexed mzML file
+func writeMzML(r *Reader, base, output, version string, p met.Msconvert) {
+
+	var w psi.MzMLWriter
+
+	mzml := mzMLHeader(r.FileName, base, version)
+	mzml.Run.SpectrumList.Count = r.Len()
+
+	w.Create(output, mzml, !p.NoIndex)
+
+	for i := 0; i < r.Len(); i++ {
+		spec := r.Spectrum(i)
+		spec.Decode()
+		w.Write(toPsiSpectrum(spec, i, p))
+	}
+
+	w.Close()
+
+	return
+}
+
+// mzMLHeader describes the converted file and the conversion process
+func mzMLHeader(f, base, version string) psi.MzML {
+
+	var mzml psi.MzML
+
+	mzml.ID = base
+
+	mzml.CvList.Count = 2
+	mzml.CvList.CV = []psi.CV{
+		{ID: "MS", FullName: "Proteomics Standards Initiative Mass Spectrometry Ontology", Version: "4.1.30", URI: "https://raw.githubusercontent.com/HUPO-PSI/psi-ms-CV/master/psi-ms.obo"},
+		{ID: "UO", FullName: "Unit Ontology", Version: "09:04:2014", URI: "https://raw.githubusercontent.com/bio-ontology-research-group/unit-ontology/master/unit.obo"},
+	}
+
+	mzml.FileDescription.FileContent.CVParam = []psi.CVParam{
+		{CVRef: "MS", Accession: "MS:1000579", Name: "MS1 spectrum"},
+		{CVRef: "MS", Accession: "MS:1000580", Name: "MSn spectrum"},
+	}
+
+	format := psi.CVParam{CVRef: "MS", Accession: "MS:1000584", Name: "mzML format"}
+	if strings.EqualFold(filepath.Ext(f), ".raw") {
+		format = psi.CVParam{CVRef: "MS", Accession: "MS:1000563", Name: "Thermo RAW format"}
+	}
+
+	location, _ := filepath.Abs(filepath.Dir(f))
+
+	mzml.FileDescription.SourceFileList.Count = 1
+	mzml.FileDescription.SourceFileList.SourceFile = []psi.MzMLSourceFile{
+		{
+			ID:       "RAW1",
+			Name:     filepath.Base(f),
+			Location: "file:///" + strings.TrimPrefix(filepath.ToSlash(location), "/"),
+			CVParam: []psi.CVParam{
+				{CVRef: "MS", Accession: "MS:1000768", Name: "Thermo nativeID format"},
+				format,
+			},
+		},
+	}
+
+	mzml.SoftwareList.Count = 1
+	mzml.SoftwareList.Software = []psi.Software{
+		{ID: "philosopher", Version: version, CVParam: []psi.CVParam{{CVRef: "MS", Accession: "MS:1000799", Name: "custom unreleased software tool", Value: "philosopher"}}},
+	}
+
+	var ic psi.InstrumentConfiguration
+	ic.ID = "IC1"
+	ic.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000031", Name: "instrument model"}}
+	ic.ComponentList.Count = 3
+	ic.ComponentList.Source.Order = 1
+	ic.ComponentList.Source.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000008", Name: "ionization type"}}
+	ic.ComponentList.Analyzer.Order = 2
+	ic.ComponentList.Analyzer.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000443", Name: "mass analyzer type"}}
+	ic.ComponentList.Detector.Order = 3
+	ic.ComponentList.Detector.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000026", Name: "detector type"}}
+	ic.SoftwareRef.Ref = "philosopher"
+
+	mzml.InstrumentConfigurationList.Count = 1
+	mzml.InstrumentConfigurationList.InstrumentConfiguration = []psi.InstrumentConfiguration{ic}
+
+	mzml.DataProcessingList.Count = 1
+	mzml.DataProcessingList.DataProcessing = []psi.DataProcessing{
+		{
+			ID: "philosopher_conversion",
+			ProcessingMethod: []psi.ProcessingMethod{
+				{Order: 1, SoftwareRef: "philosopher", CVParam: []psi.CVParam{{CVRef: "MS", Accession: "MS:1000544", Name: "Conversion to mzML"}}},
+			},
+		},
+	}
+
+	mzml.Run.ID = base
+	mzml.Run.DefaultInstrumentConfigurationRef = "IC1"
+	mzml.Run.DefaultSourceFileRef = "RAW1"
+	mzml.Run.SpectrumList.DefaultDataProcessingRef = "philosopher_conversion"
+
+	return mzml
+}
+
+// toPsiSpectrum converts a decoded spectrum to the mzML model
+func toPsiSpectrum(spec Spectrum, i int, p met.Msconvert) psi.Spectrum {
+
+	var s psi.Spectrum
+
+	s.ID = nativeID(spec.Scan)
+	s.Index = strconv.Itoa(i)
+	s.DefaultArrayLength = len(spec.Mz.DecodedStream)
+
+	kind := psi.CVParam{CVRef: "MS", Accession: "MS:1000580", Name: "MSn spectrum"}
+	if spec.Level == "1" {
+		kind = psi.CVParam{CVRef: "MS", Accession: "MS:1000579", Name: "MS1 spectrum"}
+	}
+
+	s.CVParam = []psi.CVParam{
+		{CVRef: "MS", Accession: "MS:1000511", Name: "ms level", Value: spec.Level},
+		kind,
+		{CVRef: "MS", Accession: "MS:1000127", Name: "centroid spectrum"},
+	}
+
+	if len(spec.Intensity.DecodedStream) > 0 {
+
+		var tic, bpi, bpmz float64
+		for j, v := range spec.Intensity.DecodedStream {
+			tic += v
+			if v > bpi {
+				bpi = v
+				bpmz = spec.Mz.DecodedStream[j]
+			}
+		}
+
+		s.CVParam = append(s.CVParam,
+			psi.CVParam{CVRef: "MS", Accession: "MS:1000504", Name: "base peak m/z", Value: formatFloat(bpmz), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"},
+			psi.CVParam{CVRef: "MS", Accession: "MS:1000505", Name: "base peak intensity", Value: formatFloat(bpi), UnitCvRef: "MS", UnitAccession: "MS:1000131", UnitName: "number of detector counts"},
+			psi.CVParam{CVRef: "MS", Accession: "MS:1000285", Name: "total ion current", Value: formatFloat(tic)},
+			psi.CVParam{CVRef: "MS", Accession: "MS:1000528", Name: "lowest observed m/z", Value: formatFloat(spec.Mz.DecodedStream[0]), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"},
+			psi.CVParam{CVRef: "MS", Accession: "MS:1000527", Name: "highest observed m/z", Value: formatFloat(spec.Mz.DecodedStream[len(spec.Mz.DecodedStream)-1]), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"},
+		)
+	}
+
+	s.ScanList.Count = 1
+	s.ScanList.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000795", Name: "no combination"}}
+	s.ScanList.Scan = []psi.Scan{
+		{CVParam: []psi.CVParam{{CVRef: "MS", Accession: "MS:1000016", Name: "scan start time", Value: formatFloat(spec.ScanStartTime), UnitCvRef: "UO", UnitAccession: "UO:0000031", UnitName: "minute"}}},
+	}
+
+	if spec.Level != "1" && spec.Precursor.SelectedIon > 0 {
+
+		var prec psi.Precursor
+
+		if len(spec.Precursor.ParentScan) > 0 {
+			prec.SpectrumRef = nativeID(spec.Precursor.ParentScan)
+		}
+
+		if spec.Precursor.TargetIon > 0 {
+			prec.IsolationWindow.CVParam = []psi.CVParam{
+				{CVRef: "MS", Accession: "MS:1000827", Name: "isolation window target m/z", Value: formatFloat(spec.Precursor.TargetIon), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"},
+				{CVRef: "MS", Accession: "MS:1000828", Name: "isolation window lower offset", Value: formatFloat(spec.Precursor.IsolationWindowLowerOffset), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"},
+				{CVRef: "MS", Accession: "MS:1000829", Name: "isolation window upper offset", Value: formatFloat(spec.Precursor.IsolationWindowUpperOffset), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"},
+			}
+		}
+
+		var ion psi.SelectedIon
+		ion.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000744", Name: "selected ion m/z", Value: formatFloat(spec.Precursor.SelectedIon), UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"}}
+		if spec.Precursor.ChargeState > 0 {
+			ion.CVParam = append(ion.CVParam, psi.CVParam{CVRef: "MS", Accession: "MS:1000041", Name: "charge state", Value: strconv.Itoa(spec.Precursor.ChargeState)})
+		}
+		if spec.Precursor.SelectedIonIntensity > 0 {
+			ion.CVParam = append(ion.CVParam, psi.CVParam{CVRef: "MS", Accession: "MS:1000042", Name: "peak intensity", Value: formatFloat(spec.Precursor.SelectedIonIntensity), UnitCvRef: "MS", UnitAccession: "MS:1000131", UnitName: "number of detector counts"})
+		}
+
+		prec.SelectedIonList.Count = 1
+		prec.SelectedIonList.SelectedIon = []psi.SelectedIon{ion}
+		prec.Activation.CVParam = []psi.CVParam{{CVRef: "MS", Accession: "MS:1000044", Name: "dissociation method"}}
+
+		s.PrecursorList.Count = 1
+		s.PrecursorList.Precursor = []psi.Precursor{prec}
+	}
+
+	s.BinaryDataArrayList.Count = 2
+	s.BinaryDataArrayList.BinaryDataArray = []psi.BinaryDataArray{
+		binaryArray(spec.Mz.DecodedStream, p.MZBinaryEncoding, p.Zlib, psi.CVParam{CVRef: "MS", Accession: "MS:1000514", Name: "m/z array", UnitCvRef: "MS", UnitAccession: "MS:1000040", UnitName: "m/z"}),
+		binaryArray(spec.Intensity.DecodedStream, p.IntensityBinaryEncoding, p.Zlib, psi.CVParam{CVRef: "MS", Accession: "MS:1000515", Name: "intensity array", UnitCvRef: "MS", UnitAccession: "MS:1000131", UnitName: "number of detector counts"}),
+	}
+
+	return s
+}
+
+// binaryArray encodes the values with the given precision and optional zlib compression
+func binaryArray(values []float64, precision string, compress bool, array psi.CVParam) psi.BinaryDataArray {
+
+	var a psi.BinaryDataArray
+
+	stream := writeEncoded(values, precision, compress)
+
+	a.Binary.Value = stream
+	a.EncodedLength = len(stream)
+
+	if precision == "32" {
+		a.CVParam = append(a.CVParam, psi.CVParam{CVRef: "MS", Accession: "MS:1000521", Name: "32-bit float"})
+	} else {
+		a.CVParam = append(a.CVParam, psi.CVParam{CVRef: "MS", Accession: "MS:1000523", Name: "64-bit float"})
+	}
+
+	if compress {
+		a.CVParam = append(a.CVParam, psi.CVParam{CVRef: "MS", Accession: "MS:1000574", Name: "zlib compression"})
+	} else {
+		a.CVParam = append(a.CVParam, psi.CVParam{CVRef: "MS", Accession: "MS:1000576", Name: "no compression"})
+	}
+
+	a.CVParam = append(a.CVParam, array)
+
+	return a
+}
+
+// writeEncoded transforms the float64 values into little-endian, base64 encoded binary data
+func writeEncoded(values []float64, precision string, compress bool) []byte {
+
+	var raw bytes.Buffer
+
+	if precision == "32" {
+		b := make([]byte, 4)
+		for _, v := range values {
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+			raw.Write(b)
+		}
+	} else {
+		b := make([]byte, 8)
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+			raw.Write(b)
+		}
+	}
+
+	data := raw.Bytes()
+
+	if compress {
+		var z bytes.Buffer
+		w := zlib.NewWriter(&z)
+		w.Write(data)
+		w.Close()
+		data = z.Bytes()
+	}
+
+	stream := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(stream, data)
+
+	return stream
+}
+
+// writeMGF writes the MSn spectra to an MGF file. Titles follow the pepXML spectrum
+// naming, so search results can be matched back to the converted scans
+func writeMGF(r *Reader, base, output string) {
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+
+	for i := 0; i < r.Len(); i++ {
+
+		spec := r.Spectrum(i)
+		if spec.Level == "1" {
+			continue
+		}
+		spec.Decode()
+
+		scan, _ := strconv.Atoi(spec.Scan)
+
+		fmt.Fprintln(w, "BEGIN IONS")
+		fmt.Fprintf(w, "TITLE=%s.%05d.%05d.%d\n", base, scan, scan, spec.Precursor.ChargeState)
+		fmt.Fprintf(w, "RTINSECONDS=%s\n", formatFloat(spec.ScanStartTime*60))
+
+		if spec.Precursor.SelectedIonIntensity > 0 {
+			fmt.Fprintf(w, "PEPMASS=%s %s\n", formatFloat(spec.Precursor.SelectedIon), formatFloat(spec.Precursor.SelectedIonIntensity))
+		} else {
+			fmt.Fprintf(w, "PEPMASS=%s\n", formatFloat(spec.Precursor.SelectedIon))
+		}
+
+		if spec.Precursor.ChargeState > 0 {
+			fmt.Fprintf(w, "CHARGE=%d+\n", spec.Precursor.ChargeState)
+		}
+
+		fmt.Fprintf(w, "SCANS=%d\n", scan)
+
+		for j := range spec.Mz.DecodedStream {
+			fmt.Fprintf(w, "%s %s\n", formatFloat(spec.Mz.DecodedStream[j]), formatFloat(spec.Intensity.DecodedStream[j]))
+		}
+
+		fmt.Fprintln(w, "END IONS")
+		fmt.Fprintln(w)
+	}
+
+	if e := w.Flush(); e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	return
+}
+
+// formatFloat prints the value using the shortest decimal representation
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,175 @@
+package mzn
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"philosopher/lib/met"
+	"philosopher/lib/psi"
+)
+
+func Test_writeEncoded(t *testing.T) {
+
+	values := []float64{126.127726, 127.124761, 1500.5}
+
+	tests := []struct {
+		name      string
+		precision string
+		compress  bool
+		want      []float64
+	}{
+		{
+			name:      "Testing 64-bit encoding",
+			precision: "64",
+			want:      values,
+		},
+		{
+			name:      "Testing 64-bit zlib encoding",
+			precision: "64",
+			compress:  true,
+			want:      values,
+		},
+		{
+			name:      "Testing 32-bit zlib encoding",
+			precision: "32",
+			compress:  true,
+			want:      []float64{float64(float32(values[0])), float64(float32(values[1])), float64(float32(values[2]))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compression := "0"
+			if tt.compress {
+				compression = "1"
+			}
+			if got := readEncoded(writeEncoded(values, tt.precision, tt.compress), tt.precision, compression, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("writeEncoded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeMzML(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "convert")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	mgf := filepath.Join(dir, "run.mgf")
+	content := "BEGIN IONS\nTITLE=run.00010.00010.2\nPEPMASS=500.25 1000\nCHARGE=2+\nRTINSECONDS=60\n126.1277 200\n127.1248 300\n500.2 50\nEND IONS\n" +
+		"BEGIN IONS\nTITLE=run.00012.00012.3\nPEPMASS=650.5\nCHARGE=3+\nRTINSECONDS=90\n130.1 10\n700.3 20\nEND IONS\n"
+	if e := ioutil.WriteFile(mgf, []byte(content), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	var r Reader
+	r.Open(mgf)
+
+	var want []Spectrum
+	r.AllSpectra(func(spec Spectrum) {
+		want = append(want, spec)
+	})
+
+	output := filepath.Join(dir, "run.mzML")
+	writeMzML(&r, "run", output, "test", met.Msconvert{MZBinaryEncoding: "64", IntensityBinaryEncoding: "32", Zlib: true})
+	r.Close()
+
+	b, e := ioutil.ReadFile(output)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// the checksum covers the document up to and including the opening fileChecksum tag
+	m := regexp.MustCompile(`<fileChecksum>([0-9a-f]{40})</fileChecksum>`).FindSubmatchIndex(b)
+	if m == nil {
+		t.Fatal("writeMzML() did not write the file checksum")
+	}
+	sum := sha1.Sum(b[:m[2]])
+	if hex.EncodeToString(sum[:]) != string(b[m[2]:m[3]]) {
+		t.Errorf("writeMzML() checksum = %s, want %s", b[m[2]:m[3]], hex.EncodeToString(sum[:]))
+	}
+
+	m = regexp.MustCompile(`<indexListOffset>(\d+)</indexListOffset>`).FindSubmatchIndex(b)
+	if m == nil {
+		t.Fatal("writeMzML() did not write the index list offset")
+	}
+	pos, _ := strconv.Atoi(string(b[m[2]:m[3]]))
+	if !bytes.HasPrefix(b[pos:], []byte("<indexList")) {
+		t.Errorf("writeMzML() index list offset %d does not point to the indexList", pos)
+	}
+
+	var mzml psi.MzMLReader
+	mzml.Open(output)
+	defer mzml.Close()
+
+	if mzml.Len() != len(want) {
+		t.Fatalf("MzMLReader.Len() = %d, want %d", mzml.Len(), len(want))
+	}
+
+	for i := range want {
+
+		if !bytes.HasPrefix(b[mzml.Offsets[i].Value:], []byte("<spectrum ")) || mzml.Offsets[i].IDRef != nativeID(want[i].Scan) {
+			t.Errorf("spectrum %d offset = %+v", i, mzml.Offsets[i])
+		}
+
+		spec := mzml.Read(i)
+		got := processSpectrum(spec)
+		got.Decode()
+
+		if spec.ID != nativeID(want[i].Scan) || got.Level != want[i].Level || got.Precursor.SelectedIon != want[i].Precursor.SelectedIon || got.Precursor.ChargeState != want[i].Precursor.ChargeState {
+			t.Errorf("spectrum %d = %+v, want %+v", i, got, want[i])
+		}
+
+		if !reflect.DeepEqual(got.Mz.DecodedStream, want[i].Mz.DecodedStream) {
+			t.Errorf("spectrum %d m/z = %v, want %v", i, got.Mz.DecodedStream, want[i].Mz.DecodedStream)
+		}
+
+		for j, v := range want[i].Intensity.DecodedStream {
+			if got.Intensity.DecodedStream[j] != float64(float32(v)) {
+				t.Errorf("spectrum %d intensity = %v, want %v", i, got.Intensity.DecodedStream, want[i].Intensity.DecodedStream)
+				break
+			}
+		}
+	}
+}
+
+func Test_writeMGF(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "convert")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	mgf := filepath.Join(dir, "input.mgf")
+	if e := ioutil.WriteFile(mgf, []byte("BEGIN IONS\nTITLE=run.00010.00010.2\nPEPMASS=500.25\nCHARGE=2+\nRTINSECONDS=60\n126.1277 200\nEND IONS\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	var r Reader
+	r.Open(mgf)
+
+	output := filepath.Join(dir, "run.mgf")
+	writeMGF(&r, "run", output)
+	r.Close()
+
+	b, e := ioutil.ReadFile(output)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// the titles follow the pepXML spectrum names, charge included
+	if !bytes.Contains(b, []byte("TITLE=run.00010.00010.2\n")) || !bytes.Contains(b, []byte("CHARGE=2+\n")) {
+		t.Errorf("writeMGF() = %s", b)
+	}
+}
@@ -0,0 +1,178 @@
+package mzn
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+)
+
+// mgfSource reads the MSn spectra from MGF files. Every BEGIN IONS block is located
+// when the file is opened, and parsed again only when the spectrum is requested
+type mgfSource struct {
+	offsets []int64
+	numbers []int
+	scans   map[int]int
+	file    *os.File
+	size    int64
+}
+
+var (
+	// pepXML style titles, as written by MSFragger and the convert command: run.00123.00123.2
+	mgfTitleRegex = regexp.MustCompile(`\.(\d+)\.(\d+)\.\d+$`)
+	// msconvert style titles: run.123.123.2 File:"run.raw", NativeID:"... scan=123"
+	mgfNativeRegex = regexp.MustCompile(`scan=(\d+)`)
+)
+
+// open locates the spectra in the MGF file and their scan numbers
+func (s *mgfSource) open(f string) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	info, e := file.Stat()
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	s.file = file
+	s.size = info.Size()
+	s.scans = make(map[int]int)
+
+	var pos int64
+	var title string
+	var scan int
+
+	reader := bufio.NewReader(io.NewSectionReader(file, 0, s.size))
+
+	for {
+		line, e := reader.ReadString('\n')
+		if len(line) == 0 && e != nil {
+			break
+		}
+
+		start := pos
+		pos += int64(len(line))
+		line = strings.TrimSpace(line)
+
+		if line == "BEGIN IONS" {
+			s.offsets = append(s.offsets, start)
+			title, scan = "", 0
+		} else if strings.HasPrefix(line, "TITLE=") {
+			title = line[6:]
+		} else if strings.HasPrefix(line, "SCANS=") {
+			scan = atoi(strings.Split(line[6:], "-")[0])
+		} else if line == "END IONS" {
+			if scan == 0 {
+				scan = mgfTitleScan(title)
+			}
+			if scan == 0 {
+				scan = len(s.offsets)
+			}
+			s.numbers = append(s.numbers, scan)
+			s.scans[scan] = len(s.offsets) - 1
+		}
+	}
+
+	return
+}
+
+func (s *mgfSource) Len() int     { return len(s.numbers) }
+func (s *mgfSource) Close() error { return s.file.Close() }
+
+// locate returns the position of the scan number in the file
+func (s *mgfSource) locate(scan int) (int, bool) {
+	i, ok := s.scans[scan]
+	return i, ok
+}
+
+// Spectrum parses the spectrum block at the given index. MGF files only keep MSn
+// spectra, so there is no reference to the parent scan
+func (s *mgfSource) Spectrum(i int) Spectrum {
+
+	var spec Spectrum
+
+	spec.Index = strconv.Itoa(i)
+	spec.Scan = strconv.Itoa(s.numbers[i])
+	spec.Level = "2"
+
+	scanner := bufio.NewScanner(io.NewSectionReader(s.file, s.offsets[i], s.size-s.offsets[i]))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "END IONS" {
+			break
+		} else if len(line) == 0 || line == "BEGIN IONS" {
+			continue
+		}
+
+		if idx := strings.Index(line, "="); idx > 0 && (line[0] < '0' || line[0] > '9') {
+
+			value := line[idx+1:]
+
+			switch line[:idx] {
+			case "PEPMASS":
+				fields := strings.Fields(value)
+				if len(fields) > 0 {
+					spec.Precursor.SelectedIon, _ = strconv.ParseFloat(fields[0], 64)
+					spec.Precursor.TargetIon = spec.Precursor.SelectedIon
+				}
+				if len(fields) > 1 {
+					spec.Precursor.SelectedIonIntensity, _ = strconv.ParseFloat(fields[1], 64)
+				}
+			case "CHARGE":
+				spec.Precursor.ChargeState = atoi(strings.TrimRight(strings.Fields(value + " ")[0], "+-"))
+			case "RTINSECONDS":
+				rt, _ := strconv.ParseFloat(strings.Split(value, "-")[0], 64)
+				spec.ScanStartTime = rt / 60
+			case "MSLEVEL":
+				spec.Level = value
+			}
+
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		mz, e1 := strconv.ParseFloat(fields[0], 64)
+		intensity, e2 := strconv.ParseFloat(fields[1], 64)
+		if e1 != nil || e2 != nil {
+			continue
+		}
+
+		spec.Mz.DecodedStream = append(spec.Mz.DecodedStream, mz)
+		spec.Intensity.DecodedStream = append(spec.Intensity.DecodedStream, intensity)
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return spec
+}
+
+// mgfTitleScan extracts the scan number from the spectrum title
+func mgfTitleScan(title string) int {
+
+	if m := mgfNativeRegex.FindStringSubmatch(title); m != nil {
+		return atoi(m[1])
+	}
+
+	if m := mgfTitleRegex.FindStringSubmatch(strings.Fields(title + " ")[0]); m != nil {
+		return atoi(m[1])
+	}
+
+	return 0
+}
@@ -0,0 +1,35 @@
+package mzn
+
+import "testing"
+
+func Test_mgfTitleScan(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		title string
+		want  int
+	}{
+		{
+			name:  "Testing pepXML style titles",
+			title: "01_CPTAC_TMTS1-NCI7_Z_JHUZ_20170502_LUMOS.03130.03130.2",
+			want:  3130,
+		},
+		{
+			name:  "Testing msconvert style titles",
+			title: `run.3130.3130.2 File:"run.raw", NativeID:"controllerType=0 controllerNumber=1 scan=3130"`,
+			want:  3130,
+		},
+		{
+			name:  "Testing titles without scan numbers",
+			title: "spectrum",
+			want:  0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mgfTitleScan(tt.title); got != tt.want {
+				t.Errorf("mgfTitleScan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"math"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"philosopher/lib/msg"
 
@@ -39,6 +42,7 @@ type Spectrum struct {
 	Mz            Mz
 	Intensity     Intensity
 	IonMobility   IonMobility
+	Noise         Noise
 }
 
 // Precursor struct
@@ -52,6 +56,7 @@ type Precursor struct {
 	TargetIonIntensity         float64
 	IsolationWindowLowerOffset float64
 	IsolationWindowUpperOffset float64
+	SPSIons                    []float64
 }
 
 // Mz struct
@@ -60,6 +65,7 @@ type Mz struct {
 	DecodedStream []float64
 	Precision     string
 	Compression   string
+	Numpress      string
 }
 
 // Intensity struct
@@ -68,6 +74,7 @@ type Intensity struct {
 	DecodedStream []float64
 	Precision     string
 	Compression   string
+	Numpress      string
 }
 
 // IonMobility struct
@@ -76,35 +83,187 @@ type IonMobility struct {
 	DecodedStream []float64
 	Precision     string
 	Compression   string
+	Numpress      string
+}
+
+// Noise holds the noise level of each peak, only the Thermo RAW files carry it
+type Noise struct {
+	DecodedStream []float64
 }
 
 func (a Spectra) Len() int           { return len(a) }
 func (a Spectra) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a Spectra) Less(i, j int) bool { return a[i].Index < a[j].Index }
 
-// Read is the main function for parsing mzML data
+// Read is the main function for parsing spectra, the format is defined by the file extension (mzML, mzXML, mgf or raw)
 func (p *MsData) Read(f string) {
 
-	var xml psi.IndexedMzML
-	xml.Parse(f)
+	var r Reader
+	r.Open(f)
+	defer r.Close()
 
 	p.FileName = f
 
 	var spectra Spectra
-	sl := xml.MzML.Run.SpectrumList
 
-	for _, i := range sl.Spectrum {
+	r.AllSpectra(func(spec Spectrum) {
+		spectra = append(spectra, spec)
+	})
+
+	if len(spectra) == 0 {
+		msg.NoSpectraFound(errors.New(""), "fatal")
+	}
+
+	p.Spectra = spectra
+
+	return
+}
+
+// Reader is a lazy, index-aware spectrum reader. Spectra are only parsed from disk when
+// requested, and their binary arrays are only decoded when Decode is called
+type Reader struct {
+	FileName string
+	src      source
+	levels   []string
+}
 
-		spectrum := processSpectrum(i)
+// source is implemented by every spectrum file format supported by the Reader
+type source interface {
+	Len() int
+	Spectrum(i int) Spectrum
+	Close() error
+}
 
-		spectra = append(spectra, spectrum)
+// scanLocator is implemented by the sources where scan numbers do not follow the spectrum index
+type scanLocator interface {
+	locate(scan int) (int, bool)
+}
+
+// mzMLSource reads spectra from indexed or plain mzML files
+type mzMLSource struct {
+	mzml psi.MzMLReader
+}
+
+func (s *mzMLSource) Len() int                { return s.mzml.Len() }
+func (s *mzMLSource) Spectrum(i int) Spectrum { return processSpectrum(s.mzml.Read(i)) }
+func (s *mzMLSource) Close() error            { return s.mzml.Close() }
+
+// Open indexes the spectrum file for random access, the format is defined by the file extension
+func (r *Reader) Open(f string) {
+
+	r.FileName = f
+
+	switch strings.ToLower(filepath.Ext(f)) {
+	case ".raw":
+		var raw rawSource
+		raw.open(f)
+		r.src = &raw
+	case ".mzxml":
+		var mzxml mzXMLSource
+		mzxml.open(f)
+		r.src = &mzxml
+	case ".mgf":
+		var mgf mgfSource
+		mgf.open(f)
+		r.src = &mgf
+	default:
+		var mzml mzMLSource
+		mzml.mzml.Open(f)
+		r.src = &mzml
 	}
 
-	if len(spectra) == 0 {
+	r.levels = make([]string, r.src.Len())
+
+	if r.src.Len() == 0 {
 		msg.NoSpectraFound(errors.New(""), "fatal")
 	}
 
-	p.Spectra = spectra
+	return
+}
+
+// RunName returns the file name without directory and extensions, gzip compressed files included
+func RunName(f string) string {
+
+	name := filepath.Base(f)
+	if strings.EqualFold(filepath.Ext(name), ".gz") {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Close closes the spectrum file
+func (r *Reader) Close() error {
+	return r.src.Close()
+}
+
+// Len returns the number of spectra in the file
+func (r *Reader) Len() int {
+	return r.src.Len()
+}
+
+// Spectrum returns the spectrum at the given index. The spectrum name follows the
+// pepXML spectrum IDs, built from the run name, the scan number and the charge state
+func (r *Reader) Spectrum(i int) Spectrum {
+
+	spec := r.src.Spectrum(i)
+	r.levels[i] = spec.Level
+
+	scan, _ := strconv.Atoi(spec.Scan)
+	spec.SpectrumName = fmt.Sprintf("%s.%05d.%05d.%d", RunName(r.FileName), scan, scan, spec.Precursor.ChargeState)
+
+	return spec
+}
+
+// Scan returns the spectrum with the given scan number, padded or not
+func (r *Reader) Scan(s string) (Spectrum, bool) {
+
+	scan, e := strconv.Atoi(s)
+	if e != nil {
+		return Spectrum{}, false
+	}
+
+	if l, ok := r.src.(scanLocator); ok {
+		i, ok := l.locate(scan)
+		if !ok {
+			return Spectrum{}, false
+		}
+		return r.Spectrum(i), true
+	}
+
+	if scan < 1 || scan > r.Len() {
+		return Spectrum{}, false
+	}
+
+	return r.Spectrum(scan - 1), true
+}
+
+// AllSpectra is a convenience function that runs over all spectra in the file.
+// On every encountered spectrum, the function fun is called
+func (r *Reader) AllSpectra(fun func(spec Spectrum)) {
+
+	for i := 0; i < r.Len(); i++ {
+		fun(r.Spectrum(i))
+	}
+
+	return
+}
+
+// Level runs over the spectra from the given MS level, calling fun on each one of them.
+// Spectra with a known level that differs from the requested one are not parsed again
+func (r *Reader) Level(l string, fun func(spec Spectrum)) {
+
+	for i := 0; i < r.Len(); i++ {
+
+		if len(r.levels[i]) > 0 && r.levels[i] != l {
+			continue
+		}
+
+		spec := r.Spectrum(i)
+		if spec.Level == l {
+			fun(spec)
+		}
+	}
 
 	return
 }
@@ -242,53 +401,31 @@ func processSpectrum(mzSpec psi.Spectrum) Spectrum {
 				spec.Precursor.SelectedIonIntensity = val
 			}
 		}
-	}
-
-	spec.Mz.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[0].Binary.Value
-	for _, j := range mzSpec.BinaryDataArrayList.BinaryDataArray[0].CVParam {
-		if string(j.Accession) == "MS:1000523" {
-			spec.Mz.Precision = "64"
-		} else if string(j.Accession) == "MS:1000521" {
-			spec.Mz.Precision = "32"
-		}
 
-		if string(j.Accession) == "MS:1000574" {
-			spec.Mz.Compression = "1"
-		} else if string(j.Accession) == "MS:1000576" {
-			spec.Mz.Compression = "0"
+		// synchronous precursor selection lists every isolated MS2 fragment as a precursor of the MS3 scan
+		if spec.Level == "3" {
+			for _, i := range mzSpec.PrecursorList.Precursor {
+				for _, j := range i.IsolationWindow.CVParam {
+					if string(j.Accession) == "MS:1000827" {
+						val, e := strconv.ParseFloat(j.Value, 64)
+						if e == nil {
+							spec.Precursor.SPSIons = append(spec.Precursor.SPSIons, val)
+						}
+					}
+				}
+			}
 		}
 	}
 
-	spec.Intensity.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[1].Binary.Value
-	for _, j := range mzSpec.BinaryDataArrayList.BinaryDataArray[1].CVParam {
-		if string(j.Accession) == "MS:1000523" {
-			spec.Intensity.Precision = "64"
-		} else if string(j.Accession) == "MS:1000521" {
-			spec.Intensity.Precision = "32"
-		}
+	spec.Mz.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[0].Binary.Value
+	spec.Mz.Precision, spec.Mz.Compression, spec.Mz.Numpress = binaryArrayParams(mzSpec.BinaryDataArrayList.BinaryDataArray[0].CVParam)
 
-		if string(j.Accession) == "MS:1000574" {
-			spec.Intensity.Compression = "1"
-		} else if string(j.Accession) == "MS:1000576" {
-			spec.Intensity.Compression = "0"
-		}
-	}
+	spec.Intensity.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[1].Binary.Value
+	spec.Intensity.Precision, spec.Intensity.Compression, spec.Intensity.Numpress = binaryArrayParams(mzSpec.BinaryDataArrayList.BinaryDataArray[1].CVParam)
 
 	if mzSpec.BinaryDataArrayList.Count == 3 {
 		spec.IonMobility.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[2].Binary.Value
-		for _, j := range mzSpec.BinaryDataArrayList.BinaryDataArray[2].CVParam {
-			if string(j.Accession) == "MS:1000523" {
-				spec.IonMobility.Precision = "64"
-			} else if string(j.Accession) == "MS:1000521" {
-				spec.IonMobility.Precision = "32"
-			}
-
-			if string(j.Accession) == "MS:1000574" {
-				spec.IonMobility.Compression = "1"
-			} else if string(j.Accession) == "MS:1000576" {
-				spec.IonMobility.Compression = "0"
-			}
-		}
+		spec.IonMobility.Precision, spec.IonMobility.Compression, spec.IonMobility.Numpress = binaryArrayParams(mzSpec.BinaryDataArrayList.BinaryDataArray[2].CVParam)
 	}
 
 	return spec
@@ -298,23 +435,50 @@ func processSpectrum(mzSpec psi.Spectrum) Spectrum {
 func (s *Spectrum) Decode() {
 
 	if len(s.Mz.Stream) > 0 && len(s.Intensity.Stream) > 0 {
-		s.Mz.DecodedStream = readEncoded(s.Mz.Stream, s.Mz.Precision, s.Mz.Compression)
+		s.Mz.DecodedStream = readEncoded(s.Mz.Stream, s.Mz.Precision, s.Mz.Compression, s.Mz.Numpress)
 		s.Mz.Stream = nil
 
-		s.Intensity.DecodedStream = readEncoded(s.Intensity.Stream, s.Intensity.Precision, s.Intensity.Compression)
+		s.Intensity.DecodedStream = readEncoded(s.Intensity.Stream, s.Intensity.Precision, s.Intensity.Compression, s.Intensity.Numpress)
 		s.Intensity.Stream = nil
 	}
 
 	if len(s.IonMobility.Stream) > 0 {
-		s.IonMobility.DecodedStream = readEncoded(s.IonMobility.Stream, s.IonMobility.Precision, s.IonMobility.Compression)
+		s.IonMobility.DecodedStream = readEncoded(s.IonMobility.Stream, s.IonMobility.Precision, s.IonMobility.Compression, s.IonMobility.Numpress)
 		s.IonMobility.Stream = nil
 	}
 
 	return
 }
 
-// readEncoded transforms the binary data into float64 values
-func readEncoded(bin []byte, precision, isCompressed string) []float64 {
+// binaryArrayParams reads the precision, the zlib compression and the MS-Numpress scheme of a binary data array
+func binaryArrayParams(params []psi.CVParam) (precision, compression, numpress string) {
+
+	for _, j := range params {
+		switch j.Accession {
+		case "MS:1000523":
+			precision = "64"
+		case "MS:1000521":
+			precision = "32"
+		case "MS:1000574":
+			compression = "1"
+		case "MS:1000576":
+			compression = "0"
+		default:
+			if n, ok := numpressAccessions[j.Accession]; ok {
+				numpress = n.scheme
+				if n.zlib {
+					compression = "1"
+				}
+			}
+		}
+	}
+
+	return precision, compression, numpress
+}
+
+// readEncoded transforms the binary data into float64 values, MS-Numpress streams
+// are decoded after the zlib layer when a Numpress scheme is given
+func readEncoded(bin []byte, precision, isCompressed, numpress string) []float64 {
 
 	var stream []uint8
 	var floatArray []float64
@@ -338,6 +502,10 @@ func readEncoded(bin []byte, precision, isCompressed string) []float64 {
 
 	dataArray := bytestream.Bytes()
 
+	if len(numpress) > 0 {
+		return decodeNumpress(dataArray, numpress)
+	}
+
 	var counter int
 
 	if precision == "32" {
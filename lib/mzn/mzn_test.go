@@ -108,3 +108,37 @@ func TestMS2Spectra(t *testing.T) {
 		t.Errorf("Spectrum number is incorrect, got %f, want %f", spec.Precursor.IsolationWindowLowerOffset, 0.34999999404)
 	}
 }
+
+func TestReader(t *testing.T) {
+
+	var r mzn.Reader
+	r.Open("01_CPTAC_TMTS1-NCI7_Z_JHUZ_20170502_LUMOS.mzML")
+	defer r.Close()
+
+	if r.Len() != 54357 {
+		t.Errorf("Spectra number is incorrect, got %d, want %d", r.Len(), 54357)
+	}
+
+	s, ok := r.Scan("00003")
+	if !ok {
+		t.Errorf("Spectrum scan %s was not found", "00003")
+	}
+
+	s.Decode()
+
+	if s.Level != "2" {
+		t.Errorf("Spectrum level is incorrect, got %s, want %s", s.Level, "2")
+	}
+
+	if len(s.Mz.DecodedStream) != 231 {
+		t.Errorf("MS2 Spectra number is incorrect, got %d, want %d", len(s.Mz.DecodedStream), 231)
+	}
+
+	if s.Precursor.ParentScan != "2" {
+		t.Errorf("Spectrum parent scan is incorrect, got %s, want %d", s.Precursor.ParentScan, 2)
+	}
+
+	if s.Mz.DecodedStream[0] != 110.07147216796875 {
+		t.Errorf("Spectrum MZ is incorrect, got %f, want %f", s.Mz.DecodedStream[0], 110.07147216796875)
+	}
+}
@@ -0,0 +1,309 @@
+package mzn
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// mzXMLIndex is the scan index at the end of indexed mzXML files
+type mzXMLIndex struct {
+	XMLName xml.Name      `xml:"index"`
+	Name    string        `xml:"name,attr"`
+	Offset  []mzXMLOffset `xml:"offset"`
+}
+
+// mzXMLOffset is the byte position of a scan referenced by its number
+type mzXMLOffset struct {
+	ID    string `xml:"id,attr"`
+	Value int64  `xml:",chardata"`
+}
+
+// mzXMLScan tag, nested scans are skipped when decoding
+type mzXMLScan struct {
+	XMLName       xml.Name         `xml:"scan"`
+	Num           string           `xml:"num,attr"`
+	MsLevel       string           `xml:"msLevel,attr"`
+	RetentionTime string           `xml:"retentionTime,attr"`
+	PrecursorMz   []mzXMLPrecursor `xml:"precursorMz"`
+	Peaks         mzXMLPeaks       `xml:"peaks"`
+}
+
+// mzXMLPrecursor tag
+type mzXMLPrecursor struct {
+	PrecursorScanNum   string  `xml:"precursorScanNum,attr"`
+	PrecursorIntensity float64 `xml:"precursorIntensity,attr"`
+	PrecursorCharge    int     `xml:"precursorCharge,attr"`
+	WindowWideness     float64 `xml:"windowWideness,attr"`
+	Value              string  `xml:",chardata"`
+}
+
+// mzXMLPeaks tag
+type mzXMLPeaks struct {
+	Precision       string `xml:"precision,attr"`
+	ByteOrder       string `xml:"byteOrder,attr"`
+	CompressionType string `xml:"compressionType,attr"`
+	Value           []byte `xml:",chardata"`
+}
+
+// mzXMLSource reads spectra from indexed or plain mzXML files
+type mzXMLSource struct {
+	offsets []mzXMLOffset
+	scans   map[int]int
+	file    *os.File
+	size    int64
+}
+
+// open indexes the scans from the mzXML file
+func (s *mzXMLSource) open(f string) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	info, e := file.Stat()
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	s.offsets = s.readIndex()
+	if len(s.offsets) == 0 || !s.isScanAt(s.offsets[0].Value) {
+		s.offsets = s.scanOffsets()
+	}
+
+	s.scans = make(map[int]int)
+	for i, o := range s.offsets {
+		if n, e := strconv.Atoi(o.ID); e == nil {
+			s.scans[n] = i
+		}
+	}
+
+	return
+}
+
+func (s *mzXMLSource) Len() int     { return len(s.offsets) }
+func (s *mzXMLSource) Close() error { return s.file.Close() }
+
+// locate returns the position of the scan number in the file
+func (s *mzXMLSource) locate(scan int) (int, bool) {
+	i, ok := s.scans[scan]
+	return i, ok
+}
+
+// Spectrum parses the scan at the given index, peaks are decoded right away since
+// mzXML stores the m/z and intensity values interleaved in a single array
+func (s *mzXMLSource) Spectrum(i int) Spectrum {
+
+	var scan mzXMLScan
+	var spec Spectrum
+
+	decoder := xml.NewDecoder(bufio.NewReader(io.NewSectionReader(s.file, s.offsets[i].Value, s.size-s.offsets[i].Value)))
+	decoder.CharsetReader = charset.NewReader
+
+	if e := decoder.Decode(&scan); e != nil {
+		msg.DecodeMsgPck(e, "fatal")
+	}
+
+	spec.Index = strconv.Itoa(i)
+	spec.Scan = scan.Num
+	spec.Level = scan.MsLevel
+	spec.ScanStartTime = mzXMLRetentionTime(scan.RetentionTime)
+
+	if len(scan.PrecursorMz) > 0 {
+
+		prec := scan.PrecursorMz[0]
+
+		mz, e := strconv.ParseFloat(strings.TrimSpace(prec.Value), 64)
+		if e != nil {
+			msg.CastFloatToString(e, "error")
+		}
+
+		spec.Precursor.TargetIon = mz
+		spec.Precursor.SelectedIon = mz
+		spec.Precursor.SelectedIonIntensity = prec.PrecursorIntensity
+		spec.Precursor.ChargeState = prec.PrecursorCharge
+		spec.Precursor.IsolationWindowLowerOffset = prec.WindowWideness / 2
+		spec.Precursor.IsolationWindowUpperOffset = prec.WindowWideness / 2
+
+		if len(prec.PrecursorScanNum) > 0 {
+			spec.Precursor.ParentScan = prec.PrecursorScanNum
+			if p, ok := s.scans[atoi(prec.PrecursorScanNum)]; ok {
+				spec.Precursor.ParentIndex = strconv.Itoa(p)
+			}
+		}
+
+		if spec.Level == "3" {
+			for _, i := range scan.PrecursorMz {
+				if v, e := strconv.ParseFloat(strings.TrimSpace(i.Value), 64); e == nil {
+					spec.Precursor.SPSIons = append(spec.Precursor.SPSIons, v)
+				}
+			}
+		}
+	}
+
+	spec.Mz.DecodedStream, spec.Intensity.DecodedStream = readInterleaved(scan.Peaks)
+
+	return spec
+}
+
+// readIndex retrieves the scan offsets from the index at the end of the file
+func (s *mzXMLSource) readIndex() []mzXMLOffset {
+
+	var tail = int64(4096)
+	if tail > s.size {
+		tail = s.size
+	}
+
+	b := make([]byte, tail)
+	s.file.ReadAt(b, s.size-tail)
+
+	match := regexp.MustCompile(`<indexOffset>\s*(\d+)\s*</indexOffset>`).FindSubmatch(b)
+	if match == nil {
+		return nil
+	}
+
+	pos, e := strconv.ParseInt(string(match[1]), 10, 64)
+	if e != nil || pos <= 0 || pos >= s.size {
+		return nil
+	}
+
+	var index mzXMLIndex
+	decoder := xml.NewDecoder(bufio.NewReader(io.NewSectionReader(s.file, pos, s.size-pos)))
+	decoder.CharsetReader = charset.NewReader
+
+	if e := decoder.Decode(&index); e != nil || index.Name != "scan" {
+		return nil
+	}
+
+	return index.Offset
+}
+
+// isScanAt verifies that the offset points to the beginning of a scan tag
+func (s *mzXMLSource) isScanAt(pos int64) bool {
+
+	b := make([]byte, 5)
+	if _, e := s.file.ReadAt(b, pos); e != nil {
+		return false
+	}
+
+	return string(b) == "<scan"
+}
+
+// scanOffsets collects the scan offsets by walking over the whole file
+func (s *mzXMLSource) scanOffsets() []mzXMLOffset {
+
+	var offsets []mzXMLOffset
+
+	decoder := xml.NewDecoder(bufio.NewReader(io.NewSectionReader(s.file, 0, s.size)))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+		pos := decoder.InputOffset()
+
+		t, e := decoder.RawToken()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "scan" {
+			var o mzXMLOffset
+			for _, a := range se.Attr {
+				if a.Name.Local == "num" {
+					o.ID = a.Value
+				}
+			}
+			o.Value = pos
+			offsets = append(offsets, o)
+		}
+	}
+
+	return offsets
+}
+
+// mzXMLRetentionTime converts the xs:duration retention time to minutes
+func mzXMLRetentionTime(rt string) float64 {
+
+	rt = strings.TrimPrefix(strings.TrimSpace(rt), "PT")
+
+	var factor = 1.0 / 60.0
+	if strings.HasSuffix(rt, "M") {
+		factor = 1
+	}
+
+	v, e := strconv.ParseFloat(strings.TrimRight(rt, "SM"), 64)
+	if e != nil {
+		return 0
+	}
+
+	return v * factor
+}
+
+// readInterleaved decodes the m/z and intensity pairs from the mzXML peaks
+func readInterleaved(p mzXMLPeaks) ([]float64, []float64) {
+
+	var mz, intensity []float64
+
+	data, e := base64.StdEncoding.DecodeString(strings.TrimSpace(string(p.Value)))
+	if e != nil || len(data) == 0 {
+		return mz, intensity
+	}
+
+	if p.CompressionType == "zlib" {
+		r, e := zlib.NewReader(bytes.NewReader(data))
+		if e != nil {
+			msg.ReadingMzMLZlib(e, "error")
+			return mz, intensity
+		}
+
+		var b bytes.Buffer
+		io.Copy(&b, r)
+		data = b.Bytes()
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if p.ByteOrder == "little" {
+		order = binary.LittleEndian
+	}
+
+	var size = 4
+	if p.Precision == "64" {
+		size = 8
+	}
+
+	for i := 0; i+2*size <= len(data); i += 2 * size {
+		if size == 8 {
+			mz = append(mz, math.Float64frombits(order.Uint64(data[i:])))
+			intensity = append(intensity, math.Float64frombits(order.Uint64(data[i+size:])))
+		} else {
+			mz = append(mz, float64(math.Float32frombits(order.Uint32(data[i:]))))
+			intensity = append(intensity, float64(math.Float32frombits(order.Uint32(data[i+size:]))))
+		}
+	}
+
+	return mz, intensity
+}
+
+// atoi returns the integer value of the string, or zero when it is not a number
+func atoi(s string) int {
+	v, _ := strconv.Atoi(strings.TrimSpace(s))
+	return v
+}
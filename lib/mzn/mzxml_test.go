@@ -0,0 +1,54 @@
+package mzn
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func Test_mzXMLRetentionTime(t *testing.T) {
+
+	tests := []struct {
+		name string
+		rt   string
+		want float64
+	}{
+		{
+			name: "Testing retention time in seconds",
+			rt:   "PT1830S",
+			want: 30.5,
+		},
+		{
+			name: "Testing retention time in minutes",
+			rt:   "PT30.5M",
+			want: 30.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mzXMLRetentionTime(tt.rt); got != tt.want {
+				t.Errorf("mzXMLRetentionTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readInterleaved(t *testing.T) {
+
+	var data []byte
+	for _, v := range []float32{126.5, 10, 127.5, 20} {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, math.Float32bits(v))
+		data = append(data, b...)
+	}
+
+	peaks := mzXMLPeaks{Precision: "32", ByteOrder: "network", Value: []byte(base64.StdEncoding.EncodeToString(data))}
+
+	mz, intensity := readInterleaved(peaks)
+
+	if !reflect.DeepEqual(mz, []float64{126.5, 127.5}) || !reflect.DeepEqual(intensity, []float64{10, 20}) {
+		t.Errorf("readInterleaved() = %v %v", mz, intensity)
+	}
+}
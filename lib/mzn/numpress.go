@@ -0,0 +1,197 @@
+package mzn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"philosopher/lib/msg"
+)
+
+// MS-Numpress compression schemes, identified in the binaryDataArray by their PSI-MS accessions.
+// Numpress streams can also be zlib compressed on top, see Compression on Mz and Intensity
+const (
+	numpressLinear = "linear"
+	numpressPic    = "pic"
+	numpressSlof   = "slof"
+)
+
+// numpressAccessions maps the PSI-MS accessions to the Numpress scheme and to the
+// presence of an extra zlib compression layer
+var numpressAccessions = map[string]struct {
+	scheme string
+	zlib   bool
+}{
+	"MS:1002312": {numpressLinear, false},
+	"MS:1002313": {numpressPic, false},
+	"MS:1002314": {numpressSlof, false},
+	"MS:1002746": {numpressLinear, true},
+	"MS:1002747": {numpressPic, true},
+	"MS:1002748": {numpressSlof, true},
+}
+
+// decodeNumpress decodes the byte stream with the given Numpress scheme
+func decodeNumpress(data []byte, scheme string) []float64 {
+
+	var values []float64
+	var e error
+
+	switch scheme {
+	case numpressLinear:
+		values, e = decodeLinear(data)
+	case numpressPic:
+		values, e = decodePic(data)
+	case numpressSlof:
+		values, e = decodeSlof(data)
+	}
+
+	if e != nil {
+		msg.Custom(e, "error")
+	}
+
+	return values
+}
+
+// numpressFixedPoint reads the scaling factor stored as a big-endian double on the first 8 bytes
+func numpressFixedPoint(data []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(data[:8]))
+}
+
+// numpressInt reads a variable length integer, stored as half bytes. The first half byte gives
+// the number of leading zeros (0-8) or leading 0xf half bytes (9-15) that were omitted
+func numpressInt(data []byte, di, half *int) (uint32, error) {
+
+	var res uint32
+	var head byte
+
+	if *half == 0 {
+		head = data[*di] >> 4
+	} else {
+		head = data[*di] & 0xf
+		*di++
+	}
+	*half = 1 - *half
+
+	n := int(head)
+	if head > 8 {
+		n = int(head) - 8
+		for i := 0; i < n; i++ {
+			res |= 0xf0000000 >> uint(4*i)
+		}
+	}
+
+	if n == 8 {
+		return res, nil
+	}
+
+	if *di+((8-n)-(1-*half))/2 >= len(data) {
+		return res, errors.New("Corrupt MS-Numpress data")
+	}
+
+	for i := n; i < 8; i++ {
+		var hb byte
+		if *half == 0 {
+			hb = data[*di] >> 4
+		} else {
+			hb = data[*di] & 0xf
+			*di++
+		}
+		res |= uint32(hb) << uint((i-n)*4)
+		*half = 1 - *half
+	}
+
+	return res, nil
+}
+
+// decodeLinear reverses the linear prediction compression used for m/z values
+func decodeLinear(data []byte) ([]float64, error) {
+
+	var values []float64
+
+	if len(data) == 8 {
+		return values, nil
+	} else if len(data) < 12 {
+		return values, errors.New("Corrupt MS-Numpress linear data")
+	}
+
+	fixedPoint := numpressFixedPoint(data)
+
+	var ints [3]int64
+	ints[1] = int64(binary.LittleEndian.Uint32(data[8:12]))
+	values = append(values, float64(ints[1])/fixedPoint)
+
+	if len(data) == 12 {
+		return values, nil
+	} else if len(data) < 16 {
+		return values, errors.New("Corrupt MS-Numpress linear data")
+	}
+
+	ints[2] = int64(binary.LittleEndian.Uint32(data[12:16]))
+	values = append(values, float64(ints[2])/fixedPoint)
+
+	di, half := 16, 0
+
+	for di < len(data) {
+
+		if di == len(data)-1 && half == 1 && data[di]&0xf == 0 {
+			break
+		}
+
+		ints[0] = ints[1]
+		ints[1] = ints[2]
+
+		buff, e := numpressInt(data, &di, &half)
+		if e != nil {
+			return values, e
+		}
+
+		y := ints[1] + (ints[1] - ints[0]) + int64(int32(buff))
+		values = append(values, float64(y)/fixedPoint)
+		ints[2] = y
+	}
+
+	return values, nil
+}
+
+// decodePic reverses the positive integer compression used for intensities
+func decodePic(data []byte) ([]float64, error) {
+
+	var values []float64
+
+	di, half := 0, 0
+
+	for di < len(data) {
+
+		if di == len(data)-1 && half == 1 && data[di]&0xf == 0 {
+			break
+		}
+
+		x, e := numpressInt(data, &di, &half)
+		if e != nil {
+			return values, e
+		}
+
+		values = append(values, float64(x))
+	}
+
+	return values, nil
+}
+
+// decodeSlof reverses the short logged float compression used for intensities
+func decodeSlof(data []byte) ([]float64, error) {
+
+	var values []float64
+
+	if len(data) < 8 {
+		return values, errors.New("Corrupt MS-Numpress slof data")
+	}
+
+	fixedPoint := numpressFixedPoint(data)
+
+	for i := 8; i+1 < len(data); i += 2 {
+		x := binary.LittleEndian.Uint16(data[i:])
+		values = append(values, math.Exp(float64(x)/fixedPoint)-1)
+	}
+
+	return values, nil
+}
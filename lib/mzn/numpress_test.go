@@ -0,0 +1,53 @@
+package mzn
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+func Test_decodeNumpress(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		scheme string
+		data   string
+		want   []float64
+	}{
+		{
+			name:   "Testing linear prediction decoding",
+			scheme: numpressLinear,
+			data:   "40f86a000000000080969800d059990048a16245502ea816dc11c1c59740",
+			want:   []float64{100.0, 100.5, 101.25, 250.125, 250.126, 1000.75},
+		},
+		{
+			name:   "Testing positive integer decoding",
+			scheme: numpressPic,
+			data:   "87c5cd57336021f6a2",
+			want:   []float64{0, 12, 1500, 3, 987654, 42},
+		},
+		{
+			name:   "Testing short logged float decoding",
+			scheme: numpressSlof,
+			data:   "40a77000000000001a1cb655c686",
+			want:   []float64{10.001152059542859, 1500.1698753302492, 98780.60346648142},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			data, _ := hex.DecodeString(tt.data)
+			got := decodeNumpress(data, tt.scheme)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("decodeNumpress() = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9*math.Max(1, tt.want[i]) {
+					t.Errorf("decodeNumpress() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
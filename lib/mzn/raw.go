@@ -0,0 +1,141 @@
+package mzn
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"philosopher/lib/fin"
+	"philosopher/lib/msg"
+)
+
+// the scan trailer fields holding the precursor charge and the MS2 isolation width
+const (
+	chargeLabel    = "Charge State:"
+	isolationLabel = "MS2 Isolation Width:"
+)
+
+// rawSource adapts the scans from a Thermo RAW file to the Spectrum model
+type rawSource struct {
+	raw     fin.RawData
+	parents []int
+}
+
+// open reads the RAW headers and links each MSn scan to its parent scan. The precursor charges come
+// from the scan trailer, files without them can not be named after the identified spectra
+func (s *rawSource) open(f string) {
+
+	s.raw.ProcessRaw(f)
+	s.parents = rawParentScans(s.raw.Scanevents)
+
+	if _, ok := s.raw.Trailer.Field(chargeLabel); !ok {
+		msg.Custom(errors.New("The scan trailer of "+filepath.Base(f)+" has no precursor charge states, convert the file to mzML instead"), "fatal")
+	}
+
+	if _, ok := s.raw.Trailer.Field(isolationLabel); !ok {
+		msg.Custom(errors.New("The scan trailer of "+filepath.Base(f)+" has no MS2 isolation widths, the precursor purity is not available"), "warning")
+	}
+
+	return
+}
+
+func (s *rawSource) Len() int     { return s.raw.NScans() }
+func (s *rawSource) Close() error { return s.raw.Close() }
+
+// Spectrum converts the RAW scan at the given index, peaks are read in centroid mode
+func (s *rawSource) Spectrum(i int) Spectrum {
+
+	var spec Spectrum
+
+	scan := s.raw.Scan(i + 1)
+
+	spec.Index = strconv.Itoa(i)
+	spec.Scan = strconv.Itoa(i + 1)
+	spec.Level = strconv.Itoa(int(scan.MSLevel))
+	spec.ScanStartTime = scan.Time
+
+	reaction := s.raw.Scanevents[i].Reaction
+	if scan.MSLevel > 1 && len(reaction) > 0 {
+
+		// reactions are listed from the MS2 precursor up to the current level
+		r := int(scan.MSLevel) - 2
+		if r >= len(reaction) {
+			r = len(reaction) - 1
+		}
+
+		spec.Precursor.TargetIon = reaction[r].Precursormz
+		spec.Precursor.SelectedIon = reaction[r].Precursormz
+
+		if charge, ok := s.raw.TrailerValue(i+1, chargeLabel); ok {
+			spec.Precursor.ChargeState = int(charge)
+		}
+
+		// the isolation window is centered on the MS2 precursor
+		if width, ok := s.raw.TrailerValue(i+1, isolationLabel); ok && width > 0 && scan.MSLevel == 2 {
+			spec.Precursor.IsolationWindowLowerOffset = width / 2
+			spec.Precursor.IsolationWindowUpperOffset = width / 2
+		}
+
+		if s.parents[i] > 0 {
+			spec.Precursor.ParentScan = strconv.Itoa(s.parents[i])
+			spec.Precursor.ParentIndex = strconv.Itoa(s.parents[i] - 1)
+		}
+
+		// the SPS fragments follow the MS2 precursor on the reaction list
+		if scan.MSLevel == 3 {
+			for _, j := range reaction[1:] {
+				spec.Precursor.SPSIons = append(spec.Precursor.SPSIons, j.Precursormz)
+			}
+		}
+	}
+
+	peaks := scan.Spectrum(true)
+	sort.Sort(peaks)
+
+	spec.Mz.DecodedStream = make([]float64, len(peaks))
+	spec.Intensity.DecodedStream = make([]float64, len(peaks))
+	spec.Noise.DecodedStream = make([]float64, len(peaks))
+
+	for j := range peaks {
+		spec.Mz.DecodedStream[j] = peaks[j].Mz
+		spec.Intensity.DecodedStream[j] = float64(peaks[j].I)
+		spec.Noise.DecodedStream[j] = float64(peaks[j].Noise)
+	}
+
+	return spec
+}
+
+// rawParentScans assigns to every MSn scan the closest previous scan from the level above it.
+// MS3 scans are linked to the last MS2 scan that isolated the same precursor
+func rawParentScans(se fin.ScanEvents) []int {
+
+	var parents = make([]int, len(se))
+	var last = make(map[uint8]int)
+	var ms2 = make(map[string]int)
+
+	for i := range se {
+
+		level := se[i].Preamble[6]
+
+		if level == 3 && len(se[i].Reaction) > 0 {
+			key := fmt.Sprintf("%.2f", se[i].Reaction[0].Precursormz)
+			if v, ok := ms2[key]; ok {
+				parents[i] = v
+			} else {
+				parents[i] = last[2]
+			}
+		} else if level > 1 {
+			parents[i] = last[level-1]
+		}
+
+		if level == 2 && len(se[i].Reaction) > 0 {
+			ms2[fmt.Sprintf("%.2f", se[i].Reaction[0].Precursormz)] = i + 1
+		}
+
+		last[level] = i + 1
+	}
+
+	return parents
+}
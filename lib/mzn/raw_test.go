@@ -0,0 +1,87 @@
+package mzn
+
+import (
+	"math"
+	"os"
+	"reflect"
+	"testing"
+
+	"philosopher/lib/fin"
+)
+
+func Test_rawParentScans(t *testing.T) {
+
+	event := func(level uint8, precursor float64) fin.ScanEvent {
+		var e fin.ScanEvent
+		e.Preamble[6] = level
+		if precursor > 0 {
+			e.Reaction = []fin.Reaction{{Precursormz: precursor}}
+		}
+		return e
+	}
+
+	tests := []struct {
+		name string
+		se   fin.ScanEvents
+		want []int
+	}{
+		{
+			name: "Testing MS2 parents",
+			se:   fin.ScanEvents{event(1, 0), event(2, 500.1), event(2, 600.2), event(1, 0), event(2, 700.3)},
+			want: []int{0, 1, 1, 0, 4},
+		},
+		{
+			name: "Testing SPS-MS3 parents",
+			se:   fin.ScanEvents{event(1, 0), event(2, 500.1), event(2, 600.2), event(3, 500.1), event(3, 600.2)},
+			want: []int{0, 1, 1, 2, 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rawParentScans(tt.se); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rawParentScans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawTrailer(t *testing.T) {
+
+	// the mzML was converted by msconvert from the same RAW file, and holds the vendor charges and isolation windows
+	raw := "01_CPTAC_TMTS1-NCI7_Z_JHUZ_20170502_LUMOS.raw"
+	mzml := "01_CPTAC_TMTS1-NCI7_Z_JHUZ_20170502_LUMOS.mzML"
+
+	for _, i := range []string{raw, mzml} {
+		if _, e := os.Stat(i); e != nil {
+			t.Skipf("missing test file %s", i)
+		}
+	}
+
+	var r, m Reader
+	r.Open(raw)
+	defer r.Close()
+	m.Open(mzml)
+	defer m.Close()
+
+	if r.Len() != m.Len() {
+		t.Fatalf("RAW file has %d spectra, mzML has %d", r.Len(), m.Len())
+	}
+
+	for i := 0; i < r.Len(); i++ {
+
+		got := r.Spectrum(i)
+		if got.Level != "2" {
+			continue
+		}
+
+		want := m.Spectrum(i)
+
+		if got.Precursor.ChargeState != want.Precursor.ChargeState {
+			t.Errorf("scan %s charge = %d, want %d", got.Scan, got.Precursor.ChargeState, want.Precursor.ChargeState)
+		}
+
+		if math.Abs(got.Precursor.IsolationWindowLowerOffset-want.Precursor.IsolationWindowLowerOffset) > 1e-4 || math.Abs(got.Precursor.IsolationWindowUpperOffset-want.Precursor.IsolationWindowUpperOffset) > 1e-4 {
+			t.Errorf("scan %s isolation offsets = %v %v, want %v %v", got.Scan, got.Precursor.IsolationWindowLowerOffset, got.Precursor.IsolationWindowUpperOffset, want.Precursor.IsolationWindowLowerOffset, want.Precursor.IsolationWindowUpperOffset)
+		}
+	}
+}
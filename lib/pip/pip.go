@@ -25,6 +25,7 @@ import (
 	"philosopher/lib/ext/comet"
 	"philosopher/lib/ext/msfragger"
 	"philosopher/lib/met"
+	"philosopher/lib/mzn"
 	"philosopher/lib/sys"
 	"philosopher/lib/wrk"
 
@@ -38,6 +39,7 @@ type Directives struct {
 	SlackChannel   string             `yaml:"Slack Channel"`
 	SlackUserID    string             `yaml:"Slack User ID"`
 	Steps          Steps              `yaml:"Steps"`
+	RawConversion  met.Msconvert      `yaml:"Raw Conversion"`
 	DatabaseSearch DatabaseSearch     `yaml:"Database Search"`
 	PeptideProphet met.PeptideProphet `yaml:"Peptide Validation"`
 	PTMProphet     met.PTMProphet     `yaml:"PTM Localization"`
@@ -53,6 +55,7 @@ type Directives struct {
 
 // Steps contains the high-level elements of the analysis to be executed
 type Steps struct {
+	RawConversion            string `yaml:"Raw Conversion"`
 	DatabaseSearch           string `yaml:"Database Search"`
 	PeptideValidation        string `yaml:"Peptide Validation"`
 	PTMLocalization          string `yaml:"PTM Localization"`
@@ -130,6 +133,51 @@ func InitializeWorkspaces(meta met.Data, p Directives, dir, Version, Build strin
 	return meta
 }
 
+// Convert writes the Thermo RAW files from each data set as mzML or MGF, next to the RAW files, so the
+// search and the quantification can start from the RAW files
+func Convert(meta met.Data, p Directives, dir string, data []string) met.Data {
+
+	logrus.Info("Converting the RAW files on all data")
+
+	// reload the meta data
+	meta.Restore(sys.Meta())
+
+	meta.Msconvert = p.RawConversion
+	if len(meta.Msconvert.Format) == 0 {
+		meta.Msconvert.Format = "mzML"
+	}
+	if len(meta.Msconvert.MZBinaryEncoding) == 0 {
+		meta.Msconvert.MZBinaryEncoding = "64"
+	}
+	if len(meta.Msconvert.IntensityBinaryEncoding) == 0 {
+		meta.Msconvert.IntensityBinaryEncoding = "32"
+	}
+
+	for _, i := range data {
+
+		// getting inside de the dataset folder
+		dsAbs, _ := filepath.Abs(i)
+		os.Chdir(dsAbs)
+
+		files, e := filepath.Glob("*.[rR][aA][wW]")
+		if e != nil {
+			msg.Custom(e, "fatal")
+		}
+
+		for _, j := range files {
+			f, _ := filepath.Abs(j)
+			mzn.Convert(meta, f)
+		}
+
+		// return to the top level directory
+		os.Chdir(dir)
+	}
+
+	met.CleanTemp(meta.Temp)
+
+	return meta
+}
+
 // DBSearch executes the search engines if requested
 func DBSearch(meta met.Data, p Directives, dir string, data []string) met.Data {
 
@@ -527,11 +575,22 @@ func FreeQuant(meta met.Data, p Directives, dir string, data []string) met.Data
 
 		meta.Quantify = p.Freequant
 		meta.Quantify.Dir = dsAbs
-		meta.Quantify.Format = "mzML"
+		if len(meta.Quantify.Format) < 1 {
+			meta.Quantify.Format = "mzML"
+		}
 		meta.Quantify.Pex = fmt.Sprintf("%s%sinteract.pep.xml", dsAbs, string(filepath.Separator))
 		meta.Quantify.Tag = "rev_"
 
-		qua.RunLabelFreeQuantification(meta.Quantify)
+		// the other data sets are the donors for match-between-runs
+		var donors []string
+		for _, j := range data {
+			if j != i {
+				donor, _ := filepath.Abs(j)
+				donors = append(donors, donor)
+			}
+		}
+
+		qua.RunLabelFreeQuantification(meta.Quantify, donors, meta.Database.Enz)
 
 		meta.Serialize()
 
@@ -568,7 +627,9 @@ func LabelQuant(meta met.Data, p Directives, dir string, data []string) met.Data
 
 		meta.Quantify = p.LabelQuant
 		meta.Quantify.Dir = dsAbs
-		meta.Quantify.Format = "mzML"
+		if len(meta.Quantify.Format) < 1 {
+			meta.Quantify.Format = "mzML"
+		}
 		meta.Quantify.Annot = fullAnnotation
 		meta.Quantify.Brand = p.LabelQuant.Brand
 		meta.Quantify.Pex = fmt.Sprintf("%s%sinteract.pep.xml", dsAbs, string(filepath.Separator))
@@ -0,0 +1,237 @@
+package psi
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// IndexList is the list of indices pointing to the byte offsets of the mzML elements
+type IndexList struct {
+	XMLName xml.Name `xml:"indexList"`
+	Count   int      `xml:"count,attr"`
+	Index   []Index  `xml:"index"`
+}
+
+// Index tag
+type Index struct {
+	XMLName xml.Name `xml:"index"`
+	Name    string   `xml:"name,attr"`
+	Offset  []Offset `xml:"offset"`
+}
+
+// Offset is the byte position of an element referenced by its ID
+type Offset struct {
+	XMLName xml.Name `xml:"offset"`
+	IDRef   string   `xml:"idRef,attr"`
+	Value   int64    `xml:",chardata"`
+}
+
+// MzMLReader gives random access to the spectra of an mzML file without loading
+// the whole document in memory. The spectrum offsets come from the indexList when
+// the file is indexed, otherwise they are collected by a single pass over the file
+type MzMLReader struct {
+	Name    string
+	Offsets []Offset
+	file    *os.File
+	size    int64
+	temp    string
+}
+
+// Open indexes the spectra from the given mzML file. Gzip compressed files (.mzML.gz)
+// are inflated to a temporary file first, since spectra are read by their byte offsets
+func (p *MzMLReader) Open(f string) {
+
+	var file *os.File
+	var e error
+
+	if strings.EqualFold(filepath.Ext(f), ".gz") {
+		file, e = inflate(f)
+		if e == nil {
+			p.temp = file.Name()
+		}
+	} else {
+		file, e = os.Open(f)
+	}
+
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	info, e := file.Stat()
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	p.Name = filepath.Base(f)
+	p.file = file
+	p.size = info.Size()
+
+	p.Offsets = p.readIndex()
+	if len(p.Offsets) == 0 || !p.isSpectrumAt(p.Offsets[0].Value) {
+		p.Offsets = p.scanOffsets()
+	}
+
+	return
+}
+
+// Close closes the mzML file, removing the inflated copy of compressed files
+func (p *MzMLReader) Close() error {
+
+	e := p.file.Close()
+
+	if len(p.temp) > 0 {
+		os.Remove(p.temp)
+	}
+
+	return e
+}
+
+// inflate decompresses the gzip file into a temporary file, ready for random access
+func inflate(f string) (*os.File, error) {
+
+	gz, e := os.Open(f)
+	if e != nil {
+		return nil, e
+	}
+	defer gz.Close()
+
+	r, e := gzip.NewReader(bufio.NewReader(gz))
+	if e != nil {
+		return nil, e
+	}
+	defer r.Close()
+
+	file, e := ioutil.TempFile("", "*.mzML")
+	if e != nil {
+		return nil, e
+	}
+
+	if _, e := io.Copy(file, r); e != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, e
+	}
+
+	return file, nil
+}
+
+// Len returns the number of spectra in the file
+func (p *MzMLReader) Len() int {
+	return len(p.Offsets)
+}
+
+// Read parses the spectrum at position i of the spectrum list
+func (p *MzMLReader) Read(i int) Spectrum {
+
+	var spec Spectrum
+
+	if i < 0 || i >= len(p.Offsets) {
+		msg.Custom(errors.New("Spectrum index "+strconv.Itoa(i)+" is out of bounds"), "error")
+		return spec
+	}
+
+	decoder := xml.NewDecoder(bufio.NewReader(io.NewSectionReader(p.file, p.Offsets[i].Value, p.size-p.Offsets[i].Value)))
+	decoder.CharsetReader = charset.NewReader
+
+	if e := decoder.Decode(&spec); e != nil {
+		msg.DecodeMsgPck(e, "fatal")
+	}
+
+	return spec
+}
+
+// readIndex retrieves the spectrum offsets from the indexList at the end of the file
+func (p *MzMLReader) readIndex() []Offset {
+
+	var tail = int64(4096)
+	if tail > p.size {
+		tail = p.size
+	}
+
+	b := make([]byte, tail)
+	p.file.ReadAt(b, p.size-tail)
+
+	match := regexp.MustCompile(`<indexListOffset>\s*(\d+)\s*</indexListOffset>`).FindSubmatch(b)
+	if match == nil {
+		return nil
+	}
+
+	pos, e := strconv.ParseInt(string(match[1]), 10, 64)
+	if e != nil || pos <= 0 || pos >= p.size {
+		return nil
+	}
+
+	var list IndexList
+	decoder := xml.NewDecoder(bufio.NewReader(io.NewSectionReader(p.file, pos, p.size-pos)))
+	decoder.CharsetReader = charset.NewReader
+
+	if e := decoder.Decode(&list); e != nil {
+		return nil
+	}
+
+	for _, i := range list.Index {
+		if i.Name == "spectrum" {
+			return i.Offset
+		}
+	}
+
+	return nil
+}
+
+// isSpectrumAt verifies that the offset points to the beginning of a spectrum tag
+func (p *MzMLReader) isSpectrumAt(pos int64) bool {
+
+	b := make([]byte, 9)
+	if _, e := p.file.ReadAt(b, pos); e != nil {
+		return false
+	}
+
+	return string(b) == "<spectrum"
+}
+
+// scanOffsets collects the spectrum offsets by walking over the whole file, used
+// when the file is not indexed or the index is not consistent with the content
+func (p *MzMLReader) scanOffsets() []Offset {
+
+	var offsets []Offset
+
+	decoder := xml.NewDecoder(bufio.NewReader(io.NewSectionReader(p.file, 0, p.size)))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+		pos := decoder.InputOffset()
+
+		t, e := decoder.RawToken()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		if se, ok := t.(xml.StartElement); ok && se.Name.Local == "spectrum" {
+			var o Offset
+			for _, a := range se.Attr {
+				if a.Name.Local == "id" {
+					o.IDRef = a.Value
+				}
+			}
+			o.Value = pos
+			offsets = append(offsets, o)
+		}
+	}
+
+	return offsets
+}
@@ -6,9 +6,10 @@ import (
 
 // IndexedMzML is the root level tag
 type IndexedMzML struct {
-	XMLName xml.Name `xml:"indexedmzML"`
-	Name    string
-	MzML    MzML `xml:"mzML"`
+	XMLName   xml.Name `xml:"indexedmzML"`
+	Name      string
+	MzML      MzML      `xml:"mzML"`
+	IndexList IndexList `xml:"indexList"`
 }
 
 // MzML This is the root element for the Proteomics Standards Initiative (PSI) mzML schema, which is intended to
@@ -67,8 +68,8 @@ type InstrumentConfigurationList struct {
 // InstrumentConfiguration tag
 type InstrumentConfiguration struct {
 	XMLName                    xml.Name                     `xml:"instrumentConfiguration"`
-	ID                         string                       `xml:"id,att,omitempty"`
-	ScanSettingsRef            string                       `xml:"scanSettingsRef,att,omitempty"`
+	ID                         string                       `xml:"id,attr,omitempty"`
+	ScanSettingsRef            string                       `xml:"scanSettingsRef,attr,omitempty"`
 	ReferenceableParamGroupRef []ReferenceableParamGroupRef `xml:"referenceableParamGroupRef"`
 	CVParam                    []CVParam                    `xml:"cvParam"`
 	UserParam                  []UserParam                  `xml:"userParam"`
@@ -149,14 +150,14 @@ type SourceFileRef struct {
 
 // MzMLSourceFile is a file from which this instance was created
 type MzMLSourceFile struct {
-	XMLName                     xml.Name                    `xml:"sourceFile"`
-	ID                          string                      `xml:"id,attr"`
-	Location                    string                      `xml:"location,attr"`
-	Name                        string                      `xml:"name,attr"`
-	ExternalFormatDocumentation ExternalFormatDocumentation `xml:"ExternalFormatDocumentation"`
-	FileFormat                  FileFormat                  `xml:"FileFormat"`
-	CVParam                     []CVParam                   `xml:"cvParam"`
-	UserParam                   []UserParam                 `xml:"userParam"`
+	XMLName                     xml.Name                     `xml:"sourceFile"`
+	ID                          string                       `xml:"id,attr"`
+	Location                    string                       `xml:"location,attr"`
+	Name                        string                       `xml:"name,attr"`
+	ExternalFormatDocumentation *ExternalFormatDocumentation `xml:"ExternalFormatDocumentation"`
+	FileFormat                  *FileFormat                  `xml:"FileFormat"`
+	CVParam                     []CVParam                    `xml:"cvParam"`
+	UserParam                   []UserParam                  `xml:"userParam"`
 }
 
 // RefParamGroupList is the container for a list of referenceableParamGroups
@@ -169,7 +170,7 @@ type RefParamGroupList struct {
 // ReferenceableParamGroupRef is a reference to a previously defined ParamGroup, which is a reusable container of one or more cvParams
 type ReferenceableParamGroupRef struct {
 	XMLName xml.Name `xml:"referenceableParamGroupRef"`
-	Ref     string   `xml:"id,ref"`
+	Ref     string   `xml:"ref,attr"`
 }
 
 // ReferenceableParamGroup is a collection of CVParam and UserParam elements that can be referenced from elsewhere in this mzML
@@ -248,11 +249,11 @@ type SoftwareRef struct {
 // Run tag
 type Run struct {
 	XMLName                           xml.Name                     `xml:"run"`
-	DefaultInstrumentConfigurationRef string                       `xml:"defaultInstrumentConfigurationRef,attr,omitempy"`
-	DefaultSourceFileRef              string                       `xml:"defaultSourceFileRef,attr,omitempy"`
-	ID                                string                       `xml:"id,attr,omitempy"`
-	SampleRef                         string                       `xml:"sampleRef,attr,omitempy"`
-	StartTimeStamp                    string                       `xml:"startTimeStamp,att,omitempty"`
+	DefaultInstrumentConfigurationRef string                       `xml:"defaultInstrumentConfigurationRef,attr,omitempty"`
+	DefaultSourceFileRef              string                       `xml:"defaultSourceFileRef,attr,omitempty"`
+	ID                                string                       `xml:"id,attr,omitempty"`
+	SampleRef                         string                       `xml:"sampleRef,attr,omitempty"`
+	StartTimeStamp                    string                       `xml:"startTimeStamp,attr,omitempty"`
 	ReferenceableParamGroupRef        []ReferenceableParamGroupRef `xml:"referenceableParamGroupRef"`
 	CVParam                           []CVParam                    `xml:"cvParam"`
 	UserParam                         []UserParam                  `xml:"userParam"`
@@ -271,18 +272,18 @@ type SpectrumList struct {
 // Spectrum tag
 type Spectrum struct {
 	XMLName             xml.Name            `xml:"spectrum"`
-	DataProcessingRef   string              `xml:"dataProcessingRef,att"`
-	DefaultArrayLength  float64             `xml:"defaultArrayLength,attr"`
+	DataProcessingRef   string              `xml:"dataProcessingRef,attr,omitempty"`
+	DefaultArrayLength  int                 `xml:"defaultArrayLength,attr"`
 	ID                  string              `xml:"id,attr"`
 	Index               string              `xml:"index,attr"`
-	SourceFileRef       string              `xml:"sourceFileRef,attr"`
-	SpotID              string              `xml:"spotID,attr"`
+	SourceFileRef       string              `xml:"sourceFileRef,attr,omitempty"`
+	SpotID              string              `xml:"spotID,attr,omitempty"`
 	CVParam             []CVParam           `xml:"cvParam"`
 	ScanList            ScanList            `xml:"scanList"`
 	PrecursorList       PrecursorList       `xml:"precursorList"`
 	BinaryDataArrayList BinaryDataArrayList `xml:"binaryDataArrayList"`
-	Peaks               []float64
-	Intensities         []float64
+	Peaks               []float64           `xml:"-"`
+	Intensities         []float64           `xml:"-"`
 }
 
 // ScanList tag
@@ -300,10 +301,21 @@ type PrecursorList struct {
 	Precursor []Precursor `xml:"precursor"`
 }
 
+// MarshalXML skips the precursor list on spectra without precursors
+func (p PrecursorList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+
+	if len(p.Precursor) == 0 {
+		return nil
+	}
+
+	type list PrecursorList
+	return e.EncodeElement(list(p), start)
+}
+
 // Precursor tag
 type Precursor struct {
 	XMLName         xml.Name        `xml:"precursor"`
-	SpectrumRef     string          `xml:"spectrumRef,attr"`
+	SpectrumRef     string          `xml:"spectrumRef,attr,omitempty"`
 	UserParam       []UserParam     `xml:"userParam"`
 	IsolationWindow IsolationWindow `xml:"isolationWindow"`
 	SelectedIonList SelectedIonList `xml:"selectedIonList"`
@@ -312,11 +324,22 @@ type Precursor struct {
 
 // IsolationWindow tag
 type IsolationWindow struct {
-	InstConfigurationRef string      `xml:"isolationWindow,attr"`
+	InstConfigurationRef string      `xml:"isolationWindow,attr,omitempty"`
 	CVParam              []CVParam   `xml:"cvParam"`
 	UserParam            []UserParam `xml:"userParam"`
 }
 
+// MarshalXML skips the isolation window when it has no parameters
+func (p IsolationWindow) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+
+	if len(p.CVParam) == 0 && len(p.UserParam) == 0 {
+		return nil
+	}
+
+	type window IsolationWindow
+	return e.EncodeElement(window(p), start)
+}
+
 // SelectedIonList tag
 type SelectedIonList struct {
 	XMLName     xml.Name      `xml:"selectedIonList"`
@@ -333,7 +356,7 @@ type SelectedIon struct {
 // Scan tag
 type Scan struct {
 	XMLName              xml.Name       `xml:"scan"`
-	InstConfigurationRef string         `xml:"instrumentConfigurationRef,attr"`
+	InstConfigurationRef string         `xml:"instrumentConfigurationRef,attr,omitempty"`
 	CVParam              []CVParam      `xml:"cvParam"`
 	UserParam            []UserParam    `xml:"userParam"`
 	ScanWindowList       ScanWindowList `xml:"scanWindowList"`
@@ -346,6 +369,17 @@ type ScanWindowList struct {
 	ScanWindow []ScanWindow `xml:"scanWindow"`
 }
 
+// MarshalXML skips the scan window list when no windows are defined
+func (p ScanWindowList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+
+	if len(p.ScanWindow) == 0 {
+		return nil
+	}
+
+	type list ScanWindowList
+	return e.EncodeElement(list(p), start)
+}
+
 // ScanWindow tag
 type ScanWindow struct {
 	XMLName xml.Name  `xml:"scanWindow"`
@@ -371,7 +405,7 @@ type Chromatogram struct {
 	XMLName             xml.Name            `xml:"chromatogram"`
 	Index               int                 `xml:"index,attr"`
 	ID                  string              `xml:"id,attr"`
-	DefaultArrayLength  float64             `xml:"defaultArrayLength,attr"`
+	DefaultArrayLength  int                 `xml:"defaultArrayLength,attr"`
 	CVParam             []CVParam           `xml:"cvParam"`
 	UserParam           []UserParam         `xml:"userParam"`
 	Precursor           Precursor           `xml:"precursor"`
@@ -395,7 +429,7 @@ type BinaryDataArrayList struct {
 // BinaryDataArray tag
 type BinaryDataArray struct {
 	XMLName       xml.Name  `xml:"binaryDataArray"`
-	EncodedLength float64   `xml:"encodedLength,attr"`
+	EncodedLength int       `xml:"encodedLength,attr"`
 	CVParam       []CVParam `xml:"cvParam"`
 	Binary        Binary    `xml:"binary"`
 }
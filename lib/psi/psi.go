@@ -2,11 +2,14 @@ package psi
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"philosopher/lib/msg"
 
@@ -103,7 +106,18 @@ func (p *IndexedMzML) Parse(f string) {
 		msg.ReadFile(e, "fatal")
 	}
 	defer xmlFile.Close()
-	b, _ := ioutil.ReadAll(xmlFile)
+
+	var r io.Reader = xmlFile
+	if strings.EqualFold(filepath.Ext(f), ".gz") {
+		gz, e := gzip.NewReader(xmlFile)
+		if e != nil {
+			msg.ReadFile(e, "fatal")
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	b, _ := ioutil.ReadAll(r)
 
 	var mzml IndexedMzML
 
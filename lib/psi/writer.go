@@ -0,0 +1,147 @@
+package psi
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"philosopher/lib/msg"
+)
+
+// MzMLWriter writes mzML documents one spectrum at a time, keeping the byte offset of
+// every spectrum so the indexList and the file checksum can be added at the end
+type MzMLWriter struct {
+	Offsets []Offset
+	index   bool
+	file    *os.File
+	buffer  *bufio.Writer
+	sha     hash.Hash
+	out     *countWriter
+}
+
+// countWriter keeps track of the number of bytes written to the document
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(b []byte) (int, error) {
+	n, e := c.w.Write(b)
+	c.n += int64(n)
+	return n, e
+}
+
+// Create writes the document header, from the mzML tag to the opening of the spectrum list.
+// The spectrum list count must be set in advance
+func (p *MzMLWriter) Create(f string, mzml MzML, index bool) {
+
+	file, e := os.Create(f)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	p.file = file
+	p.index = index
+	p.buffer = bufio.NewWriter(file)
+	p.sha = sha1.New()
+	p.out = &countWriter{w: io.MultiWriter(p.buffer, p.sha)}
+
+	io.WriteString(p.out, xml.Header)
+
+	if index {
+		io.WriteString(p.out, `<indexedmzML xmlns="http://psi.hupo.org/ms/mzml" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://psi.hupo.org/ms/mzml http://psidev.info/files/ms/mzML/xsd/mzML1.1.2_idx.xsd">`+"\n")
+	}
+
+	fmt.Fprintf(p.out, `  <mzML xmlns="http://psi.hupo.org/ms/mzml" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://psi.hupo.org/ms/mzml http://psidev.info/files/ms/mzML/xsd/mzML1.1.0.xsd" id="%s" version="1.1.0">`+"\n", escape(mzml.ID))
+
+	p.encode(mzml.CvList, "    ")
+	p.encode(mzml.FileDescription, "    ")
+	p.encode(mzml.SoftwareList, "    ")
+	p.encode(mzml.InstrumentConfigurationList, "    ")
+	p.encode(mzml.DataProcessingList, "    ")
+
+	fmt.Fprintf(p.out, `    <run id="%s" defaultInstrumentConfigurationRef="%s"`, escape(mzml.Run.ID), escape(mzml.Run.DefaultInstrumentConfigurationRef))
+	if len(mzml.Run.DefaultSourceFileRef) > 0 {
+		fmt.Fprintf(p.out, ` defaultSourceFileRef="%s"`, escape(mzml.Run.DefaultSourceFileRef))
+	}
+	if len(mzml.Run.StartTimeStamp) > 0 {
+		fmt.Fprintf(p.out, ` startTimeStamp="%s"`, escape(mzml.Run.StartTimeStamp))
+	}
+	io.WriteString(p.out, ">\n")
+
+	fmt.Fprintf(p.out, `      <spectrumList count="%d" defaultDataProcessingRef="%s">`+"\n", mzml.Run.SpectrumList.Count, escape(mzml.Run.SpectrumList.DefaultDataProcessingRef))
+
+	return
+}
+
+// Write appends a spectrum to the spectrum list
+func (p *MzMLWriter) Write(spec Spectrum) {
+
+	p.Offsets = append(p.Offsets, Offset{IDRef: spec.ID, Value: p.out.n + 8})
+	p.encode(spec, "        ")
+
+	return
+}
+
+// Close ends the spectrum list and the document. Indexed documents receive the
+// indexList, the indexList offset and the SHA-1 checksum of the file
+func (p *MzMLWriter) Close() {
+
+	io.WriteString(p.out, "      </spectrumList>\n    </run>\n  </mzML>\n")
+
+	if p.index {
+
+		pos := p.out.n + 2
+
+		fmt.Fprintf(p.out, "  <indexList count=\"1\">\n    <index name=\"spectrum\">\n")
+		for _, i := range p.Offsets {
+			fmt.Fprintf(p.out, "      <offset idRef=\"%s\">%d</offset>\n", escape(i.IDRef), i.Value)
+		}
+		io.WriteString(p.out, "    </index>\n  </indexList>\n")
+		fmt.Fprintf(p.out, "  <indexListOffset>%d</indexListOffset>\n", pos)
+
+		// the checksum covers the document up to and including the opening tag
+		io.WriteString(p.out, "  <fileChecksum>")
+		fmt.Fprintf(p.buffer, "%s</fileChecksum>\n</indexedmzML>\n", hex.EncodeToString(p.sha.Sum(nil)))
+	}
+
+	if e := p.buffer.Flush(); e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	if e := p.file.Close(); e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	return
+}
+
+// encode writes the element using the given indentation
+func (p *MzMLWriter) encode(v interface{}, prefix string) {
+
+	enc := xml.NewEncoder(p.out)
+	enc.Indent(prefix, "  ")
+
+	if e := enc.Encode(v); e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	io.WriteString(p.out, "\n")
+
+	return
+}
+
+// escape returns the XML representation of an attribute value
+func escape(s string) string {
+
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package qua
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/rep"
+)
+
+const (
+	// minDigestLength and maxDigestLength limit the theoretical peptides to the ones usually observed
+	minDigestLength = 6
+	maxDigestLength = 30
+)
+
+// cleavageRule returns the residues the enzyme used on the database cleaves after, and the residues that block
+// the cleavage when they follow, trypsin is used when the enzyme is not known
+func cleavageRule(enzyme string) (string, string) {
+
+	var e bio.Enzyme
+	if len(enzyme) > 0 {
+		e.Synth(enzyme)
+	}
+
+	if len(e.Pattern) == 0 {
+		e.Synth("trypsin")
+	}
+
+	if i := strings.Index(e.Pattern, "[^"); i >= 0 {
+		return e.Pattern[:i], strings.TrimSuffix(e.Pattern[i+2:], "]")
+	}
+
+	return e.Pattern, ""
+}
+
+// theoreticalPeptides counts the fully enzymatic peptides of a protein sequence, cleaving after the enzyme
+// residues except before the blocking ones, with no missed cleavages and within the observable length range
+func theoreticalPeptides(sequence, residues, blocking string) int {
+
+	var count int
+	var start int
+
+	for i := 0; i < len(sequence); i++ {
+
+		if i < len(sequence)-1 && (!strings.ContainsRune(residues, rune(sequence[i])) || strings.ContainsRune(blocking, rune(sequence[i+1]))) {
+			continue
+		}
+
+		length := i + 1 - start
+		if length >= minDigestLength && length <= maxDigestLength {
+			count++
+		}
+
+		start = i + 1
+	}
+
+	return count
+}
+
+// top3 returns the mean of the three most intense values
+func top3(intensities []float64) float64 {
+
+	if len(intensities) == 0 {
+		return 0
+	}
+
+	var sorted = make([]float64, len(intensities))
+	copy(sorted, intensities)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	if len(sorted) > 3 {
+		sorted = sorted[:3]
+	}
+
+	var sum float64
+	for _, i := range sorted {
+		sum += i
+	}
+
+	return sum / float64(len(sorted))
+}
+
+// calculateSpectralAbundances estimates the protein abundances based on spectral counts. NSAF divides the
+// unique and razor spectral count by the protein length and normalizes by the sum of all target proteins,
+// emPAI uses the fraction of the observable peptides of the database enzyme that were identified
+func calculateSpectralAbundances(e rep.Evidence, enzyme string) rep.Evidence {
+
+	residues, blocking := cleavageRule(enzyme)
+
+	var saf = make([]float64, len(e.Proteins))
+	var sum float64
+
+	for i := range e.Proteins {
+
+		length := e.Proteins[i].Length
+		if length == 0 {
+			length = len(e.Proteins[i].Sequence)
+		}
+
+		if length > 0 {
+			saf[i] = float64(e.Proteins[i].URazorSpC) / float64(length)
+		}
+
+		if e.Proteins[i].IsDecoy == false {
+			sum += saf[i]
+		}
+
+		observable := theoreticalPeptides(e.Proteins[i].Sequence, residues, blocking)
+		if observable == 0 {
+			continue
+		}
+
+		var observed = make(map[string]uint8)
+		for _, j := range e.Proteins[i].TotalPeptideIons {
+			observed[j.Sequence] = 0
+		}
+
+		ratio := float64(len(observed)) / float64(observable)
+		if ratio > 1 {
+			ratio = 1
+		}
+
+		e.Proteins[i].EmPAI = math.Pow(10, ratio) - 1
+	}
+
+	if sum > 0 {
+		for i := range e.Proteins {
+			e.Proteins[i].NSAF = saf[i] / sum
+		}
+	}
+
+	return e
+}
+
+// calculateIntensityAbundances estimates the protein abundances based on the unique and razor ion intensities.
+// Top3 is the mean of the three most intense ions, and iBAQ divides the summed intensity by the number of
+// theoretical peptides of the database enzyme on the protein
+func calculateIntensityAbundances(e rep.Evidence, intensities map[string][]float64, enzyme string) rep.Evidence {
+
+	residues, blocking := cleavageRule(enzyme)
+
+	for i := range e.Proteins {
+
+		v := intensities[e.Proteins[i].ProteinID]
+
+		e.Proteins[i].Top3Intensity = top3(v)
+
+		observable := theoreticalPeptides(e.Proteins[i].Sequence, residues, blocking)
+		if observable == 0 {
+			continue
+		}
+
+		var sum float64
+		for _, j := range v {
+			sum += j
+		}
+
+		e.Proteins[i].IBAQ = sum / float64(observable)
+	}
+
+	return e
+}
@@ -0,0 +1,67 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/rep"
+)
+
+func Test_theoreticalPeptides(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		sequence string
+		want     int
+	}{
+		{"cleaves after K and R", "AAAAAKGGGGGGRLLLLLL", 3},
+		{"no cleavage before P", "AAAAAKPGGGGGRLLLLLL", 2},
+		{"short peptides are not observable", "AAKGGRLLLLLL", 1},
+		{"long peptides are not observable", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAKGGGGGG", 1},
+		{"empty sequence", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := theoreticalPeptides(tt.sequence, "KR", "P"); got != tt.want {
+				t.Errorf("theoreticalPeptides() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cleavageRule(t *testing.T) {
+
+	if residues, blocking := cleavageRule("lys_c"); residues != "K" || blocking != "P" {
+		t.Errorf("lys_c rule is %s and %s, want K and P", residues, blocking)
+	}
+
+	if residues, blocking := cleavageRule(""); residues != "KR" || blocking != "P" {
+		t.Errorf("unknown enzyme rule is %s and %s, want the trypsin KR and P", residues, blocking)
+	}
+
+	if got := theoreticalPeptides("AAAAAKGGGGGGRLLLLLL", "K", "P"); got != 2 {
+		t.Errorf("lys_c theoretical peptides are %d, want 2", got)
+	}
+}
+
+func Test_calculateSpectralAbundances(t *testing.T) {
+
+	var e rep.Evidence
+
+	e.Proteins = rep.ProteinEvidenceList{
+		{ProteinID: "A", Length: 100, URazorSpC: 10, Sequence: "AAAAAKGGGGGGRLLLLLL", TotalPeptideIons: map[string]rep.IonEvidence{"AAAAAK#2": {Sequence: "AAAAAK"}}},
+		{ProteinID: "B", Length: 50, URazorSpC: 10},
+		{ProteinID: "rev_B", Length: 50, URazorSpC: 5, IsDecoy: true},
+	}
+
+	e = calculateSpectralAbundances(e, "trypsin")
+
+	if math.Abs(e.Proteins[0].NSAF-1.0/3) > 1e-9 || math.Abs(e.Proteins[1].NSAF-2.0/3) > 1e-9 {
+		t.Errorf("NSAF values are %f and %f, want 0.333 and 0.667", e.Proteins[0].NSAF, e.Proteins[1].NSAF)
+	}
+
+	if math.Abs(e.Proteins[0].EmPAI-(math.Pow(10, 1.0/3)-1)) > 1e-9 {
+		t.Errorf("emPAI is %f, want %f", e.Proteins[0].EmPAI, math.Pow(10, 1.0/3)-1)
+	}
+}
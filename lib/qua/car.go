@@ -0,0 +1,91 @@
+package qua
+
+import (
+	"errors"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+)
+
+const (
+	carrierRole   string = "carrier"
+	emptyRole     string = "empty"
+	referenceRole string = "reference"
+)
+
+// channelRoles flags the template channels designated as carrier, empty or reference on the annotation file,
+// these channels are left out of the normalization and of the PSM selection sums
+func channelRoles(template iso.Labels, roles map[string]string) ([]bool, []bool) {
+
+	var excluded = make([]bool, len(template.Channels))
+	var carrier = make([]bool, len(template.Channels))
+
+	for i, c := range template.Channels {
+
+		role, ok := roles[c.Name]
+		if !ok {
+			continue
+		}
+
+		switch role {
+		case carrierRole:
+			carrier[i] = true
+			excluded[i] = true
+		case emptyRole, referenceRole:
+			excluded[i] = true
+		default:
+			msg.Custom(errors.New("Unknown channel role "+role+", please use carrier, empty or reference"), "fatal")
+		}
+	}
+
+	return excluded, carrier
+}
+
+// sampleSum returns the summed intensity of the sample channels
+func sampleSum(labels iso.Labels, excluded []bool) float64 {
+
+	var sum float64
+	for i, c := range labels.Channels {
+		if isExcluded(excluded, i) {
+			continue
+		}
+		sum += c.Intensity
+	}
+
+	return sum
+}
+
+// carrierRatios calculates the ratio between the average sample channel and the carrier of each PSM, PSMs over
+// the carrier ratio cap are flagged and left out of the quantification
+func carrierRatios(evi rep.Evidence, excluded, carrier []bool, ratioCap float64) rep.Evidence {
+
+	var samples float64
+	for i := range excluded {
+		if !excluded[i] {
+			samples++
+		}
+	}
+
+	for i := range evi.PSM {
+
+		var boost float64
+		for j, c := range evi.PSM[i].Labels.Channels {
+			if j < len(carrier) && carrier[j] {
+				boost += c.Intensity
+			}
+		}
+
+		if boost == 0 || samples == 0 {
+			continue
+		}
+
+		evi.PSM[i].CarrierRatio = (sampleSum(evi.PSM[i].Labels, excluded) / samples) / boost
+
+		if ratioCap > 0 && evi.PSM[i].CarrierRatio > ratioCap {
+			evi.PSM[i].IsAboveCarrierCap = true
+		}
+	}
+
+	return evi
+}
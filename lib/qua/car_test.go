@@ -0,0 +1,51 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/rep"
+)
+
+func Test_carrierRatios(t *testing.T) {
+
+	template := iso.New([]iso.Reagent{{Name: "126"}, {Name: "127N"}, {Name: "127C"}, {Name: "128N"}, {Name: "128C"}})
+
+	excluded, carrier := channelRoles(template, map[string]string{"126": "carrier", "127N": "empty", "127C": "reference"})
+	if !carrier[0] || carrier[1] || !excluded[1] || !excluded[2] || excluded[3] {
+		t.Fatalf("channel roles are %v and %v", excluded, carrier)
+	}
+
+	var evi rep.Evidence
+	evi.PSM = rep.PSMEvidenceList{
+		{Spectrum: "run.1.1.2", Labels: labelsOf(10000, 5, 500, 100, 300)},
+		{Spectrum: "run.2.2.2", Labels: labelsOf(1000, 0, 50, 200, 200)},
+		{Spectrum: "run.3.3.2", Labels: labelsOf(0, 0, 0, 100, 100)},
+	}
+
+	if sampleSum(evi.PSM[0].Labels, excluded) != 400 {
+		t.Errorf("sample sum is %f, want 400 without the carrier, empty and reference channels", sampleSum(evi.PSM[0].Labels, excluded))
+	}
+
+	evi = carrierRatios(evi, excluded, carrier, 0.1)
+
+	if math.Abs(evi.PSM[0].CarrierRatio-0.02) > 1e-9 || evi.PSM[0].IsAboveCarrierCap {
+		t.Errorf("first PSM has ratio %f and cap flag %t, want 0.02 under the cap", evi.PSM[0].CarrierRatio, evi.PSM[0].IsAboveCarrierCap)
+	}
+
+	if math.Abs(evi.PSM[1].CarrierRatio-0.2) > 1e-9 || !evi.PSM[1].IsAboveCarrierCap {
+		t.Errorf("second PSM has ratio %f and cap flag %t, want 0.2 over the cap", evi.PSM[1].CarrierRatio, evi.PSM[1].IsAboveCarrierCap)
+	}
+
+	if evi.PSM[2].CarrierRatio != 0 || evi.PSM[2].IsAboveCarrierCap {
+		t.Errorf("PSM without carrier signal has ratio %f", evi.PSM[2].CarrierRatio)
+	}
+
+	// the carrier keeps its intensity and the sample channels are brought to the same total
+	e := rep.Evidence{PSM: rep.PSMEvidenceList{{Labels: labelsOf(1000, 0, 0, 10, 10)}, {Labels: labelsOf(3000, 0, 0, 30, 10)}}}
+	e = normalizeLabels(e, "total", excluded)
+	if e.PSM[1].Labels.Channels[0].Intensity != 3000 || e.PSM[0].Labels.Channels[3].Intensity != 7.5 {
+		t.Errorf("normalized channels are %v", e.PSM[1].Labels.Channels)
+	}
+}
@@ -0,0 +1,256 @@
+package qua
+
+import (
+	"math"
+	"sort"
+
+	"philosopher/lib/mzn"
+	"philosopher/lib/rep"
+)
+
+// c13Spacing is the mass difference between the 13C and the 12C isotopes
+const c13Spacing = 1.0033548
+
+// isotopologues is the number of isotopic peaks traced for each precursor, M, M+1 and M+2
+const isotopologues = 3
+
+// boundaryFraction is the fraction of the apex intensity where the chromatographic peak ends
+const boundaryFraction = 0.05
+
+// averagineMass is the mass of the averagine residue, the elemental composition below
+// is the average amino acid residue composition from Senko et al. 1995
+const averagineMass = 111.1254
+
+// averagine element counts and their natural isotope abundances for the +0, +1 and +2 Da isotopes
+var averagine = []struct {
+	count     float64
+	abundance [isotopologues]float64
+}{
+	{4.9384, [isotopologues]float64{0.9893, 0.0107, 0}},
+	{7.7583, [isotopologues]float64{0.999885, 0.000115, 0}},
+	{1.3577, [isotopologues]float64{0.99636, 0.00364, 0}},
+	{1.4773, [isotopologues]float64{0.99757, 0.00038, 0.00205}},
+	{0.0417, [isotopologues]float64{0.9499, 0.0075, 0.0425}},
+}
+
+// xicPoint is the MS1 intensity measured for an ion at a given retention time
+type xicPoint struct {
+	rt        float64
+	intensity float64
+}
+
+// averagineDistribution returns the expected relative abundances of the first isotopologues
+// for a peptide with the given neutral mass, assuming an averagine elemental composition
+func averagineDistribution(mass float64) []float64 {
+
+	var dist = []float64{1, 0, 0}
+	var residues = mass / averagineMass
+
+	for _, el := range averagine {
+		atoms := int(math.Round(el.count * residues))
+		for a := 0; a < atoms; a++ {
+			var conv = make([]float64, isotopologues)
+			for i := range dist {
+				for j := 0; i+j < isotopologues; j++ {
+					conv[i+j] += dist[i] * el.abundance[j]
+				}
+			}
+			dist = conv
+		}
+	}
+
+	return dist
+}
+
+// trace extracts the chromatogram of a m/z value inside the retention time range. Scans without
+// a matching peak are kept with zero intensity so the chromatographic peak boundaries can be found
+func trace(ms1 mzn.Spectra, minRT, maxRT, ppmPrecision, mzValue float64) []xicPoint {
+
+	var points []xicPoint
+
+	for j := range ms1 {
+		if ms1[j].ScanStartTime < minRT || ms1[j].ScanStartTime > maxRT {
+			continue
+		}
+
+		lowi := sort.Search(len(ms1[j].Mz.DecodedStream), func(i int) bool { return ms1[j].Mz.DecodedStream[i] >= mzValue-ppmPrecision*mzValue })
+		highi := sort.Search(len(ms1[j].Mz.DecodedStream), func(i int) bool { return ms1[j].Mz.DecodedStream[i] >= mzValue+ppmPrecision*mzValue })
+
+		var maxI = 0.0
+		for _, k := range ms1[j].Intensity.DecodedStream[lowi:highi] {
+			if k > maxI {
+				maxI = k
+			}
+		}
+
+		points = append(points, xicPoint{ms1[j].ScanStartTime, maxI})
+	}
+
+	return points
+}
+
+// traceFeature follows the isotope envelope of a precursor over the MS1 scans and integrates the
+// monoisotopic chromatographic peak closest to the identification. Times are given in minutes,
+// the reported apex time and peak width are in seconds like the PSM retention times
+func traceFeature(ms1 mzn.Spectra, minRT, maxRT, rt, pTWin, ppmPrecision, mzValue, mass float64, charge int) (rep.Feature, bool) {
+
+	var f rep.Feature
+
+	if charge < 1 {
+		return f, false
+	}
+
+	var traces = make([][]xicPoint, isotopologues)
+	for k := range traces {
+		traces[k] = trace(ms1, minRT, maxRT, ppmPrecision, mzValue+float64(k)*c13Spacing/float64(charge))
+	}
+
+	mono := traces[0]
+
+	var detected int
+	for _, p := range mono {
+		if p.intensity > 0 {
+			detected++
+		}
+	}
+
+	if detected < 5 {
+		return f, false
+	}
+
+	smooth := smoothTrace(mono)
+
+	var apex = -1
+	for i := range smooth {
+		if mono[i].rt > (rt-pTWin) && mono[i].rt < (rt+pTWin) {
+			if apex == -1 || smooth[i] > smooth[apex] {
+				apex = i
+			}
+		}
+	}
+
+	if apex == -1 || smooth[apex] == 0 {
+		return f, false
+	}
+
+	left, right := peakBoundaries(smooth, apex)
+
+	for i := left; i <= right; i++ {
+		if mono[i].intensity > f.Apex {
+			f.Apex = mono[i].intensity
+			f.ApexRetentionTime = mono[i].rt * 60
+		}
+	}
+
+	f.Area = integrate(mono, left, right)
+	f.FWHM = fullWidthHalfMaximum(mono, smooth, apex, left, right)
+
+	var areas = make([]float64, isotopologues)
+	for k := range traces {
+		areas[k] = integrate(traces[k], left, right)
+	}
+
+	f.IsotopeCorrelation = cosine(areas, averagineDistribution(mass))
+
+	return f, true
+}
+
+// smoothTrace applies a 3 points moving average to the chromatogram intensities
+func smoothTrace(points []xicPoint) []float64 {
+
+	var smooth = make([]float64, len(points))
+
+	for i := range points {
+		var sum, n float64
+		for j := i - 1; j <= i+1; j++ {
+			if j >= 0 && j < len(points) {
+				sum += points[j].intensity
+				n++
+			}
+		}
+		smooth[i] = sum / n
+	}
+
+	return smooth
+}
+
+// peakBoundaries walks away from the apex until the signal drops below a fraction of the apex,
+// or until it rises again on the tail, meaning that a neighbour peak starts
+func peakBoundaries(smooth []float64, apex int) (int, int) {
+
+	var threshold = smooth[apex] * boundaryFraction
+	var half = smooth[apex] / 2
+
+	left := apex
+	for left > 0 && smooth[left-1] > threshold {
+		if smooth[left-1] > smooth[left] && smooth[left] < half {
+			break
+		}
+		left--
+	}
+
+	right := apex
+	for right < len(smooth)-1 && smooth[right+1] > threshold {
+		if smooth[right+1] > smooth[right] && smooth[right] < half {
+			break
+		}
+		right++
+	}
+
+	return left, right
+}
+
+// integrate calculates the peak area between the boundaries with the trapezoidal rule, in seconds
+func integrate(points []xicPoint, left, right int) float64 {
+
+	var area float64
+
+	for i := left; i < right; i++ {
+		area += (points[i].intensity + points[i+1].intensity) / 2 * (points[i+1].rt - points[i].rt) * 60
+	}
+
+	return area
+}
+
+// fullWidthHalfMaximum measures the peak width at half of the apex height, in seconds. The crossing
+// points are interpolated between scans, and fall back to the peak boundaries when not reached
+func fullWidthHalfMaximum(points []xicPoint, smooth []float64, apex, left, right int) float64 {
+
+	var half = smooth[apex] / 2
+
+	var start = points[left].rt
+	for i := apex; i > left; i-- {
+		if smooth[i-1] < half {
+			start = points[i-1].rt + (half-smooth[i-1])*(points[i].rt-points[i-1].rt)/(smooth[i]-smooth[i-1])
+			break
+		}
+	}
+
+	var end = points[right].rt
+	for i := apex; i < right; i++ {
+		if smooth[i+1] < half {
+			end = points[i].rt + (smooth[i]-half)*(points[i+1].rt-points[i].rt)/(smooth[i]-smooth[i+1])
+			break
+		}
+	}
+
+	return (end - start) * 60
+}
+
+// cosine calculates the cosine similarity between the observed and the expected isotope abundances
+func cosine(a, b []float64) float64 {
+
+	var dot, na, nb float64
+
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,67 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/mzn"
+)
+
+func Test_averagineDistribution(t *testing.T) {
+
+	small := averagineDistribution(1000)
+	if small[0] < 0.55 || small[0] > 0.6 || small[0] < small[1] || small[1] < small[2] {
+		t.Errorf("Unexpected isotope distribution for 1000 Da: %v", small)
+	}
+
+	large := averagineDistribution(3000)
+	if large[1] < large[0] {
+		t.Errorf("M+1 should be the most abundant isotope for 3000 Da: %v", large)
+	}
+}
+
+func Test_traceFeature(t *testing.T) {
+
+	var mz, charge, mass = 500.0, 2, 998.0
+	var sigma = 0.1
+	var dist = averagineDistribution(mass)
+
+	var ms1 mzn.Spectra
+	for i := 0; i < 61; i++ {
+
+		var spec mzn.Spectrum
+		spec.Level = "1"
+		spec.ScanStartTime = 9 + float64(i)*0.05
+
+		height := 1e6 * math.Exp(-math.Pow(spec.ScanStartTime-10.5, 2)/(2*sigma*sigma))
+		for k := range dist {
+			spec.Mz.DecodedStream = append(spec.Mz.DecodedStream, mz+float64(k)*c13Spacing/float64(charge))
+			spec.Intensity.DecodedStream = append(spec.Intensity.DecodedStream, height*dist[k]/dist[0])
+		}
+
+		ms1 = append(ms1, spec)
+	}
+
+	f, ok := traceFeature(ms1, 9, 12, 10.45, 0.4, 10e-6, mz, mass, charge)
+	if !ok {
+		t.Fatal("Feature not traced")
+	}
+
+	if f.Apex != 1e6 || f.ApexRetentionTime != 630 {
+		t.Errorf("Apex is %f at %f, want 1e6 at 630", f.Apex, f.ApexRetentionTime)
+	}
+
+	area := 1e6 * sigma * math.Sqrt(2*math.Pi) * 60
+	if math.Abs(f.Area-area)/area > 0.05 {
+		t.Errorf("Area is %f, want %f", f.Area, area)
+	}
+
+	fwhm := 2 * math.Sqrt(2*math.Ln2) * sigma * 60
+	if math.Abs(f.FWHM-fwhm)/fwhm > 0.1 {
+		t.Errorf("FWHM is %f, want %f", f.FWHM, fwhm)
+	}
+
+	if f.IsotopeCorrelation < 0.999 {
+		t.Errorf("Isotope correlation is %f, want 1", f.IsotopeCorrelation)
+	}
+}
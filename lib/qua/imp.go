@@ -0,0 +1,252 @@
+package qua
+
+import (
+	"math"
+
+	"philosopher/lib/iso"
+)
+
+const (
+	// isotopeShift is the mass difference between the 13C and 12C isotopes
+	isotopeShift = 1.0033548
+
+	// isotopeTolerance separates the N and C reporters of the same nominal mass
+	isotopeTolerance = 0.003
+)
+
+// impurityShifts returns the mass shift of each impurity column, the 13C shifts of the -2, -1, +1 and +2 columns,
+// or the 13C and 15N shifts of the TMTpro columns, whose 15N isotopes fall on the N and C partner channels
+func impurityShifts(columns int) []float64 {
+
+	if columns == 8 {
+		return []float64{
+			-2 * isotopeShift,
+			-isotopeShift - nitrogenShift,
+			-isotopeShift,
+			-nitrogenShift,
+			nitrogenShift,
+			isotopeShift,
+			isotopeShift + nitrogenShift,
+			2 * isotopeShift,
+		}
+	}
+
+	return []float64{-2 * isotopeShift, -isotopeShift, isotopeShift, 2 * isotopeShift}
+}
+
+// impurityMatrix builds the matrix mixing the true reporter intensities into the observed ones, each column
+// is a reagent and each row a measured channel. The isotope peaks fall on the channel with the expected mass,
+// so the 13C and 15N isotopes reach different partners, or on the channel with the same nominal mass when the
+// plex has no N and C reporter pairs
+func impurityMatrix(template iso.Labels, impurities iso.Impurities) [][]float64 {
+
+	n := len(template.Channels)
+
+	var nominal = make(map[float64]int)
+	for _, i := range template.Channels {
+		nominal[math.Round(i.Mz)]++
+	}
+	resolved := len(nominal) < n
+
+	var matrix = make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+		matrix[i][i] = 1
+	}
+
+	for i, c := range template.Channels {
+
+		v, ok := impurities[c.Name]
+		if !ok {
+			continue
+		}
+
+		var spill float64
+		for k, shift := range impurityShifts(len(v)) {
+
+			spill += v[k] / 100
+
+			target := c.Mz + shift
+			for j, t := range template.Channels {
+				if (resolved && math.Abs(t.Mz-target) <= isotopeTolerance) || (!resolved && math.Round(t.Mz) == math.Round(target)) {
+					matrix[j][i] += v[k] / 100
+					break
+				}
+			}
+		}
+
+		matrix[i][i] -= spill
+	}
+
+	return matrix
+}
+
+// correctImpurities replaces the reporter intensities of each spectrum with the non-negative intensities best
+// explaining them through the impurity matrix
+func correctImpurities(labels map[string]iso.Labels, matrix [][]float64) map[string]iso.Labels {
+
+	for k, v := range labels {
+
+		if v.Sum() == 0 || len(v.Channels) != len(matrix) {
+			continue
+		}
+
+		var observed = make([]float64, len(v.Channels))
+		for i := range v.Channels {
+			observed[i] = v.Channels[i].Intensity
+		}
+
+		corrected := nnls(matrix, observed)
+		for i := range v.Channels {
+			v.Channels[i].Intensity = corrected[i]
+		}
+
+		v.IsCorrected = true
+		labels[k] = v
+	}
+
+	return labels
+}
+
+// nnls solves the least squares problem a * x = b with x >= 0 using the Lawson-Hanson active set method
+func nnls(a [][]float64, b []float64) []float64 {
+
+	n := len(a[0])
+	x := make([]float64, n)
+	passive := make([]bool, n)
+
+	const tolerance = 1e-10
+
+	for iteration := 0; iteration < 3*n; iteration++ {
+
+		// the gradient points to the variable that reduces the residual the most
+		residual := make([]float64, len(b))
+		for i := range a {
+			residual[i] = b[i]
+			for j := range x {
+				residual[i] -= a[i][j] * x[j]
+			}
+		}
+
+		best := -1
+		var bestGradient float64
+		for j := 0; j < n; j++ {
+			if passive[j] {
+				continue
+			}
+			var w float64
+			for i := range a {
+				w += a[i][j] * residual[i]
+			}
+			if w > tolerance && w > bestGradient {
+				best = j
+				bestGradient = w
+			}
+		}
+
+		if best < 0 {
+			break
+		}
+		passive[best] = true
+
+		for inner := 0; inner <= n; inner++ {
+
+			z := passiveLeastSquares(a, b, passive)
+
+			feasible := true
+			alpha := 1.0
+			for j := 0; j < n; j++ {
+				if passive[j] && z[j] <= tolerance {
+					feasible = false
+					if x[j]-z[j] > 0 && x[j]/(x[j]-z[j]) < alpha {
+						alpha = x[j] / (x[j] - z[j])
+					}
+				}
+			}
+
+			if feasible {
+				x = z
+				break
+			}
+
+			for j := 0; j < n; j++ {
+				x[j] += alpha * (z[j] - x[j])
+				if passive[j] && x[j] <= tolerance {
+					x[j] = 0
+					passive[j] = false
+				}
+			}
+		}
+	}
+
+	return x
+}
+
+// passiveLeastSquares solves the unconstrained least squares problem over the passive variables, the
+// other variables are zero
+func passiveLeastSquares(a [][]float64, b []float64, passive []bool) []float64 {
+
+	var index []int
+	for j := range passive {
+		if passive[j] {
+			index = append(index, j)
+		}
+	}
+
+	// normal equations as an augmented matrix
+	size := len(index)
+	var m = make([][]float64, size)
+	for r := range m {
+		m[r] = make([]float64, size+1)
+		for c := range index {
+			for i := range a {
+				m[r][c] += a[i][index[r]] * a[i][index[c]]
+			}
+		}
+		for i := range a {
+			m[r][size] += a[i][index[r]] * b[i]
+		}
+	}
+
+	// gaussian elimination with partial pivoting
+	for c := 0; c < size; c++ {
+
+		pivot := c
+		for r := c + 1; r < size; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[pivot][c]) {
+				pivot = r
+			}
+		}
+		m[c], m[pivot] = m[pivot], m[c]
+
+		if m[c][c] == 0 {
+			continue
+		}
+
+		for r := c + 1; r < size; r++ {
+			f := m[r][c] / m[c][c]
+			for k := c; k <= size; k++ {
+				m[r][k] -= f * m[c][k]
+			}
+		}
+	}
+
+	var z = make([]float64, len(passive))
+	var solution = make([]float64, size)
+	for r := size - 1; r >= 0; r-- {
+		if m[r][r] == 0 {
+			continue
+		}
+		sum := m[r][size]
+		for k := r + 1; k < size; k++ {
+			sum -= m[r][k] * solution[k]
+		}
+		solution[r] = sum / m[r][r]
+	}
+
+	for r, j := range index {
+		z[j] = solution[r]
+	}
+
+	return z
+}
@@ -0,0 +1,86 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/rep"
+	"philosopher/lib/tmt"
+	"philosopher/lib/trq"
+)
+
+func Test_impurityMatrix(t *testing.T) {
+
+	// the 126 +1 isotope falls on 127C, not on 127N
+	m := impurityMatrix(tmt.New("10"), iso.Impurities{"126": {0, 0, 5, 0}})
+
+	if math.Abs(m[0][0]-0.95) > 1e-9 || math.Abs(m[2][0]-0.05) > 1e-9 || m[1][0] != 0 {
+		t.Errorf("126 column is %v, want 0.95 on 126 and 0.05 on 127C", []float64{m[0][0], m[1][0], m[2][0]})
+	}
+
+	// the TMTpro 128N -15N isotope falls on 127C and the -13C isotope on 127N
+	m = impurityMatrix(tmt.New("16"), iso.Impurities{"128N": {0, 0, 2, 1, 0, 0, 0, 0}})
+
+	if math.Abs(m[1][3]-0.02) > 1e-9 || math.Abs(m[2][3]-0.01) > 1e-9 || math.Abs(m[3][3]-0.97) > 1e-9 {
+		t.Errorf("128N column is %v, want 0.02 on 127N, 0.01 on 127C and 0.97 on 128N", []float64{m[1][3], m[2][3], m[3][3]})
+	}
+
+	// iTRAQ reporters have one channel per nominal mass
+	m = impurityMatrix(trq.New("4"), iso.Impurities{"115": {0, 2, 5, 0}})
+
+	if math.Abs(m[0][1]-0.02) > 1e-9 || math.Abs(m[2][1]-0.05) > 1e-9 || math.Abs(m[1][1]-0.93) > 1e-9 {
+		t.Errorf("115 column is %v, want 0.02, 0.93 and 0.05", []float64{m[0][1], m[1][1], m[2][1]})
+	}
+}
+
+func Test_nnls(t *testing.T) {
+
+	a := [][]float64{
+		{0.9, 0.1, 0},
+		{0.1, 0.8, 0.1},
+		{0, 0.1, 0.9},
+	}
+
+	// observed intensities of the true values 100, 50 and 0
+	b := []float64{95, 50, 5}
+
+	x := nnls(a, b)
+	want := []float64{100, 50, 0}
+
+	for i := range want {
+		if math.Abs(x[i]-want[i]) > 1e-6 {
+			t.Errorf("channel %d is %f, want %f", i, x[i], want[i])
+		}
+	}
+
+	// negative solutions are clipped to zero
+	x = nnls(a, []float64{90, 10, 0})
+	for i := range x {
+		if x[i] < 0 {
+			t.Errorf("channel %d is negative, %f", i, x[i])
+		}
+	}
+}
+
+func Test_correctImpurities(t *testing.T) {
+
+	template := tmt.New("10")
+	matrix := impurityMatrix(template, iso.Impurities{"126": {0, 0, 5, 0}})
+
+	labels := template.Copy()
+	labels.Channels[0].Intensity = 95
+	labels.Channels[2].Intensity = 5
+
+	corrected := correctImpurities(map[string]iso.Labels{"1": labels}, matrix)
+
+	// the corrected flag reaches the PSM, so the report prints the Is Corrected column
+	evi := mapLabeledSpectra(corrected, 0, []rep.PSMEvidence{{Spectrum: "run.1.1.2"}})
+	if evi[0].Labels.IsCorrected == false {
+		t.Errorf("the PSM labels are not flagged as corrected")
+	}
+
+	if math.Abs(evi[0].Labels.Channels[0].Intensity-100) > 1e-6 || math.Abs(evi[0].Labels.Channels[2].Intensity) > 1e-6 {
+		t.Errorf("corrected channels are %v, want 100 on 126 and 0 on 127C", evi[0].Labels.Channels)
+	}
+}
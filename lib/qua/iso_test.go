@@ -0,0 +1,47 @@
+package qua
+
+import (
+	"testing"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/mzn"
+)
+
+func Test_matchReporterIons(t *testing.T) {
+
+	template := iso.New([]iso.Reagent{{Name: "126", Mz: 126.127726}, {Name: "127", Mz: 127.124761}})
+
+	var spec mzn.Spectrum
+	spec.Mz.DecodedStream = []float64{126.1277, 126.1278, 127.1248}
+	spec.Intensity.DecodedStream = []float64{1000, 3000, 500}
+	spec.Noise.DecodedStream = []float64{100, 200, 50}
+
+	labels := template.Copy()
+	matchReporterIons(&labels, spec, 20e-6, reporterRange(template, 20e-6))
+
+	if labels.Channels[0].Intensity != 3000 || labels.Channels[0].SN != 15 || labels.Channels[1].SN != 10 {
+		t.Errorf("channels are %+v, want the most intense peak with S/N 15 and 10", labels.Channels)
+	}
+
+	if labels.SignalToNoise() != 25 {
+		t.Errorf("summed S/N is %f, want 25", labels.SignalToNoise())
+	}
+
+	sn, missing := signalToNoiseLabels(map[string]iso.Labels{"00001": labels})
+	if sn["00001"].Channels[0].Intensity != 15 || sn["00001"].Channels[1].Intensity != 10 || missing != 0 {
+		t.Errorf("S/N labels are %+v, want the S/N as intensities", sn["00001"].Channels)
+	}
+
+	// spectra without noise levels, like mzML, have no S/N
+	spec.Noise.DecodedStream = nil
+	labels = template.Copy()
+	matchReporterIons(&labels, spec, 20e-6, reporterRange(template, 20e-6))
+	if labels.SignalToNoise() != 0 {
+		t.Errorf("summed S/N is %f without noise levels, want 0", labels.SignalToNoise())
+	}
+
+	sn, missing = signalToNoiseLabels(map[string]iso.Labels{"00001": labels})
+	if sn["00001"].Channels[0].Intensity != 3000 || missing != 1 {
+		t.Errorf("S/N labels are %+v with %d missing, want the intensities kept", sn["00001"].Channels, missing)
+	}
+}
@@ -0,0 +1,80 @@
+package qua
+
+import (
+	"strings"
+
+	"philosopher/lib/rep"
+)
+
+// correctInterference removes the co-isolated signal from the reporter ions. The precursor purity is the
+// fraction of the isolation window that belongs to the identified precursor, the remaining signal is assumed
+// to follow the channel profile of the whole run, so that fraction of the reporter sum is subtracted from each
+// channel following that profile. The correction factor, the summed reporter signal kept after the correction,
+// is stored on each PSM.
+func correctInterference(evi rep.Evidence) rep.Evidence {
+
+	// the background profile of each run is the share of every channel on the summed reporter signal
+	var profiles = make(map[string][]float64)
+
+	for _, i := range evi.PSM {
+		source := strings.Split(i.Spectrum, ".")[0]
+
+		if _, ok := profiles[source]; !ok {
+			profiles[source] = make([]float64, len(i.Labels.Channels))
+		}
+
+		for j, c := range i.Labels.Channels {
+			if j < len(profiles[source]) {
+				profiles[source][j] += c.Intensity
+			}
+		}
+	}
+
+	for _, v := range profiles {
+		var sum float64
+		for _, i := range v {
+			sum += i
+		}
+
+		for j := range v {
+			if sum > 0 {
+				v[j] /= sum
+			}
+		}
+	}
+
+	for i := range evi.PSM {
+
+		// PSMs without MS1 information have no purity to correct with
+		if evi.PSM[i].Purity <= 0 {
+			continue
+		}
+
+		sum := evi.PSM[i].Labels.Sum()
+		if sum == 0 {
+			continue
+		}
+
+		profile := profiles[strings.Split(evi.PSM[i].Spectrum, ".")[0]]
+		interference := (1 - evi.PSM[i].Purity) * sum
+
+		// the labels are copied so the run template is never shared between PSMs
+		labels := evi.PSM[i].Labels.Copy()
+
+		for j := range labels.Channels {
+			if j >= len(profile) {
+				break
+			}
+
+			labels.Channels[j].Intensity -= interference * profile[j]
+			if labels.Channels[j].Intensity < 0 {
+				labels.Channels[j].Intensity = 0
+			}
+		}
+
+		evi.PSM[i].Labels = labels
+		evi.PSM[i].InterferenceCorrection = labels.Sum() / sum
+	}
+
+	return evi
+}
@@ -0,0 +1,38 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/rep"
+)
+
+func Test_correctInterference(t *testing.T) {
+
+	var evi rep.Evidence
+	evi.PSM = rep.PSMEvidenceList{
+		{Spectrum: "run.1.1.2", Purity: 0.8, Labels: labelsOf(100, 0)},
+		{Spectrum: "run.2.2.2", Purity: 1, Labels: labelsOf(50, 50)},
+		{Spectrum: "run.3.3.2", Purity: 0, Labels: labelsOf(0, 100)},
+	}
+
+	evi = correctInterference(evi)
+
+	// the run profile is 150/300 and 150/300, so 20% of the first PSM is taken equally from both channels
+	c := evi.PSM[0].Labels.Channels
+	if math.Abs(c[0].Intensity-90) > 1e-9 || c[1].Intensity != 0 {
+		t.Errorf("corrected channels are %+v, want 90 and 0", c)
+	}
+
+	if math.Abs(evi.PSM[0].InterferenceCorrection-0.9) > 1e-9 {
+		t.Errorf("correction factor is %f, want 0.9", evi.PSM[0].InterferenceCorrection)
+	}
+
+	if evi.PSM[1].Labels.Sum() != 100 || evi.PSM[1].InterferenceCorrection != 1 {
+		t.Errorf("pure precursor was corrected to %+v", evi.PSM[1].Labels.Channels)
+	}
+
+	if evi.PSM[2].Labels.Channels[1].Intensity != 100 || evi.PSM[2].InterferenceCorrection != 0 {
+		t.Errorf("PSM without purity was corrected to %+v", evi.PSM[2].Labels.Channels)
+	}
+}
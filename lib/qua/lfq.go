@@ -4,13 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"path/filepath"
 	"sort"
 	"strings"
 
 	"philosopher/lib/bio"
 	"philosopher/lib/msg"
-	"philosopher/lib/uti"
 
 	"philosopher/lib/mzn"
 	"philosopher/lib/rep"
@@ -33,7 +31,7 @@ func NewLFQ() LFQ {
 	return self
 }
 
-func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float64, isIso bool) rep.Evidence {
+func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float64, isIso, isFeature bool, threads int) rep.Evidence {
 
 	logrus.Info("Indexing PSM information")
 
@@ -44,7 +42,7 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 	var minRT = make(map[string]float64)
 	var maxRT = make(map[string]float64)
 	var retentionTime = make(map[string]float64)
-	var intensity = make(map[string]float64)
+	var peptideMass = make(map[string]float64)
 
 	var charges = make(map[string]int)
 
@@ -59,6 +57,7 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 		minRT[i.Spectrum] = (i.RetentionTime / 60) - rTWin
 		maxRT[i.Spectrum] = (i.RetentionTime / 60) + rTWin
 		retentionTime[i.Spectrum] = i.RetentionTime
+		peptideMass[i.Spectrum] = i.CalcNeutralPepMass
 
 		charges[i.Spectrum] = int(i.AssumedCharge)
 	}
@@ -71,53 +70,75 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 	sort.Strings(sourceMapList)
 
 	logrus.Info("Reading spectra and tracing peaks")
-	for _, s := range sourceMapList {
 
-		logrus.Info("Processing ", s)
-		var mz mzn.MsData
+	var files = make([]string, len(sourceMapList))
+	for i, s := range sourceMapList {
+		files[i] = sourceFile(dir, s, format)
+	}
 
-		fileName := fmt.Sprintf("%s%s%s.mzML", dir, string(filepath.Separator), s)
+	// runs are processed concurrently, each one keeps its own intensities until all workers are done
+	var runIntensity = make([]map[string]float64, len(sourceMapList))
+	var runFeature = make([]map[string]rep.Feature, len(sourceMapList))
 
-		// load MS1, ignore MS2 and MS3
-		mz.Read(fileName)
+	runPool(files, threads, func(r int) {
 
-		for i := range mz.Spectra {
-			if mz.Spectra[i].Level == "1" {
-				mz.Spectra[i].Decode()
-			} else if mz.Spectra[i].Level == "2" {
-				spectrum := fmt.Sprintf("%s.%05s.%05s.%d", s, mz.Spectra[i].Scan, mz.Spectra[i].Scan, mz.Spectra[i].Precursor.ChargeState)
+		s := sourceMapList[r]
+
+		logrus.Info("Processing ", s)
+		var mz mzn.Reader
+		var ms1 mzn.Spectra
+		var precursor = make(map[string]float64)
+		var intensity = make(map[string]float64)
+		var feature = make(map[string]rep.Feature)
+
+		// keep only the decoded MS1, MS2 spectra are used for the precursor values and MS3 are ignored
+		mz.OpenCached(files[r])
+
+		mz.AllSpectra(func(spec mzn.Spectrum) {
+			if spec.Level == "1" {
+				spec.Decode()
+				ms1 = append(ms1, spec)
+			} else if spec.Level == "2" {
+				spectrum := fmt.Sprintf("%s.%05s.%05s.%d", s, spec.Scan, spec.Scan, spec.Precursor.ChargeState)
 				_, ok := mzMap[spectrum]
 				if ok {
 					// update the MZ with the desired Precursor value from mzML
 					if isIso == true {
-						mzMap[spectrum] = mz.Spectra[i].Precursor.TargetIon
+						precursor[spectrum] = spec.Precursor.TargetIon
 					} else {
-						mzMap[spectrum] = mz.Spectra[i].Precursor.SelectedIon
+						precursor[spectrum] = spec.Precursor.SelectedIon
 					}
 				}
 			}
-		}
+		})
+
+		mz.Close()
 
 		v, ok := spectra[s]
 		if ok {
 			for _, j := range v {
 
-				measured, retrieved := xic(mz.Spectra, minRT[j], maxRT[j], ppmPrecision[j], mzMap[j])
-
-				// if j == "20180209_03_TP_1A.03130.03130.2#interact.pep.xml" {
-				// 	fmt.Println(measured)
-				// }
+				mzValue := mzMap[j]
+				if p, ok := precursor[j]; ok {
+					mzValue = p
+				}
 
-				if retrieved == true {
+				var timeW = retentionTime[j] / 60
 
-					// create the list of mz differences for each peak
-					var mzRatio []float64
-					for k := 1; k <= 6; k++ {
-						r := float64(k) * (float64(1) / float64(charges[j]))
-						mzRatio = append(mzRatio, uti.ToFixed(r, 2))
+				// the integrated area of the isotope envelope replaces the apex intensity
+				if isFeature == true {
+					f, traced := traceFeature(ms1, minRT[j], maxRT[j], timeW, pTWin, ppmPrecision[j], mzValue, peptideMass[j], charges[j])
+					if traced == true {
+						feature[j] = f
+						intensity[j] = f.Area
 					}
+					continue
+				}
+
+				measured, retrieved := xic(ms1, minRT[j], maxRT[j], ppmPrecision[j], mzValue)
+
+				if retrieved == true {
 
-					var timeW = retentionTime[j] / 60
 					var topI = 0.0
 
 					for k, v := range measured {
@@ -132,6 +153,23 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 				}
 			}
 		}
+
+		runIntensity[r] = intensity
+		runFeature[r] = feature
+	})
+
+	var intensity = make(map[string]float64)
+	for _, i := range runIntensity {
+		for k, v := range i {
+			intensity[k] = v
+		}
+	}
+
+	var feature = make(map[string]rep.Feature)
+	for _, i := range runFeature {
+		for k, v := range i {
+			feature[k] = v
+		}
 	}
 
 	for i := range evi.PSM {
@@ -139,6 +177,7 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 		_, ok := spectra[partName[0]]
 		if ok {
 			evi.PSM[i].Intensity = intensity[evi.PSM[i].Spectrum]
+			evi.PSM[i].Feature = feature[evi.PSM[i].Spectrum]
 		}
 	}
 
@@ -185,7 +224,7 @@ func xic(mz mzn.Spectra, minRT, maxRT, ppmPrecision, mzValue float64) (map[float
 	return list, false
 }
 
-func calculateIntensities(e rep.Evidence) rep.Evidence {
+func calculateIntensities(e rep.Evidence, enzyme string) rep.Evidence {
 
 	logrus.Info("Assigning intensities to data layers")
 
@@ -195,6 +234,7 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 
 	var peptideIntMap = make(map[string]float64)
 	var ionIntMap = make(map[string]float64)
+	var ionFeatureMap = make(map[string]rep.Feature)
 
 	for _, i := range e.PSM {
 
@@ -211,13 +251,26 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 		if ok {
 			if i.Intensity > ionV {
 				ionIntMap[i.IonForm] = i.Intensity
+				ionFeatureMap[i.IonForm] = i.Feature
 			}
 		} else {
 			ionIntMap[i.IonForm] = i.Intensity
+			ionFeatureMap[i.IonForm] = i.Feature
 		}
 
 	}
 
+	// ions transferred between runs have no PSMs, their intensities come from match-between-runs
+	var transferred = make(map[string][]rep.IonEvidence)
+	for _, i := range e.Ions {
+		if i.IsTransferred == true {
+			peptideIntMap[i.Sequence] += i.Intensity
+			ionIntMap[i.IonForm] = i.Intensity
+			ionFeatureMap[i.IonForm] = i.Feature
+			transferred[i.ProteinID] = append(transferred[i.ProteinID], i)
+		}
+	}
+
 	for i := range e.Peptides {
 		v, ok := peptideIntMap[e.Peptides[i].Sequence]
 		if ok {
@@ -229,9 +282,13 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 		v, ok := ionIntMap[e.Ions[i].IonForm]
 		if ok {
 			e.Ions[i].Intensity = v
+			e.Ions[i].Feature = ionFeatureMap[e.Ions[i].IonForm]
 		}
 	}
 
+	// unique and razor ion intensities used by the absolute abundances
+	var abundanceInt = make(map[string][]float64)
+
 	// protein intensities : top 3 most intense ions
 	for i := range e.Proteins {
 
@@ -253,6 +310,27 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 					razorInt = append(razorInt, v)
 				}
 
+				if k.IsUnique == true || k.IsURazor == true {
+					abundanceInt[e.Proteins[i].ProteinID] = append(abundanceInt[e.Proteins[i].ProteinID], v)
+				}
+
+			}
+		}
+
+		for _, k := range transferred[e.Proteins[i].ProteinID] {
+
+			totalInt = append(totalInt, k.Intensity)
+
+			if k.IsUnique == true {
+				uniqueInt = append(uniqueInt, k.Intensity)
+			}
+
+			if k.IsURazor == true {
+				razorInt = append(razorInt, k.Intensity)
+			}
+
+			if k.IsUnique == true || k.IsURazor == true {
+				abundanceInt[e.Proteins[i].ProteinID] = append(abundanceInt[e.Proteins[i].ProteinID], k.Intensity)
 			}
 		}
 
@@ -286,5 +364,7 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 
 	}
 
+	e = calculateIntensityAbundances(e, abundanceInt, enzyme)
+
 	return e
 }
@@ -0,0 +1,408 @@
+package qua
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"philosopher/lib/aln"
+	"philosopher/lib/bio"
+	"philosopher/lib/met"
+	"philosopher/lib/mzn"
+	"philosopher/lib/rep"
+	"philosopher/lib/uti"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mbrDecoyShift is the mass added to the transferred ions to build the decoy transfers,
+// an integer-like shift keeps the decoys on the peptide mass lattice
+const mbrDecoyShift = 11.0050
+
+// transfer is an ion identified in the donor workspaces and missing from the local runs
+type transfer struct {
+	psm rep.PSMEvidence
+	rt  []float64
+}
+
+// transferMatch is the best trace found in the local runs for a transferred ion
+type transferMatch struct {
+	feature rep.Feature
+	score   float64
+}
+
+// matchBetweenRuns transfers the ions identified in the donor workspaces to the local runs where they were
+// not identified. The donor runs are placed on the local reference run with their stored alignment models,
+// the runs without a model are aligned on the ions shared with the local runs and their models are stored
+// with the local ones. The ions are placed on every local run with the inverse of its alignment model. Each
+// transferred ion is traced together with a decoy built by shifting its mass, transfers are accepted at the
+// given FDR and added to the local ions and peptides, flagged with their q-values
+func matchBetweenRuns(evi rep.Evidence, alignment *aln.Alignment, donors []string, p met.Quantify) rep.Evidence {
+
+	logrus.Info("Matching ions between runs")
+
+	var local = bestIonPSMs(evi.PSM)
+	var candidates = make(map[string]*transfer)
+	var fitted bool
+
+	for _, d := range donors {
+
+		var donor rep.Evidence
+		rep.RestoreEVPSMWithPath(&donor, d)
+
+		var donorRuns = make(map[string]rep.PSMEvidenceList)
+		for _, i := range donor.PSM {
+			run := strings.Split(i.Spectrum, ".")[0]
+			donorRuns[run] = append(donorRuns[run], i)
+		}
+
+		var names []string
+		for k := range donorRuns {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, run := range names {
+
+			best := bestIonPSMs(donorRuns[run])
+
+			model, ok := alignment.Models[run]
+			if !ok {
+				var donorRT, localRT []float64
+				for k, v := range best {
+					if l, ok := local[k]; ok {
+						donorRT = append(donorRT, v.RetentionTime)
+						localRT = append(localRT, alignedTime(l))
+					}
+				}
+
+				model = aln.Fit(donorRT, localRT)
+				alignment.Models[run] = model
+				fitted = true
+
+				logrus.Info("Aligned ", run, " to ", alignment.Reference, " using ", len(donorRT), " shared ions")
+			}
+
+			for k, v := range best {
+				if _, ok := local[k]; ok {
+					continue
+				}
+
+				c, ok := candidates[k]
+				if !ok {
+					c = &transfer{psm: v}
+					candidates[k] = c
+				} else if v.Probability > c.psm.Probability {
+					c.psm = v
+				}
+
+				c.rt = append(c.rt, model.Align(v.RetentionTime))
+			}
+		}
+	}
+
+	if fitted == true {
+		alignment.Serialize()
+	}
+
+	if len(candidates) == 0 {
+		logrus.Info("No ions to transfer")
+		return evi
+	}
+
+	var ionForms []string
+	for k := range candidates {
+		ionForms = append(ionForms, k)
+	}
+	sort.Strings(ionForms)
+
+	var sourceMap = make(map[string]uint8)
+	for _, i := range evi.PSM {
+		sourceMap[strings.Split(i.Spectrum, ".")[0]] = 0
+	}
+
+	var runs []string
+	for s := range sourceMap {
+		runs = append(runs, s)
+	}
+	sort.Strings(runs)
+
+	var files = make([]string, len(runs))
+	for i, s := range runs {
+		files[i] = sourceFile(p.Dir, s, p.Format)
+	}
+
+	var runTargets = make([]map[string]transferMatch, len(files))
+	var runDecoys = make([]map[string]transferMatch, len(files))
+	var ppmPrecision = p.Tol / math.Pow(10, 6)
+
+	runPool(files, p.Threads, func(r int) {
+
+		logrus.Info("Tracing transferred ions on ", files[r])
+
+		ms1 := ms1Spectra(files[r])
+
+		runTargets[r] = make(map[string]transferMatch)
+		runDecoys[r] = make(map[string]transferMatch)
+
+		for _, k := range ionForms {
+
+			c := candidates[k]
+			rt := alignment.Models[runs[r]].Inverse(uti.Median(c.rt)) / 60
+			charge := int(c.psm.AssumedCharge)
+			mass := c.psm.CalcNeutralPepMass
+
+			for d, shift := range []float64{0, mbrDecoyShift} {
+
+				mz := (mass + shift + float64(charge)*bio.Proton) / float64(charge)
+
+				f, traced := traceFeature(ms1, rt-p.RTWin, rt+p.RTWin, rt, p.PTWin, ppmPrecision, mz, mass+shift, charge)
+				if traced == false {
+					continue
+				}
+
+				m := transferMatch{f, transferScore(f, rt, p.PTWin)}
+				if d == 0 {
+					runTargets[r][k] = m
+				} else {
+					runDecoys[r][k] = m
+				}
+			}
+		}
+	})
+
+	targets := bestMatches(runTargets)
+	decoys := bestMatches(runDecoys)
+
+	var targetScores, decoyScores []float64
+	var targetIons []string
+
+	for _, k := range ionForms {
+		if m, ok := targets[k]; ok {
+			targetScores = append(targetScores, m.score)
+			targetIons = append(targetIons, k)
+		}
+		if m, ok := decoys[k]; ok {
+			decoyScores = append(decoyScores, m.score)
+		}
+	}
+
+	qValues := transferQValues(targetScores, decoyScores)
+
+	var peptides = make(map[string]int)
+	for i := range evi.Peptides {
+		peptides[evi.Peptides[i].Sequence] = i
+	}
+
+	var accepted int
+
+	for i, k := range targetIons {
+
+		if qValues[i] > p.MBRFDR {
+			continue
+		}
+
+		psm := candidates[k].psm
+		m := targets[k]
+
+		var ion rep.IonEvidence
+
+		ion.IonForm = k
+		ion.Sequence = psm.Peptide
+		ion.ModifiedSequence = psm.ModifiedPeptide
+		ion.MZ = uti.Round(((psm.CalcNeutralPepMass + (float64(psm.AssumedCharge) * bio.Proton)) / float64(psm.AssumedCharge)), 5, 4)
+		ion.ChargeState = psm.AssumedCharge
+		ion.PeptideMass = psm.CalcNeutralPepMass
+		ion.PrecursorNeutralMass = psm.PrecursorNeutralMass
+		ion.AlignedRetentionTime = uti.Median(candidates[k].rt)
+		ion.NumberOfEnzymaticTermini = uint8(psm.NumberOfEnzymaticTermini)
+		ion.Probability = psm.Probability
+		ion.Expectation = psm.Expectation
+		ion.Protein = psm.Protein
+		ion.ProteinID = psm.ProteinID
+		ion.GeneName = psm.GeneName
+		ion.EntryName = psm.EntryName
+		ion.ProteinDescription = psm.ProteinDescription
+		ion.MappedProteins = psm.MappedProteins
+		ion.MappedGenes = psm.MappedGenes
+		ion.Modifications = psm.Modifications
+		ion.IsUnique = psm.IsUnique
+		ion.IsURazor = psm.IsURazor
+		ion.Spectra = make(map[string]int)
+		ion.Feature = m.feature
+		ion.IsTransferred = true
+		ion.TransferQValue = qValues[i]
+
+		if p.Feature == true {
+			ion.Intensity = m.feature.Area
+		} else {
+			ion.Intensity = m.feature.Apex
+		}
+
+		evi.Ions = append(evi.Ions, ion)
+
+		// peptides identified locally keep their status, the missing ones are added as transferred
+		if j, ok := peptides[psm.Peptide]; ok {
+			if evi.Peptides[j].IsTransferred == true && qValues[i] < evi.Peptides[j].TransferQValue {
+				evi.Peptides[j].TransferQValue = qValues[i]
+			}
+			if evi.Peptides[j].IsTransferred == true {
+				evi.Peptides[j].ChargeState[psm.AssumedCharge] = 0
+			}
+		} else {
+			var pep rep.PeptideEvidence
+
+			pep.Sequence = psm.Peptide
+			pep.ChargeState = map[uint8]uint8{psm.AssumedCharge: 0}
+			pep.Spectra = make(map[string]uint8)
+			pep.Protein = psm.Protein
+			pep.ProteinID = psm.ProteinID
+			pep.GeneName = psm.GeneName
+			pep.EntryName = psm.EntryName
+			pep.ProteinDescription = psm.ProteinDescription
+			pep.MappedProteins = psm.MappedProteins
+			pep.MappedGenes = psm.MappedGenes
+			pep.Probability = psm.Probability
+			pep.Modifications = psm.Modifications
+			pep.IsTransferred = true
+			pep.TransferQValue = qValues[i]
+
+			peptides[psm.Peptide] = len(evi.Peptides)
+			evi.Peptides = append(evi.Peptides, pep)
+		}
+
+		accepted++
+	}
+
+	logrus.Info("Transferred ", accepted, " ions from ", len(targetIons), " traced candidates")
+
+	return evi
+}
+
+// bestIonPSMs selects the most probable target PSM for every ion
+func bestIonPSMs(psm rep.PSMEvidenceList) map[string]rep.PSMEvidence {
+
+	var best = make(map[string]rep.PSMEvidence)
+
+	for _, i := range psm {
+		if i.IsDecoy == true {
+			continue
+		}
+		if b, ok := best[i.IonForm]; !ok || i.Probability > b.Probability {
+			best[i.IonForm] = i
+		}
+	}
+
+	return best
+}
+
+// transferScore rates a traced ion by its isotope correlation, penalized by the distance between
+// the apex and the expected retention time, both given in minutes
+func transferScore(f rep.Feature, rt, pTWin float64) float64 {
+
+	deviation := math.Abs(f.ApexRetentionTime/60-rt) / pTWin
+	if deviation > 1 {
+		deviation = 1
+	}
+
+	return f.IsotopeCorrelation * (1 - deviation)
+}
+
+// bestMatches keeps the best scoring trace for every ion among the local runs
+func bestMatches(runs []map[string]transferMatch) map[string]transferMatch {
+
+	var best = make(map[string]transferMatch)
+
+	for _, r := range runs {
+		for k, v := range r {
+			if b, ok := best[k]; !ok || v.score > b.score {
+				best[k] = v
+			}
+		}
+	}
+
+	return best
+}
+
+// transferQValues estimates the q-value of each target score from the decoy score distribution,
+// decoys are ranked first on ties
+func transferQValues(targets, decoys []float64) []float64 {
+
+	type scored struct {
+		score float64
+		index int
+		decoy bool
+	}
+
+	var list []scored
+	for i, s := range targets {
+		list = append(list, scored{s, i, false})
+	}
+	for _, s := range decoys {
+		list = append(list, scored{s, -1, true})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].score == list[j].score {
+			return list[i].decoy && !list[j].decoy
+		}
+		return list[i].score > list[j].score
+	})
+
+	var fdr = make([]float64, len(list))
+	var t, d float64
+
+	for i := range list {
+		if list[i].decoy {
+			d++
+		} else {
+			t++
+		}
+		fdr[i] = d / math.Max(t, 1)
+	}
+
+	var qValues = make([]float64, len(targets))
+	var min = math.Inf(1)
+
+	for i := len(list) - 1; i >= 0; i-- {
+		if fdr[i] < min {
+			min = fdr[i]
+		}
+		if !list[i].decoy {
+			qValues[list[i].index] = math.Min(min, 1)
+		}
+	}
+
+	return qValues
+}
+
+// alignedTime returns the aligned retention time of the PSM, or the measured one when the
+// workspace was not aligned
+func alignedTime(psm rep.PSMEvidence) float64 {
+
+	if psm.AlignedRetentionTime > 0 {
+		return psm.AlignedRetentionTime
+	}
+
+	return psm.RetentionTime
+}
+
+// ms1Spectra reads the decoded MS1 spectra from the spectrum file
+func ms1Spectra(f string) mzn.Spectra {
+
+	var mz mzn.Reader
+	var ms1 mzn.Spectra
+
+	mz.OpenCached(f)
+
+	mz.AllSpectra(func(spec mzn.Spectrum) {
+		if spec.Level == "1" {
+			spec.Decode()
+			ms1 = append(ms1, spec)
+		}
+	})
+
+	mz.Close()
+
+	return ms1
+}
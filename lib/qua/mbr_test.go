@@ -0,0 +1,21 @@
+package qua
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_transferQValues(t *testing.T) {
+
+	targets := []float64{0.99, 0.95, 0.9, 0.5, 0.4}
+	decoys := []float64{0.6, 0.3}
+
+	q := transferQValues(targets, decoys)
+	want := []float64{0, 0, 0, 0.2, 0.2}
+
+	for i := range want {
+		if math.Abs(q[i]-want[i]) > 1e-9 {
+			t.Errorf("q-value of target %d is %f, want %f", i, q[i], want[i])
+		}
+	}
+}
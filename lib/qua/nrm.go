@@ -0,0 +1,278 @@
+package qua
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+	"philosopher/lib/uti"
+)
+
+// labelLevels collects the channel sets of each evidence level, so they can be normalized on their own
+func labelLevels(evi *rep.Evidence) [][]*iso.Labels {
+
+	var psm, ions, peptides, total, unique, razor []*iso.Labels
+	var phosphoIons, phosphoPeptides, phosphoTotal, phosphoUnique, phosphoRazor []*iso.Labels
+
+	for i := range evi.PSM {
+		psm = append(psm, &evi.PSM[i].Labels)
+	}
+
+	for i := range evi.Ions {
+		ions = append(ions, &evi.Ions[i].Labels)
+		phosphoIons = append(phosphoIons, &evi.Ions[i].PhosphoLabels)
+	}
+
+	for i := range evi.Peptides {
+		peptides = append(peptides, &evi.Peptides[i].Labels)
+		phosphoPeptides = append(phosphoPeptides, &evi.Peptides[i].PhosphoLabels)
+	}
+
+	for i := range evi.Proteins {
+		total = append(total, &evi.Proteins[i].TotalLabels)
+		unique = append(unique, &evi.Proteins[i].UniqueLabels)
+		razor = append(razor, &evi.Proteins[i].URazorLabels)
+		phosphoTotal = append(phosphoTotal, &evi.Proteins[i].PhosphoTotalLabels)
+		phosphoUnique = append(phosphoUnique, &evi.Proteins[i].PhosphoUniqueLabels)
+		phosphoRazor = append(phosphoRazor, &evi.Proteins[i].PhosphoURazorLabels)
+	}
+
+	return [][]*iso.Labels{psm, ions, peptides, total, unique, razor, phosphoIons, phosphoPeptides, phosphoTotal, phosphoUnique, phosphoRazor}
+}
+
+// normalizeLabels applies the within-plex normalization to the PSM, ion, peptide and protein channels. The
+// total and median methods equalize the summed or the median channel intensities of each level, sl (sample
+// loading) scales every level with the factors of the used PSMs, and quantile gives all channels of a level
+// the same intensity distribution. The excluded channels, like the carrier of single-cell plexes, are not
+// normalized and do not take part on the factors of the other channels
+func normalizeLabels(evi rep.Evidence, method string, excluded []bool) rep.Evidence {
+
+	levels := labelLevels(&evi)
+
+	switch method {
+	case "", "none":
+		return evi
+	case "total":
+		for _, i := range levels {
+			scaleChannels(i, channelFactors(i, sumValues, excluded))
+		}
+	case "median":
+		for _, i := range levels {
+			scaleChannels(i, channelFactors(i, uti.Median, excluded))
+		}
+	case "sl":
+		var used []*iso.Labels
+		for i := range evi.PSM {
+			if evi.PSM[i].Labels.IsUsed == true {
+				used = append(used, &evi.PSM[i].Labels)
+			}
+		}
+		factors := channelFactors(used, sumValues, excluded)
+		for _, i := range levels {
+			scaleChannels(i, factors)
+		}
+	case "quantile":
+		for _, i := range levels {
+			quantileChannels(i, excluded)
+		}
+	default:
+		msg.Custom(errors.New("Unknown normalization method, please use total, median, sl, quantile or none"), "fatal")
+	}
+
+	return evi
+}
+
+// sumValues adds all values
+func sumValues(values []float64) float64 {
+
+	var s float64
+	for _, i := range values {
+		s += i
+	}
+
+	return s
+}
+
+// channelFactors calculates the factors bringing the summarized intensities of each channel to their average,
+// only the quantified values are summarized and the excluded channels keep their intensities
+func channelFactors(labels []*iso.Labels, summary func([]float64) float64, excluded []bool) []float64 {
+
+	var values [][]float64
+
+	for _, i := range labels {
+		for k, c := range i.Channels {
+			if k >= len(values) {
+				values = append(values, nil)
+			}
+			if c.Intensity > 0 {
+				values[k] = append(values[k], c.Intensity)
+			}
+		}
+	}
+
+	var summaries = make([]float64, len(values))
+	var average float64
+	var count float64
+
+	for k := range values {
+		if len(values[k]) > 0 && !isExcluded(excluded, k) {
+			summaries[k] = summary(values[k])
+			average += summaries[k]
+			count++
+		}
+	}
+
+	var factors = make([]float64, len(values))
+	for k := range factors {
+		factors[k] = 1
+		if summaries[k] > 0 {
+			factors[k] = (average / count) / summaries[k]
+		}
+	}
+
+	return factors
+}
+
+// scaleChannels multiplies the channel intensities by the normalization factors
+func scaleChannels(labels []*iso.Labels, factors []float64) {
+
+	for _, i := range labels {
+		for k := range i.Channels {
+			if k < len(factors) {
+				i.Channels[k].Intensity *= factors[k]
+			}
+		}
+	}
+
+	return
+}
+
+// quantileChannels replaces the intensities of each channel with the average intensity of the same quantile
+// over all channels. The channels without signal are left out of the ranking and stay at zero, so the channels
+// with missing values are ranked on their quantified values only, the excluded channels are left out
+func quantileChannels(labels []*iso.Labels, excluded []bool) {
+
+	var channels int
+	for _, i := range labels {
+		if len(i.Channels) > channels {
+			channels = len(i.Channels)
+		}
+	}
+
+	var sorted = make([][]float64, channels)
+	var used float64
+
+	for k := 0; k < channels; k++ {
+
+		if isExcluded(excluded, k) {
+			continue
+		}
+
+		for _, i := range labels {
+			if v := channelIntensity(i, k); v > 0 {
+				sorted[k] = append(sorted[k], v)
+			}
+		}
+
+		if len(sorted[k]) > 0 {
+			sort.Float64s(sorted[k])
+			used++
+		}
+	}
+
+	if used == 0 {
+		return
+	}
+
+	// the reference distribution is the average of the channel quantiles
+	reference := func(q float64) float64 {
+		var sum float64
+		for k := range sorted {
+			if len(sorted[k]) > 0 {
+				sum += quantile(sorted[k], q)
+			}
+		}
+		return sum / used
+	}
+
+	for k := range sorted {
+
+		n := len(sorted[k])
+		if n == 0 {
+			continue
+		}
+
+		for _, i := range labels {
+
+			if k >= len(i.Channels) || i.Channels[k].Intensity <= 0 {
+				continue
+			}
+
+			q := 0.5
+			if n > 1 {
+				q = float64(sort.SearchFloat64s(sorted[k], i.Channels[k].Intensity)) / float64(n-1)
+			}
+
+			i.Channels[k].Intensity = reference(q)
+		}
+	}
+
+	return
+}
+
+// isExcluded tells if the channel is left out of the normalization
+func isExcluded(excluded []bool, k int) bool {
+	return k < len(excluded) && excluded[k]
+}
+
+// channelIntensity returns the intensity of a channel, or zero when the labels do not have it
+func channelIntensity(labels *iso.Labels, k int) float64 {
+
+	if k < len(labels.Channels) {
+		return labels.Channels[k].Intensity
+	}
+
+	return 0
+}
+
+// calculateRatios calculates the log2 ratios of every channel against the reference channel, or against the
+// average of the sample channels when there is no reference, leaving out the excluded channels
+func calculateRatios(evi rep.Evidence, reference string, excluded []bool) rep.Evidence {
+
+	for _, level := range labelLevels(&evi) {
+		for _, i := range level {
+
+			var denominator float64
+
+			for _, c := range i.Channels {
+				if len(reference) > 0 && (c.Name == reference || c.CustomName == reference) {
+					denominator = c.Intensity
+				}
+			}
+
+			if len(reference) == 0 {
+				var samples int
+				for k := range i.Channels {
+					if !isExcluded(excluded, k) {
+						samples++
+					}
+				}
+				if samples > 0 {
+					denominator = sampleSum(*i, excluded) / float64(samples)
+				}
+			}
+
+			for k := range i.Channels {
+				i.Channels[k].Ratio = 0
+				if denominator > 0 && i.Channels[k].Intensity > 0 {
+					i.Channels[k].Ratio = math.Log2(i.Channels[k].Intensity / denominator)
+				}
+			}
+		}
+	}
+
+	return evi
+}
@@ -0,0 +1,90 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/rep"
+)
+
+func labelsOf(intensities ...float64) iso.Labels {
+
+	var l iso.Labels
+	for i, v := range intensities {
+		l.Channels = append(l.Channels, iso.Channel{Name: string(rune('A' + i)), Intensity: v})
+	}
+
+	return l
+}
+
+func Test_normalizeLabels(t *testing.T) {
+
+	var e rep.Evidence
+	e.PSM = rep.PSMEvidenceList{
+		{Labels: labelsOf(10, 20)},
+		{Labels: labelsOf(30, 60)},
+	}
+
+	e = normalizeLabels(e, "total", nil)
+
+	// the channel sums are 40 and 80, both are brought to 60
+	want := [][]float64{{15, 15}, {45, 45}}
+	for i := range want {
+		for k := range want[i] {
+			if math.Abs(e.PSM[i].Labels.Channels[k].Intensity-want[i][k]) > 1e-9 {
+				t.Errorf("PSM %d channel %d is %f, want %f", i, k, e.PSM[i].Labels.Channels[k].Intensity, want[i][k])
+			}
+		}
+	}
+
+	e.PSM = rep.PSMEvidenceList{
+		{Labels: labelsOf(1, 4)},
+		{Labels: labelsOf(2, 3)},
+	}
+
+	e = normalizeLabels(e, "quantile", nil)
+
+	// both channels get the mean distribution 2 and 3
+	if e.PSM[0].Labels.Channels[0].Intensity != 2 || e.PSM[0].Labels.Channels[1].Intensity != 3 || e.PSM[1].Labels.Channels[1].Intensity != 2 {
+		t.Errorf("quantile normalization gave %v and %v", e.PSM[0].Labels.Channels, e.PSM[1].Labels.Channels)
+	}
+
+	e.PSM = rep.PSMEvidenceList{
+		{Labels: labelsOf(1, 0)},
+		{Labels: labelsOf(2, 3)},
+		{Labels: labelsOf(4, 5)},
+	}
+
+	e = normalizeLabels(e, "quantile", nil)
+
+	// the missing value is not ranked, the lowest values of both channels get (1+3)/2
+	if e.PSM[0].Labels.Channels[1].Intensity != 0 || e.PSM[0].Labels.Channels[0].Intensity != 2 || e.PSM[1].Labels.Channels[1].Intensity != 2 {
+		t.Errorf("quantile normalization with a missing value gave %v and %v", e.PSM[0].Labels.Channels, e.PSM[1].Labels.Channels)
+	}
+}
+
+func Test_calculateRatios(t *testing.T) {
+
+	var e rep.Evidence
+	e.PSM = rep.PSMEvidenceList{{Labels: labelsOf(10, 40, 0)}}
+
+	e = calculateRatios(e, "A", nil)
+
+	if e.PSM[0].Labels.Channels[0].Ratio != 0 || e.PSM[0].Labels.Channels[1].Ratio != 2 || e.PSM[0].Labels.Channels[2].Ratio != 0 {
+		t.Errorf("ratios to the reference are %v", e.PSM[0].Labels.Channels)
+	}
+
+	e = calculateRatios(e, "", nil)
+
+	if math.Abs(e.PSM[0].Labels.Channels[1].Ratio-math.Log2(40.0/(50.0/3))) > 1e-9 {
+		t.Errorf("ratio to the channel average is %f", e.PSM[0].Labels.Channels[1].Ratio)
+	}
+
+	// the excluded carrier channel is left out of the average
+	e = calculateRatios(e, "", []bool{true, false, false})
+
+	if math.Abs(e.PSM[0].Labels.Channels[1].Ratio-1) > 1e-9 {
+		t.Errorf("ratio to the sample channel average is %f", e.PSM[0].Labels.Channels[1].Ratio)
+	}
+}
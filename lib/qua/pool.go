@@ -0,0 +1,69 @@
+package qua
+
+import (
+	"sync"
+
+	"philosopher/lib/mzn"
+	"philosopher/lib/sys"
+)
+
+// runPool calls fun for every run using at most the given number of concurrent workers.
+// Each run reserves an estimate of the memory it needs before starting, so runs wait
+// for others to finish when the available memory is not enough to load all of them.
+// A run larger than the memory budget is still processed, but alone
+func runPool(files []string, threads int, fun func(i int)) {
+
+	if threads < 1 {
+		threads = 1
+	}
+
+	budget := sys.AvailableMemory()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var cond = sync.NewCond(&mu)
+	var reserved uint64
+
+	var workers = make(chan struct{}, threads)
+
+	for i := range files {
+
+		weight := estimateMemory(files[i])
+		if budget > 0 && weight > budget {
+			weight = budget
+		}
+
+		workers <- struct{}{}
+
+		mu.Lock()
+		for budget > 0 && reserved > 0 && reserved+weight > budget {
+			cond.Wait()
+		}
+		reserved += weight
+		mu.Unlock()
+
+		wg.Add(1)
+		go func(i int, weight uint64) {
+			defer wg.Done()
+
+			fun(i)
+
+			mu.Lock()
+			reserved -= weight
+			cond.Broadcast()
+			mu.Unlock()
+
+			<-workers
+		}(i, weight)
+	}
+
+	wg.Wait()
+
+	return
+}
+
+// estimateMemory returns the expected memory footprint from a spectrum file, the size of its
+// decoded peak arrays since the MS1 spectra of a run are held in memory while it is processed
+func estimateMemory(f string) uint64 {
+	return mzn.DecodedSize(f)
+}
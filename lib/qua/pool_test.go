@@ -0,0 +1,43 @@
+package qua
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_runPool(t *testing.T) {
+
+	var files = make([]string, 20)
+	var calls = make([]int, len(files))
+
+	var mu sync.Mutex
+	var running, peak int
+
+	runPool(files, 4, func(i int) {
+
+		mu.Lock()
+		calls[i]++
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	for i := range calls {
+		if calls[i] != 1 {
+			t.Errorf("runPool() called run %d %d times", i, calls[i])
+		}
+	}
+
+	if peak > 4 {
+		t.Errorf("runPool() used %d workers, want at most 4", peak)
+	}
+}
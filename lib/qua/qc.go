@@ -0,0 +1,393 @@
+package qua
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/rep"
+	"philosopher/lib/sys"
+	"philosopher/lib/uti"
+)
+
+// runQC holds the labelling efficiency and the channel statistics of a run
+type runQC struct {
+	Run             string
+	PSMs            int
+	NTerm           int
+	NTermLabelled   int
+	Lysines         int
+	LysinesLabelled int
+	FullyLabelled   int
+	OverLabelled    int
+	Channels        []channelQC
+}
+
+// channelQC holds the intensity distribution of a channel, as log2 intensities of the quantified PSMs
+type channelQC struct {
+	Name       string
+	Quantified int
+	Missing    float64
+	Q1         float64
+	Median     float64
+	Q3         float64
+	CV         float64
+	Values     []float64
+}
+
+// labelMass returns the mass of the isobaric tag, or zero when the plex has no known tag
+func labelMass(brand, plex string) float64 {
+
+	if brand == "tmt" {
+		if plex == "16" || plex == "18" {
+			return 304.207146
+		}
+		return 229.162932
+	}
+
+	if brand == "itraq" {
+		if plex == "8" {
+			return 304.205360
+		}
+		return 144.102063
+	}
+
+	return 0
+}
+
+// labelSites checks the tag on the PSM modifications, telling if the N-terminus is free for labelling and
+// labelled, how many lysines are there and labelled, and how many S, T and Y residues carry the tag
+func labelSites(psm rep.PSMEvidence, mass float64) (bool, bool, int, int, int) {
+
+	var nTerm = true
+	var nTermLabelled bool
+	var labelled = make(map[int]bool)
+
+	for _, i := range psm.Modifications.Index {
+
+		if i.Type == "Observed" {
+			continue
+		}
+
+		isLabel := math.Abs(i.MassDiff-mass) <= labelTolerance
+
+		if i.AminoAcid == "N-term" {
+			if isLabel {
+				nTermLabelled = true
+			} else {
+				// blocked N-termini, like the protein N-terminal acetylation, can not be labelled
+				nTerm = false
+			}
+		} else if p, e := strconv.Atoi(i.Position); e == nil && p > 0 && isLabel {
+			labelled[p] = true
+		}
+	}
+
+	if nTermLabelled {
+		nTerm = true
+	}
+
+	var lysines, lysinesLabelled, overLabelled int
+	for i, aa := range psm.Peptide {
+		switch aa {
+		case 'K':
+			lysines++
+			if labelled[i+1] {
+				lysinesLabelled++
+			}
+		case 'S', 'T', 'Y':
+			if labelled[i+1] {
+				overLabelled++
+			}
+		}
+	}
+
+	return nTerm, nTermLabelled, lysines, lysinesLabelled, overLabelled
+}
+
+// labellingQC calculates for each run the labelling efficiency at the peptide N-termini and lysines, the
+// over-labelling on S, T and Y, and the intensity distribution, missing rate and CV of each channel. The CV
+// is calculated on the channel share of the summed reporter intensity of each PSM
+func labellingQC(evi rep.Evidence, mass, minProb float64) []runQC {
+
+	var runs = make(map[string]*runQC)
+	var names []string
+
+	var shares = make(map[string][][]float64)
+
+	for _, i := range evi.PSM {
+
+		if i.IsDecoy == true || i.Probability < minProb {
+			continue
+		}
+
+		run := strings.Split(i.Spectrum, ".")[0]
+		r, ok := runs[run]
+		if !ok {
+			r = &runQC{Run: run}
+			runs[run] = r
+			names = append(names, run)
+		}
+
+		r.PSMs++
+
+		nTerm, nTermLabelled, lysines, lysinesLabelled, overLabelled := labelSites(i, mass)
+
+		if nTerm {
+			r.NTerm++
+		}
+		if nTermLabelled {
+			r.NTermLabelled++
+		}
+		r.Lysines += lysines
+		r.LysinesLabelled += lysinesLabelled
+
+		if nTerm == nTermLabelled && lysines == lysinesLabelled {
+			r.FullyLabelled++
+		}
+		if overLabelled > 0 {
+			r.OverLabelled++
+		}
+
+		sum := i.Labels.Sum()
+		if sum == 0 {
+			continue
+		}
+
+		if len(r.Channels) < len(i.Labels.Channels) {
+			r.Channels = make([]channelQC, len(i.Labels.Channels))
+			shares[run] = make([][]float64, len(i.Labels.Channels))
+		}
+
+		for k, c := range i.Labels.Channels {
+			r.Channels[k].Name = c.Name
+			if c.Intensity > 0 {
+				r.Channels[k].Values = append(r.Channels[k].Values, math.Log2(c.Intensity))
+			} else {
+				r.Channels[k].Missing++
+			}
+			shares[run][k] = append(shares[run][k], c.Intensity/sum)
+		}
+	}
+
+	sort.Strings(names)
+
+	var list []runQC
+	for _, i := range names {
+
+		r := runs[i]
+
+		for k := range r.Channels {
+
+			c := &r.Channels[k]
+			c.Quantified = len(c.Values)
+
+			if total := float64(c.Quantified) + c.Missing; total > 0 {
+				c.Missing /= total
+			}
+
+			sorted := make([]float64, len(c.Values))
+			copy(sorted, c.Values)
+			sort.Float64s(sorted)
+
+			c.Q1 = quantile(sorted, 0.25)
+			c.Median = uti.Median(sorted)
+			c.Q3 = quantile(sorted, 0.75)
+			c.CV = coefficientOfVariation(shares[i][k])
+		}
+
+		list = append(list, *r)
+	}
+
+	return list
+}
+
+// quantile returns the linearly interpolated quantile of sorted values
+func quantile(sorted []float64, q float64) float64 {
+
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	position := q * float64(len(sorted)-1)
+	low := int(math.Floor(position))
+	high := int(math.Ceil(position))
+
+	return sorted[low] + (position-float64(low))*(sorted[high]-sorted[low])
+}
+
+// coefficientOfVariation returns the standard deviation of the values divided by their mean
+func coefficientOfVariation(values []float64) float64 {
+
+	if len(values) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, i := range values {
+		mean += i
+	}
+	mean /= float64(len(values))
+
+	if mean == 0 {
+		return 0
+	}
+
+	var variance float64
+	for _, i := range values {
+		variance += (i - mean) * (i - mean)
+	}
+	variance /= float64(len(values) - 1)
+
+	return math.Sqrt(variance) / mean
+}
+
+// fraction divides two counts, or returns zero when there is nothing to count
+func fraction(part, total int) float64 {
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) / float64(total)
+}
+
+// saveLabellingQC writes the labelling efficiency and the channel statistics of each run, as tsv tables and
+// an HTML summary
+func saveLabellingQC(runs []runQC) {
+
+	efficiency := fmt.Sprintf("%s%slabelling_efficiency.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, e := os.Create(efficiency)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the labelling efficiency report"), "fatal")
+	}
+
+	_, e = io.WriteString(file, "Run\tPSMs\tN-term\tN-term Labelled\tN-term Efficiency\tLysines\tLysines Labelled\tLysine Efficiency\tFully Labelled PSMs\tFull Labelling Efficiency\tS/T/Y Over-labelled PSMs\tOver-labelling Rate\n")
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range runs {
+		line := fmt.Sprintf("%s\t%d\t%d\t%d\t%.4f\t%d\t%d\t%.4f\t%d\t%.4f\t%d\t%.4f\n",
+			i.Run,
+			i.PSMs,
+			i.NTerm,
+			i.NTermLabelled,
+			fraction(i.NTermLabelled, i.NTerm),
+			i.Lysines,
+			i.LysinesLabelled,
+			fraction(i.LysinesLabelled, i.Lysines),
+			i.FullyLabelled,
+			fraction(i.FullyLabelled, i.PSMs),
+			i.OverLabelled,
+			fraction(i.OverLabelled, i.PSMs),
+		)
+		_, e = io.WriteString(file, line)
+		if e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	file.Close()
+
+	channels := fmt.Sprintf("%s%schannel_qc.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, e = os.Create(channels)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the channel quality control report"), "fatal")
+	}
+
+	_, e = io.WriteString(file, "Run\tChannel\tQuantified PSMs\tMissing Rate\tLog2 Intensity Q1\tLog2 Intensity Median\tLog2 Intensity Q3\tCV\n")
+	if e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	for _, i := range runs {
+		for _, j := range i.Channels {
+			line := fmt.Sprintf("%s\t%s\t%d\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\n", i.Run, j.Name, j.Quantified, j.Missing, j.Q1, j.Median, j.Q3, j.CV)
+			_, e = io.WriteString(file, line)
+			if e != nil {
+				msg.WriteToFile(e, "fatal")
+			}
+		}
+	}
+
+	file.Close()
+
+	summary := fmt.Sprintf("%s%slabelling_qc.html", sys.MetaDir(), string(filepath.Separator))
+
+	file, e = os.Create(summary)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the labelling quality control summary"), "fatal")
+	}
+
+	io.WriteString(file, "<head>\n")
+	io.WriteString(file, "  <script src=\"https://cdn.plot.ly/plotly-latest.min.js\"></script>\n")
+	io.WriteString(file, "</head>\n")
+	io.WriteString(file, "<body>\n")
+	io.WriteString(file, "<h2>Labelling efficiency</h2>\n")
+	io.WriteString(file, "<table border=\"1\" cellpadding=\"4\">\n")
+	io.WriteString(file, "<tr><th>Run</th><th>PSMs</th><th>N-term Efficiency</th><th>Lysine Efficiency</th><th>Full Labelling Efficiency</th><th>Over-labelling Rate</th></tr>\n")
+	for _, i := range runs {
+		io.WriteString(file, fmt.Sprintf("<tr><td>%s</td><td>%d</td><td>%.2f%%</td><td>%.2f%%</td><td>%.2f%%</td><td>%.2f%%</td></tr>\n",
+			i.Run,
+			i.PSMs,
+			100*fraction(i.NTermLabelled, i.NTerm),
+			100*fraction(i.LysinesLabelled, i.Lysines),
+			100*fraction(i.FullyLabelled, i.PSMs),
+			100*fraction(i.OverLabelled, i.PSMs),
+		))
+	}
+	io.WriteString(file, "</table>\n")
+
+	io.WriteString(file, "<h2>Channels</h2>\n")
+	io.WriteString(file, "<table border=\"1\" cellpadding=\"4\">\n")
+	io.WriteString(file, "<tr><th>Run</th><th>Channel</th><th>Quantified PSMs</th><th>Missing Rate</th><th>Log2 Median Intensity</th><th>CV</th></tr>\n")
+	for _, i := range runs {
+		for _, j := range i.Channels {
+			io.WriteString(file, fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%d</td><td>%.2f%%</td><td>%.2f</td><td>%.4f</td></tr>\n", i.Run, j.Name, j.Quantified, 100*j.Missing, j.Median, j.CV))
+		}
+	}
+	io.WriteString(file, "</table>\n")
+
+	for k, i := range runs {
+
+		io.WriteString(file, fmt.Sprintf("<div id=\"run%d\" style=\"width: 1024px; height: 512px;\"></div>\n", k))
+		io.WriteString(file, "<script>\n")
+
+		var traces []string
+		for j, c := range i.Channels {
+
+			var values []string
+			for _, v := range c.Values {
+				values = append(values, fmt.Sprintf("%.3f", v))
+			}
+
+			io.WriteString(file, fmt.Sprintf("var trace%d_%d = {y: [%s], name: '%s', type: 'box'};\n", k, j, strings.Join(values, ","), c.Name))
+			traces = append(traces, fmt.Sprintf("trace%d_%d", k, j))
+		}
+
+		io.WriteString(file, fmt.Sprintf("var layout = {title: '%s channel intensities', yaxis: {title: 'log2 intensity'}, showlegend: false};\n", i.Run))
+		io.WriteString(file, fmt.Sprintf("Plotly.newPlot('run%d', [%s], layout);\n", k, strings.Join(traces, ", ")))
+		io.WriteString(file, "</script>\n")
+	}
+
+	io.WriteString(file, "</body>")
+
+	file.Close()
+
+	// copy to work directory
+	sys.CopyFile(efficiency, filepath.Base(efficiency))
+	sys.CopyFile(channels, filepath.Base(channels))
+	sys.CopyFile(summary, filepath.Base(summary))
+
+	return
+}
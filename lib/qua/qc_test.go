@@ -0,0 +1,55 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/rep"
+)
+
+func Test_labelSites(t *testing.T) {
+
+	var psm rep.PSMEvidence
+	psm.Peptide = "PESKAK"
+	psm.Modifications.Index = map[string]mod.Modification{
+		"n":  {AminoAcid: "N-term", MassDiff: 229.1629, Type: "Assigned"},
+		"K4": {Position: "4", AminoAcid: "K", MassDiff: 229.1629, Type: "Assigned"},
+		"S3": {Position: "3", AminoAcid: "S", MassDiff: 229.1629, Type: "Assigned"},
+	}
+
+	nTerm, nTermLabelled, lysines, lysinesLabelled, overLabelled := labelSites(psm, 229.162932)
+	if !nTerm || !nTermLabelled || lysines != 2 || lysinesLabelled != 1 || overLabelled != 1 {
+		t.Errorf("labelSites() = %t, %t, %d, %d, %d, want true, true, 2, 1, 1", nTerm, nTermLabelled, lysines, lysinesLabelled, overLabelled)
+	}
+
+	psm.Modifications.Index = map[string]mod.Modification{"n": {AminoAcid: "N-term", MassDiff: 42.0106, Type: "Assigned"}}
+	if nTerm, _, _, _, _ := labelSites(psm, 229.162932); nTerm {
+		t.Errorf("acetylated N-terminus is counted as free for labelling")
+	}
+}
+
+func Test_labellingQC(t *testing.T) {
+
+	var evi rep.Evidence
+
+	evi.PSM = rep.PSMEvidenceList{
+		{Spectrum: "run.1.1.2", Peptide: "PEPTIDE", Probability: 0.99, Labels: labelsOf(8, 8), Modifications: mod.Modifications{Index: map[string]mod.Modification{"n": {AminoAcid: "N-term", MassDiff: 229.1629}}}},
+		{Spectrum: "run.2.2.2", Peptide: "PEPTIDE", Probability: 0.99, Labels: labelsOf(4, 0)},
+		{Spectrum: "run.3.3.2", Peptide: "PEPTIDE", Probability: 0.1, Labels: labelsOf(4, 4)},
+	}
+
+	runs := labellingQC(evi, 229.162932, 0.7)
+	if len(runs) != 1 || runs[0].PSMs != 2 || runs[0].NTermLabelled != 1 || runs[0].FullyLabelled != 1 {
+		t.Fatalf("labellingQC() = %+v", runs)
+	}
+
+	c := runs[0].Channels[1]
+	if c.Quantified != 1 || c.Missing != 0.5 || c.Median != 3 {
+		t.Errorf("channel B is %+v, want 1 quantified PSM, 50%% missing and median 3", c)
+	}
+
+	if math.Abs(quantile([]float64{1, 2, 3, 4, 5}, 0.25)-2) > 1e-9 {
+		t.Errorf("first quartile is %f, want 2", quantile([]float64{1, 2, 3, 4, 5}, 0.25))
+	}
+}
@@ -3,10 +3,12 @@ package qua
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"philosopher/lib/aln"
 	"philosopher/lib/iso"
 	"philosopher/lib/met"
 	"philosopher/lib/msg"
@@ -32,8 +34,10 @@ func (p PairList) Len() int           { return len(p) }
 func (p PairList) Less(i, j int) bool { return p[i].Value < p[j].Value }
 func (p PairList) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
-// RunLabelFreeQuantification is the top function for label free quantification
-func RunLabelFreeQuantification(p met.Quantify) {
+// RunLabelFreeQuantification is the top function for label free quantification, the donor
+// workspaces provide the identifications transferred with match-between-runs and the enzyme of the
+// database gives the theoretical peptides of the iBAQ abundances
+func RunLabelFreeQuantification(p met.Quantify, donors []string, enzyme string) {
 
 	// This parameter is hardcoded now because of the changes in the latest msconvert version 3.20.
 	p.Isolated = true
@@ -41,9 +45,23 @@ func RunLabelFreeQuantification(p met.Quantify) {
 	var evi rep.Evidence
 	evi.RestoreGranular()
 
-	evi = peakIntensity(evi, p.Dir, p.Format, p.RTWin, p.PTWin, p.Tol, p.Isolated)
+	// align the runs first, so the transferred ions can be placed on every run
+	alignment := aln.New()
+	alignment.Restore()
+	evi = alignment.Run(evi)
 
-	evi = calculateIntensities(evi)
+	evi = peakIntensity(evi, p.Dir, p.Format, p.RTWin, p.PTWin, p.Tol, p.Isolated, p.Feature, p.Threads)
+
+	if p.MBR == true {
+		evi = matchBetweenRuns(evi, &alignment, donors, p)
+	}
+
+	evi = calculateIntensities(evi, enzyme)
+
+	// labelled pairs are quantified on the MS1 scans when the heavy form is defined
+	if len(p.Heavy) > 0 {
+		evi = quantifyMS1Labels(evi, p)
+	}
 
 	evi.SerializeGranular()
 
@@ -57,15 +75,23 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 	var sourceMap = make(map[string][]rep.PSMEvidence)
 	var sourceList []string
 
-	if p.Brand == "" {
+	if p.Brand == "" && len(p.Reagents) < 1 {
 		msg.NoParametersFound(errors.New("You need to specify a brand type (tmt or itraq)"), "fatal")
 	}
 
 	var evi rep.Evidence
 	evi.RestoreGranular()
 
+	template := labelTemplate(p.Brand, p.Plex, p.Reagents)
+
+	// the reporter ions are corrected with the isotopic impurities of the reagent lot
+	var impurities [][]float64
+	if p.Correct == true || len(p.Impurity) > 0 {
+		impurities = impurityMatrix(template, labelImpurities(p.Brand, p.Plex, p.Impurity))
+	}
+
 	// removed all calculated defined values from before
-	evi = cleanPreviousData(evi, p.Brand, p.Plex)
+	evi = cleanPreviousData(evi, template)
 
 	// collect all used source file names
 	for _, i := range evi.PSM {
@@ -84,40 +110,73 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 
 	// read the annotation file
 	p.LabelNames = make(map[string]string)
+	p.LabelRoles = make(map[string]string)
 	if len(p.Annot) > 0 {
 		p.LabelNames = uti.GetLabelNames(p.Annot)
+		p.LabelRoles = uti.GetLabelRoles(p.Annot)
 	}
 
+	// the carrier, empty and reference channels of single-cell plexes are left out of the sums and the normalization
+	excluded, carrier := channelRoles(template, p.LabelRoles)
+
 	logrus.Info("Calculating intensities and ion interference")
 
+	var files = make([]string, len(sourceList))
 	for i := range sourceList {
+		files[i] = sourceFile(p.Dir, sourceList[i], p.Format)
+	}
 
-		var mz mzn.MsData
+	// runs are processed concurrently, purity and labels are merged afterwards following the run order
+	var mappedPurity = make([][]rep.PSMEvidence, len(sourceList))
+	var mappedPSM = make([][]rep.PSMEvidence, len(sourceList))
+	var spsPSMs = make([]int, len(sourceList))
 
-		logrus.Info("Processing ", sourceList[i])
-		fileName := fmt.Sprintf("%s%s%s.mzML", p.Dir, string(filepath.Separator), sourceList[i])
+	runPool(files, p.Threads, func(i int) {
 
-		mz.Read(fileName)
+		var mz mzn.Reader
 
-		for i := range mz.Spectra {
-			mz.Spectra[i].Decode()
-		}
+		logrus.Info("Processing ", sourceList[i])
+
+		mz.OpenCached(files[i])
 
-		mappedPurity := calculateIonPurity(p.Dir, p.Format, mz, sourceMap[sourceList[i]])
+		mappedPurity[i] = calculateIonPurity(p.Dir, p.Format, &mz, sourceMap[sourceList[i]])
 
 		var labels map[string]iso.Labels
+		var sps map[string][]float64
 		if p.Level == 3 {
-			labels = prepareLabelStructureWithMS3(p.Dir, p.Format, p.Brand, p.Plex, p.Tol, mz)
+			labels, sps = prepareLabelStructureWithMS3(p.Dir, p.Format, template, p.Tol, &mz)
 
 		} else {
-			labels = prepareLabelStructureWithMS2(p.Dir, p.Format, p.Brand, p.Plex, p.Tol, mz)
+			labels = prepareLabelStructureWithMS2(p.Dir, p.Format, template, p.Tol, &mz)
 		}
 
-		labels = assignLabelNames(labels, p.LabelNames, p.Brand, p.Plex)
+		mz.Close()
 
-		mappedPSM := mapLabeledSpectra(labels, p.Purity, sourceMap[sourceList[i]])
+		// the signal-to-noise values replace the intensities, so the roll-ups sum the S/N of the channels
+		if p.SN == true {
+			var missing int
+			labels, missing = signalToNoiseLabels(labels)
+			if missing > 0 {
+				msg.Custom(fmt.Errorf("%d spectra from %s have no noise levels, their reporter intensities are kept", missing, sourceList[i]), "warning")
+			}
+		}
+
+		if impurities != nil {
+			labels = correctImpurities(labels, impurities)
+		}
 
-		for _, j := range mappedPurity {
+		labels = assignLabelNames(labels, p.LabelNames)
+
+		mappedPSM[i] = mapLabeledSpectra(labels, p.Purity, sourceMap[sourceList[i]])
+
+		if sps != nil {
+			mappedPSM[i], spsPSMs[i] = matchSPSIons(mappedPSM[i], sps)
+		}
+	})
+
+	for i := range sourceList {
+
+		for _, j := range mappedPurity[i] {
 			v, ok := psmMap[j.Spectrum]
 			if ok {
 				psm := v
@@ -126,15 +185,15 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 			}
 		}
 
-		for _, j := range mappedPSM {
+		for _, j := range mappedPSM[i] {
 			v, ok := psmMap[j.Spectrum]
 			if ok {
 				psm := v
 				psm.Labels = j.Labels
+				psm.SPSMatch = j.SPSMatch
 				psmMap[j.Spectrum] = psm
 			}
 		}
-
 	}
 
 	for i := range evi.PSM {
@@ -142,13 +201,56 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 		if ok {
 			evi.PSM[i].Purity = v.Purity
 			evi.PSM[i].Labels = v.Labels
+			evi.PSM[i].SPSMatch = v.SPSMatch
 		}
 	}
 	//psmMap = nil
 
+	// the labelling quality control uses the reporter intensities before the filters and the normalization
+	if p.QC == true {
+		logrus.Info("Calculating the labelling efficiency")
+		mass := labelMass(p.Brand, p.Plex)
+		if mass == 0 {
+			msg.Custom(errors.New("The labelling quality control needs the tmt or itraq brand"), "warning")
+		} else {
+			saveLabellingQC(labellingQC(evi, mass, p.MinProb))
+		}
+	}
+
+	// the co-isolated signal is removed before the filters, so the roll-ups use the corrected channels
+	if p.Interfere == true {
+		logrus.Info("Correcting the reporter ions for precursor interference")
+		evi = correctInterference(evi)
+	}
+
+	var hasCarrier bool
+	for _, i := range carrier {
+		if i == true {
+			hasCarrier = true
+		}
+	}
+
+	if hasCarrier == true {
+		logrus.Info("Calculating the sample to carrier ratios")
+		evi = carrierRatios(evi, excluded, carrier, p.CarrierCap)
+	} else if p.CarrierCap > 0 {
+		msg.Custom(errors.New("There are no carrier channels on the annotation file, the carrier ratio cap will be disabled"), "warning")
+	}
+
+	// the SPS filter would drop every PSM when the MS3 scans do not carry their SPS ions
+	var spsFound int
+	for _, i := range spsPSMs {
+		spsFound += i
+	}
+
+	if p.SPSMatch > 0 && spsFound == 0 {
+		msg.Custom(errors.New("No SPS ions were found on the MS3 scans, the SPS match filter will be disabled"), "warning")
+		p.SPSMatch = 0
+	}
+
 	// classification and filtering based on quality filters
 	logrus.Info("Filtering spectra for label quantification")
-	spectrumMap, phosphoSpectrumMap := classification(evi, mods, p.BestPSM, p.RemoveLow, p.Purity, p.MinProb)
+	spectrumMap, phosphoSpectrumMap := classification(evi, mods, p.BestPSM, p.RemoveLow, p.Purity, p.MinProb, p.SPSMatch, p.MinSN, excluded)
 
 	// assignment happens only for general PSMs
 	evi = assignUsage(evi, spectrumMap)
@@ -162,9 +264,11 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 
 	evi = rollUpProteins(evi, spectrumMap, phosphoSpectrumMap)
 
-	// normalize to the total protein levels
-	logrus.Info("Calculating normalized protein levels")
-	evi = NormToTotalProteins(evi)
+	// normalize the channels of every level and compare them to the reference
+	logrus.Info("Normalizing channel intensities and calculating ratios")
+	evi = normalizeLabels(evi, p.ChanNorm, excluded)
+
+	evi = calculateRatios(evi, p.RefChan, excluded)
 
 	logrus.Info("Saving")
 
@@ -210,228 +314,123 @@ func RunBioQuantification(c met.Data) {
 	return
 }
 
+// sourceFile builds the spectrum file name for the given run, the extension is
+// accepted as given, in upper or in lower case, or gzip compressed
+func sourceFile(dir, source, format string) string {
+
+	fileName := fmt.Sprintf("%s%s%s.%s", dir, string(filepath.Separator), source, format)
+
+	// extensions are not always written with the same case, like .RAW or .MGF
+	if _, e := os.Stat(fileName); os.IsNotExist(e) {
+		for _, ext := range []string{strings.ToUpper(format), strings.ToLower(format), format + ".gz"} {
+			alt := fmt.Sprintf("%s%s%s.%s", dir, string(filepath.Separator), source, ext)
+			if _, e := os.Stat(alt); e == nil {
+				return alt
+			}
+		}
+	}
+
+	return fileName
+}
+
 // cleanPreviousData cleans previous label quantifications
-func cleanPreviousData(evi rep.Evidence, brand, plex string) rep.Evidence {
+func cleanPreviousData(evi rep.Evidence, template iso.Labels) rep.Evidence {
 
 	for i := range evi.PSM {
-		if brand == "tmt" {
-			evi.PSM[i].Labels = tmt.New(plex)
-		} else if brand == "itraq" {
-			evi.PSM[i].Labels = trq.New(plex)
-		}
+		evi.PSM[i].Labels = template.Copy()
+		evi.PSM[i].InterferenceCorrection = 0
+		evi.PSM[i].CarrierRatio = 0
+		evi.PSM[i].IsAboveCarrierCap = false
 	}
 
 	for i := range evi.Ions {
-		if brand == "tmt" {
-			evi.Ions[i].Labels = tmt.New(plex)
-		} else if brand == "itraq" {
-			evi.Ions[i].Labels = trq.New(plex)
-		}
+		evi.Ions[i].Labels = template.Copy()
 	}
 
 	for i := range evi.Proteins {
-		if brand == "tmt" {
-			evi.Proteins[i].TotalLabels = tmt.New(plex)
-			evi.Proteins[i].UniqueLabels = tmt.New(plex)
-			evi.Proteins[i].URazorLabels = tmt.New(plex)
-		} else if brand == "itraq" {
-			evi.Proteins[i].TotalLabels = trq.New(plex)
-			evi.Proteins[i].UniqueLabels = trq.New(plex)
-			evi.Proteins[i].URazorLabels = trq.New(plex)
-		}
+		evi.Proteins[i].TotalLabels = template.Copy()
+		evi.Proteins[i].UniqueLabels = template.Copy()
+		evi.Proteins[i].URazorLabels = template.Copy()
 	}
 
 	return evi
 }
 
-// checks for custom names and assign the normal channel or the custom name to the CustomName
-func assignLabelNames(labels map[string]iso.Labels, labelNames map[string]string, brand, plex string) map[string]iso.Labels {
-
-	for k, v := range labels {
-		v2 := v
-
-		if brand == "tmt" {
-
-			if len(labelNames["126"]) < 1 {
-				v2.Channel1.CustomName = "126"
-			} else {
-				v2.Channel1.CustomName = labelNames["126"]
-			}
-
-			if len(labelNames["127N"]) < 1 {
-				v2.Channel2.CustomName = "127N"
-			} else {
-				v2.Channel2.CustomName = labelNames["127N"]
-			}
-
-			if len(labelNames["127C"]) < 1 {
-				v2.Channel3.CustomName = "127C"
-			} else {
-				v2.Channel3.CustomName = labelNames["127C"]
-			}
-
-			if len(labelNames["128N"]) < 1 {
-				v2.Channel4.CustomName = "128N"
-			} else {
-				v2.Channel4.CustomName = labelNames["128N"]
-			}
-
-			if len(labelNames["128C"]) < 1 {
-				v2.Channel5.CustomName = "128C"
-			} else {
-				v2.Channel5.CustomName = labelNames["128C"]
-			}
-
-			if len(labelNames["129N"]) < 1 {
-				v2.Channel6.CustomName = "129N"
-			} else {
-				v2.Channel6.CustomName = labelNames["129N"]
-			}
-
-			if len(labelNames["129C"]) < 1 {
-				v2.Channel7.CustomName = "129C"
-			} else {
-				v2.Channel7.CustomName = labelNames["129C"]
-			}
-
-			if len(labelNames["130N"]) < 1 {
-				v2.Channel8.CustomName = "130N"
-			} else {
-				v2.Channel8.CustomName = labelNames["130N"]
-			}
-
-			if len(labelNames["130C"]) < 1 {
-				v2.Channel9.CustomName = "130C"
-			} else {
-				v2.Channel9.CustomName = labelNames["130C"]
-			}
+// labelTemplate builds the reporter channels from the plex definitions, or from the user reagent table
+func labelTemplate(brand, plex, reagents string) iso.Labels {
 
-			if len(labelNames["131N"]) < 1 {
-				v2.Channel10.CustomName = "131N"
-			} else {
-				v2.Channel10.CustomName = labelNames["131N"]
-			}
-
-			if len(labelNames["131C"]) < 1 {
-				v2.Channel11.CustomName = "131C"
-			} else {
-				v2.Channel11.CustomName = labelNames["131C"]
-			}
-
-			if len(labelNames["132N"]) < 1 {
-				v2.Channel12.CustomName = "132N"
-			} else {
-				v2.Channel12.CustomName = labelNames["132N"]
-			}
-
-			if len(labelNames["132C"]) < 1 {
-				v2.Channel13.CustomName = "132C"
-			} else {
-				v2.Channel13.CustomName = labelNames["132C"]
-			}
-
-			if len(labelNames["133N"]) < 1 {
-				v2.Channel14.CustomName = "133N"
-			} else {
-				v2.Channel14.CustomName = labelNames["133N"]
-			}
-
-			if len(labelNames["133C"]) < 1 {
-				v2.Channel15.CustomName = "133C"
-			} else {
-				v2.Channel15.CustomName = labelNames["133C"]
-			}
+	if len(reagents) > 0 {
+		return iso.New(iso.ReadReagents(reagents))
+	}
 
-			if len(labelNames["134N"]) < 1 {
-				v2.Channel16.CustomName = "134N"
-			} else {
-				v2.Channel16.CustomName = labelNames["134N"]
-			}
+	if brand == "tmt" {
+		return tmt.New(plex)
+	} else if brand == "itraq" {
+		return trq.New(plex)
+	}
 
-		} else if brand == "itraq" && plex == "4" {
+	return iso.Labels{}
+}
 
-			if len(labelNames["114"]) < 1 {
-				v2.Channel1.CustomName = "114"
-			} else {
-				v2.Channel1.CustomName = labelNames["114"]
-			}
+// HasLabelChannel tells if the name is one of the plex channels, or one of their custom names on the
+// annotation file
+func HasLabelChannel(p met.Quantify, name string) bool {
 
-			if len(labelNames["115"]) < 1 {
-				v2.Channel2.CustomName = "115"
-			} else {
-				v2.Channel2.CustomName = labelNames["115"]
-			}
+	var names = make(map[string]string)
+	if len(p.Annot) > 0 {
+		names = uti.GetLabelNames(p.Annot)
+	}
 
-			if len(labelNames["116"]) < 1 {
-				v2.Channel3.CustomName = "116"
-			} else {
-				v2.Channel3.CustomName = labelNames["116"]
-			}
+	for _, i := range labelTemplate(p.Brand, p.Plex, p.Reagents).Channels {
+		if i.Name == name || names[i.Name] == name {
+			return true
+		}
+	}
 
-			if len(labelNames["117"]) < 1 {
-				v2.Channel4.CustomName = "117"
-			} else {
-				v2.Channel4.CustomName = labelNames["117"]
-			}
+	return false
+}
 
-		} else if brand == "itraq" && plex == "8" {
-			if len(labelNames["113"]) < 1 {
-				v2.Channel1.CustomName = "113"
-			} else {
-				v2.Channel1.CustomName = labelNames["113"]
-			}
+// labelImpurities returns the lot impurities from the impurity table, or the default ones of the plex
+func labelImpurities(brand, plex, impurity string) iso.Impurities {
 
-			if len(labelNames["114"]) < 1 {
-				v2.Channel2.CustomName = "114"
-			} else {
-				v2.Channel2.CustomName = labelNames["114"]
-			}
+	if len(impurity) > 0 {
+		return iso.ReadImpurities(impurity)
+	}
 
-			if len(labelNames["115"]) < 1 {
-				v2.Channel3.CustomName = "115"
-			} else {
-				v2.Channel3.CustomName = labelNames["115"]
-			}
+	var impurities iso.Impurities
+	if brand == "tmt" {
+		impurities = tmt.Impurities(plex)
+	} else if brand == "itraq" {
+		impurities = trq.Impurities(plex)
+	}
 
-			if len(labelNames["116"]) < 1 {
-				v2.Channel4.CustomName = "116"
-			} else {
-				v2.Channel4.CustomName = labelNames["116"]
-			}
+	if impurities == nil {
+		msg.Custom(errors.New("There are no default impurities for this plex, please provide the lot impurity table"), "warning")
+	}
 
-			if len(labelNames["117"]) < 1 {
-				v2.Channel5.CustomName = "117"
-			} else {
-				v2.Channel5.CustomName = labelNames["117"]
-			}
+	return impurities
+}
 
-			if len(labelNames["118"]) < 1 {
-				v2.Channel6.CustomName = "118"
-			} else {
-				v2.Channel6.CustomName = labelNames["118"]
-			}
+// checks for custom names and assign the normal channel or the custom name to the CustomName
+func assignLabelNames(labels map[string]iso.Labels, labelNames map[string]string) map[string]iso.Labels {
 
-			if len(labelNames["119"]) < 1 {
-				v2.Channel7.CustomName = "119"
-			} else {
-				v2.Channel7.CustomName = labelNames["119"]
-			}
+	for k, v := range labels {
 
-			if len(labelNames["121"]) < 1 {
-				v2.Channel8.CustomName = "121"
+		for i := range v.Channels {
+			if len(labelNames[v.Channels[i].Name]) < 1 {
+				v.Channels[i].CustomName = v.Channels[i].Name
 			} else {
-				v2.Channel8.CustomName = labelNames["121"]
+				v.Channels[i].CustomName = labelNames[v.Channels[i].Name]
 			}
-
 		}
 
-		labels[k] = v2
+		labels[k] = v
 	}
 
 	return labels
 }
 
-func classification(evi rep.Evidence, mods, best bool, remove, purity, probability float64) (map[string]iso.Labels, map[string]iso.Labels) {
+func classification(evi rep.Evidence, mods, best bool, remove, purity, probability, spsMatch, minSN float64, excluded []bool) (map[string]iso.Labels, map[string]iso.Labels) {
 
 	var spectrumMap = make(map[string]iso.Labels)
 	var phosphoSpectrumMap = make(map[string]iso.Labels)
@@ -440,11 +439,14 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 
 	var psmLabelSumList PairList
 
-	// 1st check: Purity the score and the Probability levels
+	// 1st check: Purity, the SPS fragment match, the reporter signal-to-noise, the carrier ratio cap, the score and the Probability levels
 	for _, i := range evi.PSM {
-		if i.Probability >= probability && i.Purity >= purity {
+		if i.Probability >= probability && i.Purity >= purity && i.SPSMatch >= spsMatch && i.Labels.SignalToNoise() >= minSN && i.IsAboveCarrierCap == false {
+
+			// the classified labels are kept apart from the PSMs, the unlabelled PSMs are cleaned afterwards
+			labels := i.Labels.Copy()
 
-			spectrumMap[i.Spectrum] = i.Labels
+			spectrumMap[i.Spectrum] = labels
 			bestMap[i.Spectrum] = 0
 
 			if mods == true {
@@ -452,29 +454,15 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 				_, ok2 := i.LocalizedPTMSites["PTMProphet_STY79.96633"]
 				_, ok3 := i.LocalizedPTMSites["PTMProphet_STY79.966331"]
 				if ok1 || ok2 || ok3 {
-					phosphoSpectrumMap[i.Spectrum] = i.Labels
+					phosphoSpectrumMap[i.Spectrum] = labels
 				}
 			}
 
 		}
 
+		// the carrier would dominate the sums, only the sample channels are used
 		if remove != 0 {
-			sum := i.Labels.Channel1.Intensity +
-				i.Labels.Channel2.Intensity +
-				i.Labels.Channel3.Intensity +
-				i.Labels.Channel4.Intensity +
-				i.Labels.Channel5.Intensity +
-				i.Labels.Channel6.Intensity +
-				i.Labels.Channel7.Intensity +
-				i.Labels.Channel8.Intensity +
-				i.Labels.Channel9.Intensity +
-				i.Labels.Channel10.Intensity +
-				i.Labels.Channel11.Intensity +
-				i.Labels.Channel12.Intensity +
-				i.Labels.Channel13.Intensity +
-				i.Labels.Channel14.Intensity +
-				i.Labels.Channel15.Intensity +
-				i.Labels.Channel16.Intensity
+			sum := sampleSum(i.Labels, excluded)
 			psmLabelSumList = append(psmLabelSumList, Pair{i.Spectrum, sum})
 		}
 	}
@@ -498,22 +486,7 @@ func classification(evi rep.Evidence, mods, best bool, remove, purity, probabili
 				var bestPSM string
 				var bestPSMInt float64
 				for _, i := range v {
-					tmtSum := i.Labels.Channel1.Intensity +
-						i.Labels.Channel2.Intensity +
-						i.Labels.Channel3.Intensity +
-						i.Labels.Channel4.Intensity +
-						i.Labels.Channel5.Intensity +
-						i.Labels.Channel6.Intensity +
-						i.Labels.Channel7.Intensity +
-						i.Labels.Channel8.Intensity +
-						i.Labels.Channel9.Intensity +
-						i.Labels.Channel10.Intensity +
-						i.Labels.Channel11.Intensity +
-						i.Labels.Channel12.Intensity +
-						i.Labels.Channel13.Intensity +
-						i.Labels.Channel14.Intensity +
-						i.Labels.Channel15.Intensity +
-						i.Labels.Channel16.Intensity
+					tmtSum := sampleSum(i.Labels, excluded)
 
 					if tmtSum > bestPSMInt {
 						bestPSM = i.Spectrum
@@ -0,0 +1,357 @@
+package qua
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
+	"philosopher/lib/rep"
+	"philosopher/lib/uti"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// nitrogenShift is the mass difference between the 15N and 14N isotopes
+	nitrogenShift = 0.997034893
+
+	// labelTolerance is the mass tolerance to recognize a label among the PSM modifications
+	labelTolerance = 0.01
+)
+
+// nitrogens is the number of nitrogen atoms of each residue
+var nitrogens = map[string]int{"A": 1, "R": 4, "N": 2, "D": 1, "C": 1, "Q": 2, "E": 1, "G": 1, "H": 3, "I": 1, "L": 1, "K": 2, "M": 1, "F": 1, "P": 1, "S": 1, "T": 1, "W": 2, "Y": 1, "V": 1}
+
+// ms1Label is a labelled form, with the mass added to each labelled residue and to the peptide N-terminus (n)
+type ms1Label map[string]float64
+
+// parseMS1Label reads a label definition, a comma separated list of residue+mass items like K+8.0142,R+10.0083,
+// with n for the peptide N-terminus, or 15N for the metabolic labelling of every nitrogen
+func parseMS1Label(definition string) ms1Label {
+
+	var label = make(ms1Label)
+
+	for _, i := range strings.Split(definition, ",") {
+
+		i = strings.TrimSpace(i)
+		if len(i) == 0 {
+			continue
+		}
+
+		if strings.EqualFold(i, "15N") {
+			for k, v := range nitrogens {
+				label[k] += float64(v) * nitrogenShift
+			}
+			continue
+		}
+
+		parts := strings.Split(i, "+")
+		if len(parts) != 2 || len(parts[0]) != 1 {
+			msg.Custom(errors.New("Cannot parse the label "+i+", please use residue+mass, like K+8.0142"), "fatal")
+		}
+
+		mass, e := strconv.ParseFloat(parts[1], 64)
+		if e != nil {
+			msg.Custom(errors.New("Cannot parse the mass of the label "+i), "fatal")
+		}
+
+		// unlabelled residues, like K+0, are left out
+		if mass != 0 {
+			label[parts[0]] += mass
+		}
+	}
+
+	return label
+}
+
+// shift returns the mass the label adds to a peptide
+func (l ms1Label) shift(sequence string) float64 {
+
+	var s = l["n"]
+	for _, i := range sequence {
+		s += l[string(i)]
+	}
+
+	return s
+}
+
+// carries tells if every labelled position of the PSM has a modification with the label mass, a label without
+// labelled positions on the peptide is never carried
+func (l ms1Label) carries(psm rep.PSMEvidence) bool {
+
+	var positions = make(map[int][]float64)
+
+	for _, i := range psm.Modifications.Index {
+
+		if i.Type == "Observed" {
+			continue
+		}
+
+		if i.AminoAcid == "N-term" {
+			positions[0] = append(positions[0], i.MassDiff)
+		} else if p, e := strconv.Atoi(i.Position); e == nil && p > 0 {
+			positions[p] = append(positions[p], i.MassDiff)
+		}
+	}
+
+	var sites = make(map[int]float64)
+	if v, ok := l["n"]; ok && v != 0 {
+		sites[0] = v
+	}
+	for i := range psm.Peptide {
+		if v, ok := l[string(psm.Peptide[i])]; ok && v != 0 {
+			sites[i+1] = v
+		}
+	}
+
+	if len(sites) == 0 {
+		return false
+	}
+
+	for p, v := range sites {
+		var found bool
+		for _, j := range positions[p] {
+			if math.Abs(j-v) <= labelTolerance {
+				found = true
+				break
+			}
+		}
+		if found == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+// labelForm returns the form of the PSM, 0 for light, 1 for medium and 2 for heavy, or -1 when the PSM does not
+// carry any of the defined labels. PSMs without labels are light when the light form has no definition
+func labelForm(psm rep.PSMEvidence, labels []ms1Label) int {
+
+	for k := len(labels) - 1; k >= 0; k-- {
+		if len(labels[k]) > 0 && labels[k].carries(psm) {
+			return k
+		}
+	}
+
+	if len(labels[0]) == 0 {
+		return 0
+	}
+
+	return -1
+}
+
+// quantifyMS1Labels finds the partner isotope clusters of every PSM on the MS1 scans and calculates the
+// medium/light and heavy/light ratios of the PSMs, ions, peptides and proteins
+func quantifyMS1Labels(evi rep.Evidence, p met.Quantify) rep.Evidence {
+
+	logrus.Info("Quantifying the MS1 labelled forms")
+
+	var labels = []ms1Label{parseMS1Label(p.Light), parseMS1Label(p.Medium), parseMS1Label(p.Heavy)}
+
+	var spectra = make(map[string][]int)
+	for i := range evi.PSM {
+		partName := strings.Split(evi.PSM[i].Spectrum, ".")
+		spectra[partName[0]] = append(spectra[partName[0]], i)
+	}
+
+	var sourceList []string
+	for i := range spectra {
+		sourceList = append(sourceList, i)
+	}
+	sort.Strings(sourceList)
+
+	var files = make([]string, len(sourceList))
+	for i := range sourceList {
+		files[i] = sourceFile(p.Dir, sourceList[i], p.Format)
+	}
+
+	var runLabels = make([]map[string]rep.MS1Labels, len(sourceList))
+
+	runPool(files, p.Threads, func(r int) {
+
+		logrus.Info("Processing ", sourceList[r])
+
+		ms1 := ms1Spectra(files[r])
+
+		var measured = make(map[string]rep.MS1Labels)
+
+		for _, j := range spectra[sourceList[r]] {
+
+			psm := evi.PSM[j]
+
+			form := labelForm(psm, labels)
+			if form < 0 || psm.AssumedCharge < 1 {
+				continue
+			}
+
+			rt := psm.RetentionTime / 60
+
+			var intensities = make([]float64, len(labels))
+			for k, mz := range formMZ(psm, labels, form) {
+				if mz > 0 {
+					intensities[k] = apexIntensity(ms1, rt, p.RTWin, p.PTWin, p.Tol/math.Pow(10, 6), mz)
+				}
+			}
+
+			measured[psm.Spectrum] = labelRatios(rep.MS1Labels{Light: intensities[0], Medium: intensities[1], Heavy: intensities[2]})
+		}
+
+		runLabels[r] = measured
+	})
+
+	for i := range evi.PSM {
+		for _, j := range runLabels {
+			if v, ok := j[evi.PSM[i].Spectrum]; ok {
+				evi.PSM[i].MS1Labels = v
+				break
+			}
+		}
+	}
+
+	return rollUpMS1Labels(evi)
+}
+
+// formMZ returns the precursor m/z of each labelled form of the PSM. The forms that are not defined, or that
+// have the same shift as the identified form, like on peptides without labelled residues, cannot be told apart
+// on the MS1 and are left at zero
+func formMZ(psm rep.PSMEvidence, labels []ms1Label, form int) []float64 {
+
+	var mz = make([]float64, len(labels))
+
+	identified := labels[form].shift(psm.Peptide)
+	base := psm.CalcNeutralPepMass - identified
+	charge := float64(psm.AssumedCharge)
+
+	for k := range labels {
+		if k > 0 && len(labels[k]) == 0 {
+			continue
+		}
+
+		shift := labels[k].shift(psm.Peptide)
+		if k != form && shift == identified {
+			continue
+		}
+
+		mz[k] = (base + shift + charge*bio.Proton) / charge
+	}
+
+	return mz
+}
+
+// apexIntensity returns the most intense point of the ion chromatogram inside the peak time window
+func apexIntensity(ms1 mzn.Spectra, rt, rTWin, pTWin, ppmPrecision, mz float64) float64 {
+
+	var topI float64
+
+	measured, retrieved := xic(ms1, rt-rTWin, rt+rTWin, ppmPrecision, mz)
+	if retrieved == false {
+		return 0
+	}
+
+	for k, v := range measured {
+		if k > (rt-pTWin) && k < (rt+pTWin) && v > topI {
+			topI = v
+		}
+	}
+
+	return topI
+}
+
+// labelRatios calculates the medium/light and heavy/light ratios, the ratios without both forms are zero
+func labelRatios(l rep.MS1Labels) rep.MS1Labels {
+
+	l.MediumRatio = 0
+	l.HeavyRatio = 0
+
+	if l.Light > 0 && l.Medium > 0 {
+		l.MediumRatio = l.Medium / l.Light
+	}
+
+	if l.Light > 0 && l.Heavy > 0 {
+		l.HeavyRatio = l.Heavy / l.Light
+	}
+
+	return l
+}
+
+// rollUpMS1Labels assigns to each ion and peptide the labelled forms of its most intense PSM, the light and
+// heavy PSMs of an ion measure the same pair. Proteins sum the forms of their unique and razor peptides, and
+// take the median of the peptide ratios
+func rollUpMS1Labels(evi rep.Evidence) rep.Evidence {
+
+	var ionMap = make(map[string]rep.MS1Labels)
+	var peptideMap = make(map[string]rep.MS1Labels)
+
+	for _, i := range evi.PSM {
+
+		sum := i.MS1Labels.Light + i.MS1Labels.Medium + i.MS1Labels.Heavy
+		if sum == 0 {
+			continue
+		}
+
+		if v, ok := ionMap[i.IonForm]; !ok || sum > v.Light+v.Medium+v.Heavy {
+			ionMap[i.IonForm] = i.MS1Labels
+		}
+
+		if v, ok := peptideMap[i.Peptide]; !ok || sum > v.Light+v.Medium+v.Heavy {
+			peptideMap[i.Peptide] = i.MS1Labels
+		}
+	}
+
+	for i := range evi.Ions {
+		evi.Ions[i].MS1Labels = ionMap[evi.Ions[i].IonForm]
+	}
+
+	for i := range evi.Peptides {
+		evi.Peptides[i].MS1Labels = peptideMap[evi.Peptides[i].Sequence]
+	}
+
+	for i := range evi.Proteins {
+
+		var l rep.MS1Labels
+		var medium, heavy []float64
+		var used = make(map[string]bool)
+
+		for _, k := range evi.Proteins[i].TotalPeptideIons {
+
+			if (k.IsUnique == false && k.IsURazor == false) || used[k.Sequence] {
+				continue
+			}
+			used[k.Sequence] = true
+
+			v, ok := peptideMap[k.Sequence]
+			if !ok {
+				continue
+			}
+
+			l.Light += v.Light
+			l.Medium += v.Medium
+			l.Heavy += v.Heavy
+
+			if v.MediumRatio > 0 {
+				medium = append(medium, v.MediumRatio)
+			}
+			if v.HeavyRatio > 0 {
+				heavy = append(heavy, v.HeavyRatio)
+			}
+		}
+
+		if len(medium) > 0 {
+			l.MediumRatio = uti.Median(medium)
+		}
+		if len(heavy) > 0 {
+			l.HeavyRatio = uti.Median(heavy)
+		}
+
+		evi.Proteins[i].MS1Labels = l
+	}
+
+	return evi
+}
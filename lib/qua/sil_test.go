@@ -0,0 +1,85 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/rep"
+)
+
+func Test_parseMS1Label(t *testing.T) {
+
+	silac := parseMS1Label("K+8.0142, R+10.0083")
+	if math.Abs(silac.shift("PEPTIDEKAR")-18.0225) > 1e-6 {
+		t.Errorf("SILAC shift is %f, want 18.0225", silac.shift("PEPTIDEKAR"))
+	}
+
+	dimethyl := parseMS1Label("n+28.0313,K+28.0313")
+	if math.Abs(dimethyl.shift("PEPTIDEK")-56.0626) > 1e-6 {
+		t.Errorf("dimethyl shift is %f, want 56.0626", dimethyl.shift("PEPTIDEK"))
+	}
+
+	nitrogen := parseMS1Label("15N")
+	if math.Abs(nitrogen.shift("GK")-3*nitrogenShift) > 1e-6 {
+		t.Errorf("15N shift is %f, want %f", nitrogen.shift("GK"), 3*nitrogenShift)
+	}
+
+	if len(parseMS1Label("K+0,R+0")) != 0 {
+		t.Errorf("unlabelled residues should not be part of the label")
+	}
+}
+
+func Test_labelForm(t *testing.T) {
+
+	labels := []ms1Label{parseMS1Label(""), parseMS1Label("K+4.0251,R+6.0201"), parseMS1Label("K+8.0142,R+10.0083")}
+
+	var heavy rep.PSMEvidence
+	heavy.Peptide = "PEPTIDEK"
+	heavy.Modifications.Index = map[string]mod.Modification{"K8": {Position: "8", AminoAcid: "K", MassDiff: 8.0142, Type: "Assigned"}}
+
+	var medium rep.PSMEvidence
+	medium.Peptide = "PEPTIDEK"
+	medium.Modifications.Index = map[string]mod.Modification{"K8": {Position: "8", AminoAcid: "K", MassDiff: 4.0251, Type: "Assigned"}}
+
+	var light rep.PSMEvidence
+	light.Peptide = "PEPTIDEK"
+
+	if f := labelForm(heavy, labels); f != 2 {
+		t.Errorf("heavy PSM form is %d, want 2", f)
+	}
+
+	if f := labelForm(medium, labels); f != 1 {
+		t.Errorf("medium PSM form is %d, want 1", f)
+	}
+
+	if f := labelForm(light, labels); f != 0 {
+		t.Errorf("light PSM form is %d, want 0", f)
+	}
+
+	labels[0] = parseMS1Label("K+28.0313")
+	if f := labelForm(light, labels); f != -1 {
+		t.Errorf("PSM without the light label has form %d, want -1", f)
+	}
+}
+
+func Test_formMZ(t *testing.T) {
+
+	labels := []ms1Label{parseMS1Label(""), parseMS1Label(""), parseMS1Label("K+8.0142,R+10.0083")}
+
+	var psm rep.PSMEvidence
+	psm.Peptide = "PEPTIDEK"
+	psm.AssumedCharge = 2
+	psm.CalcNeutralPepMass = 1000
+
+	mz := formMZ(psm, labels, 0)
+	if mz[1] != 0 || math.Abs(mz[2]-mz[0]-4.0071) > 1e-6 {
+		t.Errorf("form m/z are %v, want the heavy form 4.0071 over the light one and no medium", mz)
+	}
+
+	// a C-terminal peptide without labelled residues has no heavy form to measure
+	psm.Peptide = "PEPTIDE"
+	if mz := formMZ(psm, labels, 0); mz[0] == 0 || mz[2] != 0 {
+		t.Errorf("form m/z without labelled residues are %v, want only the light form", mz)
+	}
+}
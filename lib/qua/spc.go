@@ -4,8 +4,8 @@ import (
 	"philosopher/lib/rep"
 )
 
-// CalculateSpectralCounts add Spc to ions and proteins
-func CalculateSpectralCounts(e rep.Evidence) rep.Evidence {
+// CalculateSpectralCounts add Spc to ions and proteins, and the NSAF and emPAI protein abundances
+func CalculateSpectralCounts(e rep.Evidence, enzyme string) rep.Evidence {
 
 	// if len(e.PSM) < 1 && len(e.Ions) < 1 {
 	// 	fmt.Println("spc")
@@ -42,5 +42,7 @@ func CalculateSpectralCounts(e rep.Evidence) rep.Evidence {
 
 	}
 
+	e = calculateSpectralAbundances(e, enzyme)
+
 	return e
 }
@@ -0,0 +1,82 @@
+package qua
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/rep"
+)
+
+// spsTolerance is the m/z tolerance for the SPS ions, selected from ion trap MS2 scans
+const spsTolerance = 0.5
+
+// matchSPSIons calculates for each PSM the fraction of the SPS ions of its MS3 scan explained by the peptide,
+// and returns how many PSMs had SPS ions to match
+func matchSPSIons(evi []rep.PSMEvidence, sps map[string][]float64) ([]rep.PSMEvidence, int) {
+
+	var matched int
+
+	for i := range evi {
+
+		split := strings.Split(evi[i].Spectrum, ".")
+
+		ions, ok := sps[split[2]]
+		if !ok || len(ions) == 0 {
+			continue
+		}
+
+		evi[i].SPSMatch = spsMatch(evi[i], ions)
+		matched++
+	}
+
+	return evi, matched
+}
+
+// spsMatch returns the fraction of the SPS ions matching a b or y fragment of the peptide, with the fragment
+// charges up to the precursor charge minus one
+func spsMatch(psm rep.PSMEvidence, ions []float64) float64 {
+
+	var shifts = make([]float64, len(psm.Peptide))
+
+	for _, i := range psm.Modifications.Index {
+
+		if i.Type == "Observed" || len(shifts) == 0 {
+			continue
+		}
+
+		if i.AminoAcid == "N-term" {
+			shifts[0] += i.MassDiff
+		} else if i.AminoAcid == "C-term" {
+			shifts[len(shifts)-1] += i.MassDiff
+		} else if p, e := strconv.Atoi(i.Position); e == nil && p > 0 && p <= len(shifts) {
+			shifts[p-1] += i.MassDiff
+		}
+	}
+
+	var fragments []float64
+
+	maxCharge := int(psm.AssumedCharge) - 1
+	if maxCharge < 1 {
+		maxCharge = 1
+	}
+
+	for z := 1; z <= maxCharge; z++ {
+		b, y := bio.FragmentIons(psm.Peptide, shifts, z)
+		fragments = append(fragments, b...)
+		fragments = append(fragments, y...)
+	}
+
+	var matched float64
+	for _, i := range ions {
+		for _, j := range fragments {
+			if math.Abs(i-j) <= spsTolerance {
+				matched++
+				break
+			}
+		}
+	}
+
+	return matched / float64(len(ions))
+}
@@ -0,0 +1,26 @@
+package qua
+
+import (
+	"math"
+	"testing"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/rep"
+)
+
+func Test_spsMatch(t *testing.T) {
+
+	var psm rep.PSMEvidence
+	psm.Peptide = "GAVLK"
+	psm.AssumedCharge = 2
+	psm.Modifications.Index = map[string]mod.Modification{
+		"K#5#357.2579": {AminoAcid: "K", Position: "5", MassDiff: 229.162932},
+	}
+
+	// y1 with the TMT label, b2 and an unrelated ion
+	ions := []float64{376.2757, 129.0659, 600.0}
+
+	if got := spsMatch(psm, ions); math.Abs(got-2.0/3) > 1e-9 {
+		t.Errorf("spsMatch() = %f, want 0.667", got)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"philosopher/lib/iso"
 	"philosopher/lib/msg"
 
 	"philosopher/lib/bio"
@@ -49,6 +50,16 @@ func (evi *Evidence) AssembleIonReport(ion id.PepIDList, decoyTag string) {
 
 	}
 
+	// the ion list holds the ion-level error rates, the best ones are kept for each ion
+	var errorRates = make(map[string][2]float64)
+	for _, i := range ion {
+		ionForm := fmt.Sprintf("%s#%d#%.4f", i.Peptide, i.AssumedCharge, i.CalcNeutralPepMass)
+		v, ok := errorRates[ionForm]
+		if !ok || i.QValue < v[0] || (i.QValue == v[0] && i.PEP < v[1]) {
+			errorRates[ionForm] = [2]float64{i.QValue, i.PEP}
+		}
+	}
+
 	for _, i := range ion {
 		var pr IonEvidence
 
@@ -78,6 +89,8 @@ func (evi *Evidence) AssembleIonReport(ion id.PepIDList, decoyTag string) {
 		pr.MappedProteins[i.Protein] = 0
 		pr.Modifications = i.Modifications
 		pr.Probability = bestProb[pr.IonForm]
+		pr.QValue = errorRates[pr.IonForm][0]
+		pr.PEP = errorRates[pr.IonForm][1]
 
 		// get the mapped proteins
 		for _, j := range psmPtMap[pr.IonForm] {
@@ -109,7 +122,7 @@ func (evi *Evidence) AssembleIonReport(ion id.PepIDList, decoyTag string) {
 }
 
 // MetaIonReport reports consist on ion reporting
-func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabels bool) {
+func (evi Evidence) MetaIonReport(labels iso.Labels, opt ReportOptions) {
 
 	var header string
 	output := fmt.Sprintf("%s%sion.tsv", sys.MetaDir(), string(filepath.Separator))
@@ -125,7 +138,7 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabe
 	for _, i := range evi.Ions {
 		// This inclusion is necessary to avoid unexistent observations from being included after using the filter --mods options
 		if i.Probability > 0 {
-			if hasDecoys == false {
+			if opt.Decoys == false {
 				if i.IsDecoy == false {
 					printSet = append(printSet, i)
 				}
@@ -137,77 +150,33 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabe
 
 	header = "Peptide Sequence\tModified Sequence\tPeptide Length\tM/Z\tCharge\tObserved Mass\tProbability\tExpectation\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
 
-	if brand == "tmt" {
-		switch channels {
-		case 6:
-			header += "\tChannel 126\tChannel 127N\tChannel 128C\tChannel 129N\tChannel 130C\tChannel 131"
-		case 10:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N"
-		case 11:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C"
-		case 16:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C\tChannel 132N\tChannel 132C\tChannel 133N\tChannel 133C\tChannel 134N"
-		default:
-			header += ""
-		}
-	} else if brand == "itraq" {
-		switch channels {
-		case 4:
-			header += "\tChannel 114\tChannel 115\tChannel 116\tChannel 117"
-		case 8:
-			header += "\tChannel 113\tChannel 114\tChannel 115\tChannel 116\tChannel 117\tChannel 118\tChannel 119\tChannel 121"
-		default:
-			header += ""
-		}
+	if opt.ErrorRates == true {
+		header += "\tq-value\tPEP"
 	}
 
-	header += "\n"
+	if opt.Alignment == true {
+		header += "\tAligned Retention"
+	}
 
-	// verify if the structure has labels, if so, replace the original channel names by them.
-	if hasLabels == true {
-
-		var c1, c2, c3, c4, c5, c6, c7, c8, c9, c10, c11, c12, c13, c14, c15, c16 string
-
-		for _, i := range printSet {
-			if len(i.Labels.Channel1.CustomName) >= 1 {
-				c1 = i.Labels.Channel1.CustomName
-				c2 = i.Labels.Channel2.CustomName
-				c3 = i.Labels.Channel3.CustomName
-				c4 = i.Labels.Channel4.CustomName
-				c5 = i.Labels.Channel5.CustomName
-				c6 = i.Labels.Channel6.CustomName
-				c7 = i.Labels.Channel7.CustomName
-				c8 = i.Labels.Channel8.CustomName
-				c9 = i.Labels.Channel9.CustomName
-				c10 = i.Labels.Channel10.CustomName
-				c11 = i.Labels.Channel11.CustomName
-				c12 = i.Labels.Channel12.CustomName
-				c13 = i.Labels.Channel13.CustomName
-				c14 = i.Labels.Channel14.CustomName
-				c15 = i.Labels.Channel15.CustomName
-				c16 = i.Labels.Channel16.CustomName
-				break
-			}
-		}
+	if opt.Features == true {
+		header += "\tApex Intensity\tApex Retention\tPeak Area\tPeak FWHM\tIsotope Correlation"
+	}
+
+	if opt.Transfers == true {
+		header += "\tIs Transferred\tTransfer q-value"
+	}
+
+	if opt.MS1Labels == true {
+		header += ms1LabelHeader
+	}
 
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel1.Name, c1, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel2.Name, c2, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel3.Name, c3, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel4.Name, c4, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel5.Name, c5, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel6.Name, c6, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel7.Name, c7, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel8.Name, c8, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel9.Name, c9, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel10.Name, c10, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel11.Name, c11, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel12.Name, c12, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel13.Name, c13, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel14.Name, c14, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel15.Name, c15, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel16.Name, c16, -1)
+	header += labelHeader(labels, opt.Labels)
+	if opt.Ratios == true {
+		header += ratioHeader(labels, opt.Labels)
 	}
 
+	header += "\n"
+
 	_, e = io.WriteString(file, header)
 	if e != nil {
 		msg.WriteToFile(errors.New("Cannot print Ion to file"), "fatal")
@@ -258,88 +227,47 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabe
 			strings.Join(mappedProteins, ","),
 		)
 
-		switch channels {
-		case 4:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-			)
-		case 6:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-			)
-		case 8:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		if opt.ErrorRates == true {
+			line = fmt.Sprintf("%s\t%.6f\t%.6f",
 				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
+				i.QValue,
+				i.PEP,
 			)
-		case 10:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		}
+
+		if opt.Alignment == true {
+			line = fmt.Sprintf("%s\t%.4f",
 				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
+				i.AlignedRetentionTime,
 			)
-		case 11:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		}
+
+		if opt.Features == true {
+			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
 				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-				i.Labels.Channel11.Intensity,
+				i.Feature.Apex,
+				i.Feature.ApexRetentionTime,
+				i.Feature.Area,
+				i.Feature.FWHM,
+				i.Feature.IsotopeCorrelation,
 			)
-		case 16:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		}
+
+		if opt.Transfers == true {
+			line = fmt.Sprintf("%s\t%t\t%.4f",
 				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-				i.Labels.Channel11.Intensity,
-				i.Labels.Channel12.Intensity,
-				i.Labels.Channel13.Intensity,
-				i.Labels.Channel14.Intensity,
-				i.Labels.Channel15.Intensity,
-				i.Labels.Channel16.Intensity,
+				i.IsTransferred,
+				i.TransferQValue,
 			)
-		default:
-			header += ""
+		}
+
+		if opt.MS1Labels == true {
+			line += ms1LabelColumns(i.MS1Labels)
+		}
+
+		line += labelColumns(i.Labels, len(labels.Channels))
+		if opt.Ratios == true {
+			line += ratioColumns(i.Labels, len(labels.Channels))
 		}
 
 		line += "\n"
@@ -8,9 +8,25 @@ import (
 	"time"
 
 	"philosopher/lib/dat"
+	"philosopher/lib/iso"
 	"philosopher/lib/psi"
 )
 
+// tmtAccessions are the PSI-MS terms of the TMT reagents
+var tmtAccessions = map[string]string{
+	"126":  "MS:1002616",
+	"127N": "MS:1002763",
+	"127C": "MS:1002764",
+	"128N": "MS:1002765",
+	"128C": "MS:1002766",
+	"129N": "MS:1002767",
+	"129C": "MS:1002768",
+	"130N": "MS:1002769",
+	"130C": "MS:1002770",
+	"131":  "MS:1002621",
+	"131N": "MS:1002621",
+}
+
 // MzIdentMLReport creates a MzIdentML structure to be encoded
 func (e Evidence) MzIdentMLReport(version, database string) {
 
@@ -905,117 +921,21 @@ func (e Evidence) MzIdentMLReport(version, database string) {
 									Name:      "razor peptide",
 									Value:     fmt.Sprintf("%v", j.IsURazor),
 								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002616",
-									Name:      "TMT reagent 126",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel1.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002763",
-									Name:      "TMT reagent 127N",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel2.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002764",
-									Name:      "TMT reagent 127C",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel3.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002765",
-									Name:      "TMT reagent 128N",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel4.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002766",
-									Name:      "TMT reagent 128C",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel5.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002767",
-									Name:      "TMT reagent 129N",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel6.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002768",
-									Name:      "TMT reagent 129C",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel7.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002769",
-									Name:      "TMT reagent 130N",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel8.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002770",
-									Name:      "TMT reagent 130C",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel9.Intensity),
-								},
-								{
-									CVRef:     "PSI-MS",
-									Accession: "MS:1002621",
-									Name:      "TMT reagent 131",
-									Value:     fmt.Sprintf("%f", j.Labels.Channel10.Intensity),
-								},
 							},
 							UserParam: []psi.UserParam{
 								{
 									Name:  "entry name",
 									Value: j.EntryName,
 								},
-								{
-									Name:  "TMT reagent 126 Label",
-									Value: j.Labels.Channel1.Name,
-								},
-								{
-									Name:  "TMT reagent 17N Label",
-									Value: j.Labels.Channel2.Name,
-								},
-								{
-									Name:  "TMT reagent 127C Label",
-									Value: j.Labels.Channel3.Name,
-								},
-								{
-									Name:  "TMT reagent 128N Label",
-									Value: j.Labels.Channel4.Name,
-								},
-								{
-									Name:  "TMT reagent 128C Label",
-									Value: j.Labels.Channel5.Name,
-								},
-								{
-									Name:  "TMT reagent 129N Label",
-									Value: j.Labels.Channel6.Name,
-								},
-								{
-									Name:  "TMT reagent 129C Label",
-									Value: j.Labels.Channel7.Name,
-								},
-								{
-									Name:  "TMT reagent 130N Label",
-									Value: j.Labels.Channel8.Name,
-								},
-								{
-									Name:  "TMT reagent 130C Label",
-									Value: j.Labels.Channel9.Name,
-								},
-								{
-									Name:  "TMT reagent 131 Label",
-									Value: j.Labels.Channel10.Name,
-								},
 							},
 						},
 					},
 				}
 
+				labelCV, labelUser := labelParams(j.Labels)
+				sir.SpectrumIdentificationItem[0].CVParam = append(sir.SpectrumIdentificationItem[0].CVParam, labelCV...)
+				sir.SpectrumIdentificationItem[0].UserParam = append(sir.SpectrumIdentificationItem[0].UserParam, labelUser...)
+
 				specRef[j.Spectrum] = fmt.Sprintf("Spectrum_%d", idCounter)
 				ad.SpectrumIdentificationList[0].SpectrumIdentificationResult = append(ad.SpectrumIdentificationList[0].SpectrumIdentificationResult, *sir)
 			}
@@ -1112,3 +1032,38 @@ func (e Evidence) MzIdentMLReport(version, database string) {
 
 	return
 }
+
+// labelParams reports the channel intensities of the PSM, the reagents with a PSI-MS term are reported as
+// cvParams and the other ones as userParams, together with the channel labels
+func labelParams(labels iso.Labels) ([]psi.CVParam, []psi.UserParam) {
+
+	var cv []psi.CVParam
+	var user []psi.UserParam
+
+	for _, i := range labels.Channels {
+
+		if accession, ok := tmtAccessions[i.Name]; ok {
+			cv = append(cv, psi.CVParam{
+				CVRef:     "PSI-MS",
+				Accession: accession,
+				Name:      fmt.Sprintf("TMT reagent %s", i.Name),
+				Value:     fmt.Sprintf("%f", i.Intensity),
+			})
+			user = append(user, psi.UserParam{
+				Name:  fmt.Sprintf("TMT reagent %s Label", i.Name),
+				Value: i.Name,
+			})
+		} else {
+			user = append(user, psi.UserParam{
+				Name:  fmt.Sprintf("reagent %s", i.Name),
+				Value: fmt.Sprintf("%f", i.Intensity),
+			})
+			user = append(user, psi.UserParam{
+				Name:  fmt.Sprintf("reagent %s Label", i.Name),
+				Value: i.Name,
+			})
+		}
+	}
+
+	return cv, user
+}
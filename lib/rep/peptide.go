@@ -12,6 +12,7 @@ import (
 
 	"philosopher/lib/cla"
 	"philosopher/lib/id"
+	"philosopher/lib/iso"
 	"philosopher/lib/mod"
 	"philosopher/lib/msg"
 	"philosopher/lib/sys"
@@ -31,6 +32,15 @@ func (evi *Evidence) AssemblePeptideReport(pep id.PepIDList, decoyTag string) {
 	var bestProb = make(map[string]float64)
 	var pepMods = make(map[string][]mod.Modification)
 
+	// the peptide list holds the peptide-level error rates, the best ones are kept for each sequence
+	var errorRates = make(map[string][2]float64)
+	for _, i := range pep {
+		v, ok := errorRates[i.Peptide]
+		if !ok || i.QValue < v[0] || (i.QValue == v[0] && i.PEP < v[1]) {
+			errorRates[i.Peptide] = [2]float64{i.QValue, i.PEP}
+		}
+	}
+
 	for _, i := range pep {
 		if !cla.IsDecoyPSM(i, decoyTag) {
 			pepSeqMap[i.Peptide] = false
@@ -84,6 +94,8 @@ func (evi *Evidence) AssemblePeptideReport(pep id.PepIDList, decoyTag string) {
 		pep.Sequence = k
 
 		pep.Probability = bestProb[k]
+		pep.QValue = errorRates[k][0]
+		pep.PEP = errorRates[k][1]
 
 		for _, i := range spectra[k] {
 			pep.Spectra[i] = 0
@@ -132,7 +144,7 @@ func (evi *Evidence) AssemblePeptideReport(pep id.PepIDList, decoyTag string) {
 }
 
 // MetaPeptideReport report consist on ion reporting
-func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys, hasLabels bool) {
+func (evi Evidence) MetaPeptideReport(labels iso.Labels, opt ReportOptions) {
 
 	var header string
 	output := fmt.Sprintf("%s%speptide.tsv", sys.MetaDir(), string(filepath.Separator))
@@ -148,7 +160,7 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys, has
 	for _, i := range evi.Peptides {
 		// This inclusion is necessary to avoid unexistent observations from being included after using the filter --mods options
 		if i.Probability > 0 {
-			if hasDecoys == false {
+			if opt.Decoys == false {
 				if i.IsDecoy == false {
 					printSet = append(printSet, i)
 				}
@@ -160,77 +172,25 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys, has
 
 	header = "Peptide\tPeptide Length\tCharges\tProbability\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
 
-	if brand == "tmt" {
-		switch channels {
-		case 6:
-			header += "\tChannel 126\tChannel 127N\tChannel 128C\tChannel 129N\tChannel 130C\tChannel 131"
-		case 10:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N"
-		case 11:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C"
-		case 16:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C\tChannel 132N\tChannel 132C\tChannel 133N\tChannel 133C\tChannel 134N"
-		default:
-			header += ""
-		}
-	} else if brand == "itraq" {
-		switch channels {
-		case 4:
-			header += "\tChannel 114\tChannel 115\tChannel 116\tChannel 117"
-		case 8:
-			header += "\tChannel 113\tChannel 114\tChannel 115\tChannel 116\tChannel 117\tChannel 118\tChannel 119\tChannel 121"
-		default:
-			header += ""
-		}
+	if opt.ErrorRates == true {
+		header += "\tq-value\tPEP"
 	}
 
-	header += "\n"
+	if opt.Transfers == true {
+		header += "\tIs Transferred\tTransfer q-value"
+	}
 
-	// verify if the structure has labels, if so, replace the original channel names by them.
-	if hasLabels == true {
-
-		var c1, c2, c3, c4, c5, c6, c7, c8, c9, c10, c11, c12, c13, c14, c15, c16 string
-
-		for _, i := range printSet {
-			if len(i.Labels.Channel1.CustomName) >= 1 {
-				c1 = i.Labels.Channel1.CustomName
-				c2 = i.Labels.Channel2.CustomName
-				c3 = i.Labels.Channel3.CustomName
-				c4 = i.Labels.Channel4.CustomName
-				c5 = i.Labels.Channel5.CustomName
-				c6 = i.Labels.Channel6.CustomName
-				c7 = i.Labels.Channel7.CustomName
-				c8 = i.Labels.Channel8.CustomName
-				c9 = i.Labels.Channel9.CustomName
-				c10 = i.Labels.Channel10.CustomName
-				c11 = i.Labels.Channel11.CustomName
-				c12 = i.Labels.Channel12.CustomName
-				c13 = i.Labels.Channel13.CustomName
-				c14 = i.Labels.Channel14.CustomName
-				c15 = i.Labels.Channel15.CustomName
-				c16 = i.Labels.Channel16.CustomName
-				break
-			}
-		}
+	if opt.MS1Labels == true {
+		header += ms1LabelHeader
+	}
 
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel1.Name, c1, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel2.Name, c2, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel3.Name, c3, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel4.Name, c4, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel5.Name, c5, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel6.Name, c6, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel7.Name, c7, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel8.Name, c8, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel9.Name, c9, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel10.Name, c10, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel11.Name, c11, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel12.Name, c12, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel13.Name, c13, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel14.Name, c14, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel15.Name, c15, -1)
-		header = strings.Replace(header, "Channel "+printSet[0].Labels.Channel16.Name, c16, -1)
+	header += labelHeader(labels, opt.Labels)
+	if opt.Ratios == true {
+		header += ratioHeader(labels, opt.Labels)
 	}
 
+	header += "\n"
+
 	_, e = io.WriteString(file, header)
 	if e != nil {
 		msg.WriteToFile(errors.New("Cannot print PSM to file"), "fatal")
@@ -283,88 +243,29 @@ func (evi Evidence) MetaPeptideReport(brand string, channels int, hasDecoys, has
 			strings.Join(mappedProteins, ", "),
 		)
 
-		switch channels {
-		case 4:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f",
+		if opt.ErrorRates == true {
+			line = fmt.Sprintf("%s\t%.6f\t%.6f",
 				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
+				i.QValue,
+				i.PEP,
 			)
-		case 6:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-			)
-		case 8:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-			)
-		case 10:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-			)
-		case 11:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-				i.Labels.Channel11.Intensity,
-			)
-		case 16:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		}
+
+		if opt.Transfers == true {
+			line = fmt.Sprintf("%s\t%t\t%.4f",
 				line,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-				i.Labels.Channel11.Intensity,
-				i.Labels.Channel12.Intensity,
-				i.Labels.Channel13.Intensity,
-				i.Labels.Channel14.Intensity,
-				i.Labels.Channel15.Intensity,
-				i.Labels.Channel16.Intensity,
+				i.IsTransferred,
+				i.TransferQValue,
 			)
-		default:
-			header += ""
+		}
+
+		if opt.MS1Labels == true {
+			line += ms1LabelColumns(i.MS1Labels)
+		}
+
+		line += labelColumns(i.Labels, len(labels.Channels))
+		if opt.Ratios == true {
+			line += ratioColumns(i.Labels, len(labels.Channels))
 		}
 
 		line += "\n"
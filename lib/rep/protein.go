@@ -12,6 +12,7 @@ import (
 
 	"philosopher/lib/dat"
 	"philosopher/lib/id"
+	"philosopher/lib/iso"
 	"philosopher/lib/mod"
 	"philosopher/lib/msg"
 	"philosopher/lib/sys"
@@ -51,6 +52,8 @@ func (evi *Evidence) AssembleProteinReport(pro id.ProtIDList, weight float64, de
 		rep.UniqueStrippedPeptides = len(i.UniqueStrippedPeptides)
 		rep.Probability = i.Probability
 		rep.TopPepProb = i.TopPepProb
+		rep.QValue = i.QValue
+		rep.PEP = i.PEP
 
 		if strings.HasPrefix(i.ProteinName, decoyTag) {
 			rep.IsDecoy = true
@@ -232,7 +235,7 @@ func (evi *Evidence) AssembleProteinReport(pro id.ProtIDList, weight float64, de
 }
 
 // MetaProteinReport creates the TSV Protein report
-func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, hasRazor, uniqueOnly, hasLabels bool) {
+func (evi Evidence) MetaProteinReport(labels iso.Labels, opt ReportOptions) {
 
 	var header string
 	output := fmt.Sprintf("%s%sprotein.tsv", sys.MetaDir(), string(filepath.Separator))
@@ -247,7 +250,7 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 	// building the printing set tat may or not contain decoys
 	var printSet ProteinEvidenceList
 	for _, i := range evi.Proteins {
-		if hasDecoys == false {
+		if opt.Decoys == false {
 			if i.IsDecoy == false {
 				printSet = append(printSet, i)
 			}
@@ -258,82 +261,42 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 
 	header = fmt.Sprintf("Group\tSubGroup\tProtein\tProtein ID\tEntry Name\tGene\tLength\tPercent Coverage\tOrganism\tProtein Description\tProtein Existence\tProtein Probability\tTop Peptide Probability\tStripped Peptides\tTotal Peptide Ions\tUnique Peptide Ions\tRazor Peptide Ions\tTotal Spectral Count\tUnique Spectral Count\tRazor Spectral Count\tTotal Intensity\tUnique Intensity\tRazor Intensity\tRazor Assigned Modifications\tRazor Observed Modifications\tIndistinguishable Proteins")
 
-	if brand == "tmt" {
-		switch channels {
-		case 6:
-			header += "\tChannel 126\tChannel 127N\tChannel 128C\tChannel 129N\tChannel 130C\tChannel 131"
-		case 10:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N"
-		case 11:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C"
-		case 16:
-			header += "\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C\tChannel 132N\tChannel 132C\tChannel 133N\tChannel 133C\tChannel 134N"
-		default:
-			header += ""
-		}
-	} else if brand == "itraq" {
-		switch channels {
-		case 4:
-			header += "\tChannel 114\tChannel 115\tChannel 116\tChannel 117"
-		case 8:
-			header += "\tChannel 113\tChannel 114\tChannel 115\tChannel 116\tChannel 117\tChannel 118\tChannel 119\tChannel 121"
-		default:
-			header += ""
-		}
+	if opt.ErrorRates == true {
+		header += "\tq-value\tPEP"
 	}
 
-	header += "\n"
+	if opt.Transfers == true {
+		header += "\tTransferred Ions"
+	}
 
-	// verify if the structure has labels, if so, replace the original channel names by them.
-	if hasLabels == true {
-
-		var c1, c2, c3, c4, c5, c6, c7, c8, c9, c10, c11, c12, c13, c14, c15, c16 string
-
-		for _, i := range printSet {
-			if len(i.UniqueLabels.Channel1.CustomName) >= 1 {
-				c1 = i.UniqueLabels.Channel1.CustomName
-				c2 = i.UniqueLabels.Channel2.CustomName
-				c3 = i.UniqueLabels.Channel3.CustomName
-				c4 = i.UniqueLabels.Channel4.CustomName
-				c5 = i.UniqueLabels.Channel5.CustomName
-				c6 = i.UniqueLabels.Channel6.CustomName
-				c7 = i.UniqueLabels.Channel7.CustomName
-				c8 = i.UniqueLabels.Channel8.CustomName
-				c9 = i.UniqueLabels.Channel9.CustomName
-				c10 = i.UniqueLabels.Channel10.CustomName
-				c11 = i.UniqueLabels.Channel11.CustomName
-				c12 = i.UniqueLabels.Channel12.CustomName
-				c13 = i.UniqueLabels.Channel13.CustomName
-				c14 = i.UniqueLabels.Channel14.CustomName
-				c15 = i.UniqueLabels.Channel15.CustomName
-				c16 = i.UniqueLabels.Channel16.CustomName
-				break
-			}
-		}
+	if opt.Abundances == true {
+		header += "\tTop3 Intensity\tiBAQ\tNSAF\temPAI"
+	}
 
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel1.Name, c1, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel2.Name, c2, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel3.Name, c3, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel4.Name, c4, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel5.Name, c5, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel6.Name, c6, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel7.Name, c7, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel8.Name, c8, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel9.Name, c9, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel10.Name, c10, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel11.Name, c11, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel12.Name, c12, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel13.Name, c13, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel14.Name, c14, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel15.Name, c15, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].UniqueLabels.Channel16.Name, c16, -1)
+	if opt.MS1Labels == true {
+		header += ms1LabelHeader
 	}
 
+	header += labelHeader(labels, opt.Labels)
+	if opt.Ratios == true {
+		header += ratioHeader(labels, opt.Labels)
+	}
+
+	header += "\n"
+
 	_, e = io.WriteString(file, header)
 	if e != nil {
 		msg.WriteToFile(e, "fatal")
 	}
 
+	// ions transferred with match-between-runs are not part of the protein evidence
+	var transferred = make(map[string]int)
+	for _, i := range evi.Ions {
+		if i.IsTransferred == true {
+			transferred[i.ProteinID]++
+		}
+	}
+
 	for _, i := range printSet {
 
 		var ip []string
@@ -362,43 +325,9 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 		sort.Strings(ip)
 
 		// change between Unique+Razor and Unique only based on parameter defined on labelquant
-		var reportIntensities [16]float64
-		if uniqueOnly == true || hasRazor == false {
-			reportIntensities[0] = i.UniqueLabels.Channel1.Intensity
-			reportIntensities[1] = i.UniqueLabels.Channel2.Intensity
-			reportIntensities[2] = i.UniqueLabels.Channel3.Intensity
-			reportIntensities[3] = i.UniqueLabels.Channel4.Intensity
-			reportIntensities[4] = i.UniqueLabels.Channel5.Intensity
-			reportIntensities[5] = i.UniqueLabels.Channel6.Intensity
-			reportIntensities[6] = i.UniqueLabels.Channel7.Intensity
-			reportIntensities[7] = i.UniqueLabels.Channel8.Intensity
-			reportIntensities[8] = i.UniqueLabels.Channel9.Intensity
-			reportIntensities[9] = i.UniqueLabels.Channel10.Intensity
-			reportIntensities[10] = i.UniqueLabels.Channel11.Intensity
-			reportIntensities[11] = i.UniqueLabels.Channel12.Intensity
-			reportIntensities[12] = i.UniqueLabels.Channel13.Intensity
-			reportIntensities[13] = i.UniqueLabels.Channel14.Intensity
-			reportIntensities[14] = i.UniqueLabels.Channel15.Intensity
-			reportIntensities[15] = i.UniqueLabels.Channel16.Intensity
-
-		} else {
-			reportIntensities[0] = i.URazorLabels.Channel1.Intensity
-			reportIntensities[1] = i.URazorLabels.Channel2.Intensity
-			reportIntensities[2] = i.URazorLabels.Channel3.Intensity
-			reportIntensities[3] = i.URazorLabels.Channel4.Intensity
-			reportIntensities[4] = i.URazorLabels.Channel5.Intensity
-			reportIntensities[5] = i.URazorLabels.Channel6.Intensity
-			reportIntensities[6] = i.URazorLabels.Channel7.Intensity
-			reportIntensities[7] = i.URazorLabels.Channel8.Intensity
-			reportIntensities[8] = i.URazorLabels.Channel9.Intensity
-			reportIntensities[9] = i.URazorLabels.Channel10.Intensity
-			reportIntensities[10] = i.URazorLabels.Channel11.Intensity
-			reportIntensities[11] = i.URazorLabels.Channel12.Intensity
-			reportIntensities[12] = i.URazorLabels.Channel13.Intensity
-			reportIntensities[13] = i.URazorLabels.Channel14.Intensity
-			reportIntensities[14] = i.URazorLabels.Channel15.Intensity
-			reportIntensities[15] = i.URazorLabels.Channel16.Intensity
-
+		reportLabels := i.URazorLabels
+		if opt.UniqueOnly == true || opt.Razor == false {
+			reportLabels = i.UniqueLabels
 		}
 
 		// proteins with almost no evidences, and completely shared with decoys are eliminated from the analysis,
@@ -432,88 +361,38 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 			strings.Join(ip, ", "),   // Indistinguishable Proteins
 		)
 
-		switch channels {
-		case 4:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				reportIntensities[0],
-				reportIntensities[1],
-				reportIntensities[2],
-				reportIntensities[3],
-			)
-		case 6:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				reportIntensities[0],
-				reportIntensities[1],
-				reportIntensities[2],
-				reportIntensities[3],
-				reportIntensities[4],
-				reportIntensities[5],
-			)
-		case 8:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				reportIntensities[0],
-				reportIntensities[1],
-				reportIntensities[2],
-				reportIntensities[3],
-				reportIntensities[4],
-				reportIntensities[5],
-				reportIntensities[6],
-				reportIntensities[7],
-			)
-		case 10:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		if opt.ErrorRates == true {
+			line = fmt.Sprintf("%s\t%.6f\t%.6f",
 				line,
-				reportIntensities[0],
-				reportIntensities[1],
-				reportIntensities[2],
-				reportIntensities[3],
-				reportIntensities[4],
-				reportIntensities[5],
-				reportIntensities[6],
-				reportIntensities[7],
-				reportIntensities[8],
-				reportIntensities[9],
+				i.QValue,
+				i.PEP,
 			)
-		case 11:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		}
+
+		if opt.Transfers == true {
+			line = fmt.Sprintf("%s\t%d",
 				line,
-				reportIntensities[0],
-				reportIntensities[1],
-				reportIntensities[2],
-				reportIntensities[3],
-				reportIntensities[4],
-				reportIntensities[5],
-				reportIntensities[6],
-				reportIntensities[7],
-				reportIntensities[8],
-				reportIntensities[9],
-				reportIntensities[10],
+				transferred[i.ProteinID],
 			)
-		case 16:
-			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		}
+
+		if opt.Abundances == true {
+			line = fmt.Sprintf("%s\t%6.f\t%6.f\t%.6f\t%.4f",
 				line,
-				reportIntensities[0],
-				reportIntensities[1],
-				reportIntensities[2],
-				reportIntensities[3],
-				reportIntensities[4],
-				reportIntensities[5],
-				reportIntensities[6],
-				reportIntensities[7],
-				reportIntensities[8],
-				reportIntensities[9],
-				reportIntensities[10],
-				reportIntensities[11],
-				reportIntensities[12],
-				reportIntensities[13],
-				reportIntensities[14],
-				reportIntensities[15],
+				i.Top3Intensity, // Top3 Intensity
+				i.IBAQ,          // iBAQ
+				i.NSAF,          // NSAF
+				i.EmPAI,         // emPAI
 			)
-		default:
-			header += ""
+		}
+
+		if opt.MS1Labels == true {
+			line += ms1LabelColumns(i.MS1Labels)
+		}
+
+		line += labelColumns(reportLabels, len(labels.Channels))
+		if opt.Ratios == true {
+			line += ratioColumns(reportLabels, len(labels.Channels))
 		}
 
 		line += "\n"
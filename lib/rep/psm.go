@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"philosopher/lib/iso"
 	"philosopher/lib/msg"
 
 	"philosopher/lib/bio"
@@ -61,6 +62,8 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 		p.LocalizedPTMSites = i.LocalizedPTMSites
 		p.LocalizedPTMMassDiff = i.LocalizedPTMMassDiff
 		p.Probability = i.Probability
+		p.QValue = i.QValue
+		p.PEP = i.PEP
 		p.Expectation = i.Expectation
 		p.Xcorr = i.Xcorr
 		p.DeltaCN = i.DeltaCN
@@ -118,7 +121,7 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 }
 
 // MetaPSMReport report all psms from study that passed the FDR filter
-func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet, hasLoc, hasLabels bool) {
+func (evi Evidence) MetaPSMReport(labels iso.Labels, opt ReportOptions) {
 
 	var header string
 	output := fmt.Sprintf("%s%spsm.tsv", sys.MetaDir(), string(filepath.Separator))
@@ -137,7 +140,7 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 		compositeName := strings.Split(evi.PSM[i].Spectrum, "#")
 		evi.PSM[i].Spectrum = compositeName[0]
 
-		if hasDecoys == false {
+		if opt.Decoys == false {
 			if evi.PSM[i].IsDecoy == false {
 				printSet = append(printSet, evi.PSM[i])
 			}
@@ -148,89 +151,59 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 
 	header = "Spectrum\tSpectrum File\tPeptide\tModified Peptide\tPeptide Length\tCharge\tRetention\tObserved Mass\tCalibrated Observed Mass\tObserved M/Z\tCalibrated Observed M/Z\tCalculated Peptide Mass\tCalculated M/Z\tDelta Mass"
 
-	if isComet == true {
+	if opt.Alignment == true {
+		header += "\tAligned Retention"
+	}
+
+	if opt.IsComet == true {
 		header += "\tXCorr\tDeltaCN\tDeltaCNStar\tSPScore\tSPRank"
 	}
 
 	header += "\tExpectation\tHyperscore\tNextscore\tPeptideProphet Probability\tNumber of Enzymatic Termini\tNumber of Missed Cleavages\tIntensity\tIon Mobility\tCompensation Voltage\tAssigned Modifications\tObserved Modifications"
 
-	if hasLoc == true {
+	if opt.ErrorRates == true {
+		header += "\tq-value\tPEP"
+	}
+
+	if opt.Features == true {
+		header += "\tApex Intensity\tApex Retention\tPeak Area\tPeak FWHM\tIsotope Correlation"
+	}
+
+	if opt.Localization == true {
 		header += "\tNumber of Phospho Sites\tPhospho Site Localization"
 	}
 
 	header += "\tIs Unique\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
 
-	if brand == "tmt" {
-		switch channels {
-		case 6:
-			header += "\tIs Used\tPurity\tChannel 126\tChannel 127N\tChannel 128C\tChannel 129N\tChannel 130C\tChannel 131"
-		case 10:
-			header += "\tIs Used\tPurity\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N"
-		case 11:
-			header += "\tIs Used\tPurity\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C"
-		case 16:
-			header += "\tIs Used\tPurity\tChannel 126\tChannel 127N\tChannel 127C\tChannel 128N\tChannel 128C\tChannel 129N\tChannel 129C\tChannel 130N\tChannel 130C\tChannel 131N\tChannel 131C\tChannel 132N\tChannel 132C\tChannel 133N\tChannel 133C\tChannel 134N"
-		default:
-			header += ""
+	if opt.MS1Labels == true {
+		header += ms1LabelHeader
+	}
+
+	if len(labels.Channels) > 0 {
+		header += "\tIs Used\tPurity"
+		if opt.Correction == true {
+			header += "\tIs Corrected"
+		}
+		if opt.SPS == true {
+			header += "\tSPS Match"
+		}
+		if opt.SN == true {
+			header += "\tSummed S/N"
+		}
+		if opt.Interference == true {
+			header += "\tInterference Correction"
 		}
-	} else if brand == "itraq" {
-		switch channels {
-		case 4:
-			header += "\tIs Used\tPurity\tChannel 114\tChannel 115\tChannel 116\tChannel 117"
-		case 8:
-			header += "\tIs Used\tPurity\tChannel 113\tChannel 114\tChannel 115\tChannel 116\tChannel 117\tChannel 118\tChannel 119\tChannel 121"
-		default:
-			header += ""
+		if opt.Carrier == true {
+			header += "\tSample/Carrier Ratio\tAbove Carrier Cap"
+		}
+		header += labelHeader(labels, opt.Labels)
+		if opt.Ratios == true {
+			header += ratioHeader(labels, opt.Labels)
 		}
 	}
 
 	header += "\n"
 
-	// verify if the structure has labels, if so, replace the original channel names by them.
-	if hasLabels == true {
-
-		var c1, c2, c3, c4, c5, c6, c7, c8, c9, c10, c11, c12, c13, c14, c15, c16 string
-
-		for _, i := range printSet {
-			if len(i.Labels.Channel1.CustomName) >= 1 {
-				c1 = i.Labels.Channel1.CustomName
-				c2 = i.Labels.Channel2.CustomName
-				c3 = i.Labels.Channel3.CustomName
-				c4 = i.Labels.Channel4.CustomName
-				c5 = i.Labels.Channel5.CustomName
-				c6 = i.Labels.Channel6.CustomName
-				c7 = i.Labels.Channel7.CustomName
-				c8 = i.Labels.Channel8.CustomName
-				c9 = i.Labels.Channel9.CustomName
-				c10 = i.Labels.Channel10.CustomName
-				c11 = i.Labels.Channel11.CustomName
-				c12 = i.Labels.Channel12.CustomName
-				c13 = i.Labels.Channel13.CustomName
-				c14 = i.Labels.Channel14.CustomName
-				c15 = i.Labels.Channel15.CustomName
-				c16 = i.Labels.Channel16.CustomName
-				break
-			}
-		}
-
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel1.Name, c1, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel2.Name, c2, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel3.Name, c3, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel4.Name, c4, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel5.Name, c5, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel6.Name, c6, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel7.Name, c7, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel8.Name, c8, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel9.Name, c9, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel10.Name, c10, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel11.Name, c11, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel12.Name, c12, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel13.Name, c13, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel14.Name, c14, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel15.Name, c15, -1)
-		header = strings.Replace(header, "Channel "+printSet[10].Labels.Channel16.Name, c16, -1)
-	}
-
 	_, e = io.WriteString(file, header)
 	if e != nil {
 		msg.WriteToFile(errors.New("Cannot print PSM to file"), "fatal")
@@ -276,7 +249,14 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			i.Massdiff,
 		)
 
-		if isComet == true {
+		if opt.Alignment == true {
+			line = fmt.Sprintf("%s\t%.4f",
+				line,
+				i.AlignedRetentionTime,
+			)
+		}
+
+		if opt.IsComet == true {
 			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
 				line,
 				i.Xcorr,
@@ -302,7 +282,26 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			strings.Join(obs, ", "),
 		)
 
-		if hasLoc == true {
+		if opt.ErrorRates == true {
+			line = fmt.Sprintf("%s\t%.6f\t%.6f",
+				line,
+				i.QValue,
+				i.PEP,
+			)
+		}
+
+		if opt.Features == true {
+			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+				line,
+				i.Feature.Apex,
+				i.Feature.ApexRetentionTime,
+				i.Feature.Area,
+				i.Feature.FWHM,
+				i.Feature.IsotopeCorrelation,
+			)
+		}
+
+		if opt.Localization == true {
 
 			var sites int
 			var md string
@@ -334,100 +333,35 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			strings.Join(mappedProteins, ", "),
 		)
 
-		switch channels {
-		case 4:
-			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.IsUsed,
-				i.Purity,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-			)
-		case 6:
-			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.IsUsed,
-				i.Purity,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-			)
-		case 8:
-			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.IsUsed,
-				i.Purity,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-			)
-		case 10:
-			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.IsUsed,
-				i.Purity,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-			)
-		case 11:
-			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
-				line,
-				i.Labels.IsUsed,
-				i.Purity,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-				i.Labels.Channel11.Intensity,
-			)
-		case 16:
-			line = fmt.Sprintf("%s\t%t\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
+		if opt.MS1Labels == true {
+			line += ms1LabelColumns(i.MS1Labels)
+		}
+
+		if len(labels.Channels) > 0 {
+			line = fmt.Sprintf("%s\t%t\t%.4f",
 				line,
 				i.Labels.IsUsed,
 				i.Purity,
-				i.Labels.Channel1.Intensity,
-				i.Labels.Channel2.Intensity,
-				i.Labels.Channel3.Intensity,
-				i.Labels.Channel4.Intensity,
-				i.Labels.Channel5.Intensity,
-				i.Labels.Channel6.Intensity,
-				i.Labels.Channel7.Intensity,
-				i.Labels.Channel8.Intensity,
-				i.Labels.Channel9.Intensity,
-				i.Labels.Channel10.Intensity,
-				i.Labels.Channel11.Intensity,
-				i.Labels.Channel12.Intensity,
-				i.Labels.Channel13.Intensity,
-				i.Labels.Channel14.Intensity,
-				i.Labels.Channel15.Intensity,
-				i.Labels.Channel16.Intensity,
 			)
-		default:
-			header += ""
+			if opt.Correction == true {
+				line = fmt.Sprintf("%s\t%t", line, i.Labels.IsCorrected)
+			}
+			if opt.SPS == true {
+				line = fmt.Sprintf("%s\t%.4f", line, i.SPSMatch)
+			}
+			if opt.SN == true {
+				line = fmt.Sprintf("%s\t%.4f", line, i.Labels.SignalToNoise())
+			}
+			if opt.Interference == true {
+				line = fmt.Sprintf("%s\t%.4f", line, i.InterferenceCorrection)
+			}
+			if opt.Carrier == true {
+				line = fmt.Sprintf("%s\t%.4f\t%t", line, i.CarrierRatio, i.IsAboveCarrierCap)
+			}
+			line += labelColumns(i.Labels, len(labels.Channels))
+			if opt.Ratios == true {
+				line += ratioColumns(i.Labels, len(labels.Channels))
+			}
 		}
 
 		line += "\n"
@@ -2,7 +2,6 @@ package rep
 
 import (
 	"fmt"
-	"strconv"
 
 	"philosopher/lib/id"
 	"philosopher/lib/iso"
@@ -133,12 +132,15 @@ type PSMEvidence struct {
 	PrecursorNeutralMass             float64
 	PrecursorExpMass                 float64
 	RetentionTime                    float64
+	AlignedRetentionTime             float64
 	CalcNeutralPepMass               float64
 	RawMassdiff                      float64
 	Massdiff                         float64
 	LocalizedPTMSites                map[string]int
 	LocalizedPTMMassDiff             map[string]string
 	Probability                      float64
+	QValue                           float64
+	PEP                              float64
 	Expectation                      float64
 	Xcorr                            float64
 	DeltaCN                          float64
@@ -151,14 +153,39 @@ type PSMEvidence struct {
 	Intensity                        float64
 	IonMobility                      float64
 	Purity                           float64
+	SPSMatch                         float64
+	InterferenceCorrection           float64
+	CarrierRatio                     float64
 	CompensationVoltage              float64
 	IsDecoy                          bool
 	IsUnique                         bool
 	IsURazor                         bool
+	IsAboveCarrierCap                bool
+	Feature                          Feature
+	MS1Labels                        MS1Labels
 	Labels                           iso.Labels
 	Modifications                    mod.Modifications
 }
 
+// Feature holds the MS1 isotope envelope traced for a precursor during label-free quantification
+type Feature struct {
+	Apex               float64
+	ApexRetentionTime  float64
+	Area               float64
+	FWHM               float64
+	IsotopeCorrelation float64
+}
+
+// MS1Labels holds the MS1 intensities of the light, medium and heavy forms of a labelled precursor, and
+// their ratios to the light form
+type MS1Labels struct {
+	Light       float64
+	Medium      float64
+	Heavy       float64
+	MediumRatio float64
+	HeavyRatio  float64
+}
+
 // PSMEvidenceList ...
 type PSMEvidenceList []PSMEvidence
 
@@ -186,20 +213,27 @@ type IonEvidence struct {
 	MZ                       float64
 	PeptideMass              float64
 	PrecursorNeutralMass     float64
+	AlignedRetentionTime     float64
 	Weight                   float64
 	GroupWeight              float64
 	Intensity                float64
 	Probability              float64
+	QValue                   float64
+	PEP                      float64
 	Expectation              float64
 	SummedLabelIntensity     float64
+	TransferQValue           float64
 	IsUnique                 bool
 	IsURazor                 bool
 	IsDecoy                  bool
+	IsTransferred            bool
 	Protein                  string
 	ProteinID                string
 	GeneName                 string
 	EntryName                string
 	ProteinDescription       string
+	Feature                  Feature
+	MS1Labels                MS1Labels
 	Labels                   iso.Labels
 	PhosphoLabels            iso.Labels
 	Modifications            mod.Modifications
@@ -233,9 +267,14 @@ type PeptideEvidence struct {
 	Spc                    int
 	Intensity              float64
 	Probability            float64
+	QValue                 float64
+	PEP                    float64
 	ModifiedObservations   int
 	UnModifiedObservations int
+	TransferQValue         float64
 	IsDecoy                bool
+	IsTransferred          bool
+	MS1Labels              MS1Labels
 	Labels                 iso.Labels
 	PhosphoLabels          iso.Labels
 	Modifications          mod.Modifications
@@ -280,10 +319,17 @@ type ProteinEvidence struct {
 	TotalIntensity         float64
 	UniqueIntensity        float64
 	URazorIntensity        float64 // Unique + razor
+	Top3Intensity          float64
+	IBAQ                   float64
+	NSAF                   float64
+	EmPAI                  float64
 	Probability            float64
 	TopPepProb             float64
+	QValue                 float64
+	PEP                    float64
 	IsDecoy                bool
 	IsContaminant          bool
+	MS1Labels              MS1Labels
 	TotalLabels            iso.Labels
 	UniqueLabels           iso.Labels
 	URazorLabels           iso.Labels // Unique + razor
@@ -320,12 +366,21 @@ type CombinedProteinEvidence struct {
 	ProteinProbability     float64
 	TopPepProb             float64
 	PeptideIons            []id.PeptideIonIdentification
+	QValue                 map[string]float64
+	PEP                    map[string]float64
 	TotalSpc               map[string]int
 	UniqueSpc              map[string]int
 	UrazorSpc              map[string]int
 	TotalIntensity         map[string]float64
 	UniqueIntensity        map[string]float64
 	UrazorIntensity        map[string]float64
+	TransferredIons        map[string]int
+	MaxLFQ                 map[string]float64
+	Top3Intensity          map[string]float64
+	IBAQ                   map[string]float64
+	NSAF                   map[string]float64
+	EmPAI                  map[string]float64
+	MS1Labels              map[string]MS1Labels
 	TotalLabels            map[string]iso.Labels
 	UniqueLabels           map[string]iso.Labels
 	URazorLabels           map[string]iso.Labels // Unique + razor
@@ -352,6 +407,12 @@ type CombinedPeptideEvidence struct {
 	AssignedMassDiffs  map[string]uint8
 	Spc                map[string]int
 	Intensity          map[string]float64
+	TransferQValue     map[string]float64
+	QValue             map[string]float64
+	PEP                map[string]float64
+	MaxLFQ             map[string]float64
+	RetentionTime      map[string]float64
+	MS1Labels          map[string]MS1Labels
 }
 
 // CombinedPeptideEvidenceList is a list of Combined Peptide Evidences
@@ -366,6 +427,28 @@ type ModificationEvidence struct {
 	MassBins []MassBin
 }
 
+// ReportOptions tells which optional columns are printed by the PSM, ion, peptide and protein reports
+type ReportOptions struct {
+	Decoys       bool
+	IsComet      bool
+	Localization bool
+	Labels       bool
+	Features     bool
+	Transfers    bool
+	Alignment    bool
+	Abundances   bool
+	Correction   bool
+	Ratios       bool
+	SPS          bool
+	SN           bool
+	Interference bool
+	Carrier      bool
+	ErrorRates   bool
+	MS1Labels    bool
+	Razor        bool
+	UniqueOnly   bool
+}
+
 // MassBin represents each bin from the mass distribution
 type MassBin struct {
 	LowerMass     float64
@@ -392,32 +475,111 @@ func Run(m met.Data) {
 	var repo = New()
 	repo.RestoreGranular()
 
-	var isComet bool
-	var hasLoc bool
-	var hasLabels bool
-	var isoBrand string
-	var isoChannels int
+	// the optional columns are found once and shared by the reports
+	var opt ReportOptions
+	opt.Decoys = m.Report.Decoys
+	opt.Razor = m.Filter.Razor
+	opt.UniqueOnly = m.Quantify.Unique
 
 	if len(m.Comet.Param) > 0 {
-		isComet = true
+		opt.IsComet = true
 	}
 
 	if m.PTMProphet.InputFiles != nil || len(m.PTMProphet.InputFiles) > 0 {
-		hasLoc = true
+		opt.Localization = true
 	}
 
-	if m.Quantify.Brand == "tmt" {
-		isoBrand = "tmt"
-	} else if m.Quantify.Brand == "itraq" {
-		isoBrand = "itraq"
+	// the isobaric channels are reported as quantified by labelquant
+	isoLabels := labelTemplate(repo.PSM)
+
+	if len(m.Quantify.Annot) > 0 {
+		opt.Labels = true
 	}
 
-	if len(m.Quantify.Plex) > 0 {
-		isoChannels, _ = strconv.Atoi(m.Quantify.Plex)
+	for _, i := range repo.PSM {
+		if i.Feature.Area > 0 {
+			opt.Features = true
+			break
+		}
 	}
 
-	if len(m.Quantify.Annot) > 0 {
-		hasLabels = true
+	for _, i := range repo.PSM {
+		if i.AlignedRetentionTime > 0 {
+			opt.Alignment = true
+			break
+		}
+	}
+
+	for _, i := range repo.PSM {
+		for _, j := range i.Labels.Channels {
+			if j.Ratio != 0 {
+				opt.Ratios = true
+			}
+		}
+		if opt.Ratios == true {
+			break
+		}
+	}
+
+	for _, i := range repo.PSM {
+		if i.SPSMatch > 0 {
+			opt.SPS = true
+			break
+		}
+	}
+
+	for _, i := range repo.PSM {
+		if i.MS1Labels.Light > 0 || i.MS1Labels.Medium > 0 || i.MS1Labels.Heavy > 0 {
+			opt.MS1Labels = true
+			break
+		}
+	}
+
+	for _, i := range repo.PSM {
+		if i.Labels.SignalToNoise() > 0 {
+			opt.SN = true
+			break
+		}
+	}
+
+	for _, i := range repo.PSM {
+		if i.InterferenceCorrection > 0 {
+			opt.Interference = true
+			break
+		}
+	}
+
+	// the q-values and PEPs are stored on every level when the workspace was filtered with this version
+	if m.Filter.ErrorRates == true {
+		opt.ErrorRates = true
+	}
+
+	for _, i := range repo.PSM {
+		if i.CarrierRatio > 0 {
+			opt.Carrier = true
+			break
+		}
+	}
+
+	for _, i := range repo.PSM {
+		if i.Labels.IsCorrected == true {
+			opt.Correction = true
+			break
+		}
+	}
+
+	for _, i := range repo.Ions {
+		if i.IsTransferred == true {
+			opt.Transfers = true
+			break
+		}
+	}
+
+	for _, i := range repo.Proteins {
+		if i.NSAF > 0 {
+			opt.Abundances = true
+			break
+		}
 	}
 
 	// // get the labels from the annotation file
@@ -430,20 +592,23 @@ func Run(m met.Data) {
 	logrus.Info("Creating reports")
 
 	// PSM
-	repo.MetaPSMReport(isoBrand, isoChannels, m.Report.Decoys, isComet, hasLoc, hasLabels)
+	repo.MetaPSMReport(isoLabels, opt)
 
 	// Ion
-	repo.MetaIonReport(isoBrand, isoChannels, m.Report.Decoys, hasLabels)
+	repo.MetaIonReport(isoLabels, opt)
 
 	// Peptide
-	repo.MetaPeptideReport(isoBrand, isoChannels, m.Report.Decoys, hasLabels)
+	repo.MetaPeptideReport(isoLabels, opt)
 
 	// Protein
 	if len(m.Filter.Pox) > 0 || m.Filter.Inference == true {
-		repo.MetaProteinReport(isoBrand, isoChannels, m.Report.Decoys, m.Filter.Razor, m.Quantify.Unique, hasLabels)
+		repo.MetaProteinReport(isoLabels, opt)
 		repo.ProteinFastaReport(m.Report.Decoys)
 	}
 
+	// Sites
+	repo.SiteReport(isoLabels, opt.Labels)
+
 	// Modifications
 	if len(repo.Modifications.MassBins) > 0 {
 		repo.ModificationReport()
@@ -457,7 +622,7 @@ func Run(m met.Data) {
 
 	// MSstats
 	if m.Report.MSstats == true {
-		repo.MetaMSstatsReport(isoBrand, isoChannels, m.Report.Decoys)
+		repo.MetaMSstatsReport(isoLabels, m.Report.Decoys, opt.Labels)
 	}
 
 	// MzID
@@ -487,3 +652,99 @@ func getModsList(m map[string]mod.Modification) ([]string, []string) {
 
 	return a, o
 }
+
+// labelHeader builds the report columns of the isobaric channels, the custom names from the
+// annotation file replace the channel names when available
+func labelHeader(labels iso.Labels, hasLabels bool) string {
+
+	var header string
+
+	for _, i := range labels.Channels {
+		if hasLabels == true && len(i.CustomName) > 0 {
+			header += "\t" + i.CustomName
+		} else {
+			header += "\tChannel " + i.Name
+		}
+	}
+
+	return header
+}
+
+// labelColumns formats the channel intensities, the channels missing from the labels are reported as zero
+func labelColumns(labels iso.Labels, channels int) string {
+
+	var line string
+
+	for i := 0; i < channels; i++ {
+		if i < len(labels.Channels) {
+			line += fmt.Sprintf("\t%.4f", labels.Channels[i].Intensity)
+		} else {
+			line += "\t0.0000"
+		}
+	}
+
+	return line
+}
+
+// ratioHeader builds the report columns of the channel log2 ratios
+func ratioHeader(labels iso.Labels, hasLabels bool) string {
+
+	var header string
+
+	for _, i := range labels.Channels {
+		if hasLabels == true && len(i.CustomName) > 0 {
+			header += "\tLog2 Ratio " + i.CustomName
+		} else {
+			header += "\tLog2 Ratio " + i.Name
+		}
+	}
+
+	return header
+}
+
+// ratioColumns formats the channel log2 ratios, the channels missing from the labels are reported as zero
+func ratioColumns(labels iso.Labels, channels int) string {
+
+	var line string
+
+	for i := 0; i < channels; i++ {
+		if i < len(labels.Channels) {
+			line += fmt.Sprintf("\t%.4f", labels.Channels[i].Ratio)
+		} else {
+			line += "\t0.0000"
+		}
+	}
+
+	return line
+}
+
+// ms1LabelHeader are the report columns of the MS1 labelled forms
+const ms1LabelHeader = "\tLight Intensity\tMedium Intensity\tHeavy Intensity\tMedium/Light Ratio\tHeavy/Light Ratio"
+
+// ms1LabelColumns formats the MS1 intensities and ratios of the labelled forms
+func ms1LabelColumns(l MS1Labels) string {
+	return fmt.Sprintf("\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f", l.Light, l.Medium, l.Heavy, l.MediumRatio, l.HeavyRatio)
+}
+
+// labelTemplate returns the isobaric channels of the quantified PSMs, preferring the ones with custom names
+func labelTemplate(psm PSMEvidenceList) iso.Labels {
+
+	var labels iso.Labels
+
+	for _, i := range psm {
+
+		if len(i.Labels.Channels) == 0 {
+			continue
+		}
+
+		if len(labels.Channels) == 0 {
+			labels = i.Labels
+		}
+
+		if len(i.Labels.Channels[0].CustomName) > 0 {
+			return i.Labels
+		}
+	}
+
+	return labels
+}
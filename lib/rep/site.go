@@ -0,0 +1,265 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/iso"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+const (
+	// siteProbability is the minimum localization probability for a PSM to support a site
+	siteProbability = 0.5
+
+	// siteWindow is the number of residues reported on each side of a site
+	siteWindow = 7
+)
+
+// SiteEvidence is a localized modification site on a protein
+type SiteEvidence struct {
+	Modification       string
+	ProteinID          string
+	Protein            string
+	Gene               string
+	Position           int
+	AminoAcid          string
+	Window             string
+	Probability        float64
+	BestPSM            string
+	BestPSMProbability float64
+	Spc                int
+	Intensity          float64
+	Labels             iso.Labels
+}
+
+// SiteEvidenceList is a list of modification sites
+type SiteEvidenceList []SiteEvidence
+
+func (a SiteEvidenceList) Len() int      { return len(a) }
+func (a SiteEvidenceList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a SiteEvidenceList) Less(i, j int) bool {
+	if a[i].ProteinID == a[j].ProteinID {
+		return a[i].Position < a[j].Position
+	}
+	return a[i].ProteinID < a[j].ProteinID
+}
+
+// Sites builds the site table of each localized modification, keyed by modification. Each PSM supports the
+// protein positions localized with at least siteProbability, the intensity sums the most intense PSM of each
+// ion and the isobaric channels sum the used PSMs
+func (evi Evidence) Sites() map[string]SiteEvidenceList {
+
+	var sequences = make(map[string]string)
+	for _, i := range evi.Proteins {
+		if len(i.Sequence) > 0 {
+			sequences[i.ProteinID] = i.Sequence
+		}
+	}
+
+	var sites = make(map[string]map[string]*SiteEvidence)
+	var ions = make(map[*SiteEvidence]map[string]float64)
+
+	for _, i := range evi.PSM {
+
+		if i.IsDecoy == true || len(i.LocalizedPTMMassDiff) == 0 {
+			continue
+		}
+
+		sequence := sequences[i.ProteinID]
+		offset := strings.Index(sequence, i.Peptide)
+		if offset < 0 {
+			continue
+		}
+
+		for k, v := range i.LocalizedPTMMassDiff {
+
+			name := SiteName(k)
+
+			if _, ok := sites[name]; !ok {
+				sites[name] = make(map[string]*SiteEvidence)
+			}
+
+			for position, probability := range SiteProbabilities(v) {
+
+				if probability < siteProbability || position > len(i.Peptide) {
+					continue
+				}
+
+				site := offset + position
+				key := fmt.Sprintf("%s#%d", i.ProteinID, site)
+
+				s, ok := sites[name][key]
+				if !ok {
+					s = &SiteEvidence{
+						Modification: name,
+						ProteinID:    i.ProteinID,
+						Protein:      i.Protein,
+						Gene:         i.GeneName,
+						Position:     site,
+						AminoAcid:    string(sequence[site-1]),
+						Window:       sequenceWindow(sequence, site),
+					}
+					sites[name][key] = s
+					ions[s] = make(map[string]float64)
+				}
+
+				s.Spc++
+
+				if probability > s.Probability || (probability == s.Probability && i.Probability > s.BestPSMProbability) {
+					s.Probability = probability
+					s.BestPSM = i.Spectrum
+					s.BestPSMProbability = i.Probability
+				}
+
+				if i.Intensity > ions[s][i.IonForm] {
+					ions[s][i.IonForm] = i.Intensity
+				}
+
+				if i.Labels.IsUsed == true {
+					s.Labels.Add(i.Labels)
+				}
+			}
+		}
+	}
+
+	var tables = make(map[string]SiteEvidenceList)
+
+	for name, v := range sites {
+
+		var list SiteEvidenceList
+		for _, s := range v {
+			for _, j := range ions[s] {
+				s.Intensity += j
+			}
+			list = append(list, *s)
+		}
+
+		if len(list) > 0 {
+			sort.Sort(list)
+			tables[name] = list
+		}
+	}
+
+	return tables
+}
+
+// SiteName turns a PTMProphet modification, like PTMProphet_STY79.9663, into a name usable on file names
+func SiteName(ptm string) string {
+
+	name := strings.TrimPrefix(ptm, "PTMProphet_")
+
+	return strings.NewReplacer(":", "", "/", "", "\\", "", " ", "").Replace(name)
+}
+
+// SiteProbabilities parses a PTMProphet peptide, like PEPS(0.950)T(0.050)IDEK, into the localization
+// probabilities of the peptide positions
+func SiteProbabilities(ptmPeptide string) map[int]float64 {
+
+	var probabilities = make(map[int]float64)
+	var position int
+
+	for i := 0; i < len(ptmPeptide); i++ {
+
+		c := ptmPeptide[i]
+
+		switch {
+		case c == '[':
+			// mass shifts are not residues
+			for i < len(ptmPeptide) && ptmPeptide[i] != ']' {
+				i++
+			}
+		case c == '(':
+			end := strings.IndexByte(ptmPeptide[i:], ')')
+			if end < 0 {
+				return probabilities
+			}
+			if p, e := strconv.ParseFloat(ptmPeptide[i+1:i+end], 64); e == nil && position > 0 {
+				probabilities[position] = p
+			}
+			i += end
+		case c >= 'A' && c <= 'Z':
+			position++
+		}
+	}
+
+	return probabilities
+}
+
+// sequenceWindow returns the residues around a protein position, padded with _ past the protein termini
+func sequenceWindow(sequence string, position int) string {
+
+	var window string
+
+	for i := position - 1 - siteWindow; i <= position-1+siteWindow; i++ {
+		if i < 0 || i >= len(sequence) {
+			window += "_"
+		} else {
+			window += string(sequence[i])
+		}
+	}
+
+	return window
+}
+
+// SiteReport writes the site table of each localized modification
+func (evi Evidence) SiteReport(labels iso.Labels, hasLabels bool) {
+
+	for name, sites := range evi.Sites() {
+
+		output := fmt.Sprintf("%s%ssite_%s.tsv", sys.MetaDir(), string(filepath.Separator), name)
+
+		file, e := os.Create(output)
+		if e != nil {
+			msg.WriteFile(errors.New("site output file"), "fatal")
+		}
+
+		header := "Protein ID\tProtein\tGene\tPosition\tAmino Acid\tSequence Window\tLocalization Probability\tBest PSM\tBest PSM Probability\tSpectral Count\tIntensity"
+		header += labelHeader(labels, hasLabels)
+		header += "\n"
+
+		_, e = io.WriteString(file, header)
+		if e != nil {
+			msg.WriteToFile(errors.New("Cannot print sites to file"), "fatal")
+		}
+
+		for _, i := range sites {
+
+			line := fmt.Sprintf("%s\t%s\t%s\t%d\t%s\t%s\t%.4f\t%s\t%.4f\t%d\t%.4f",
+				i.ProteinID,
+				i.Protein,
+				i.Gene,
+				i.Position,
+				i.AminoAcid,
+				i.Window,
+				i.Probability,
+				i.BestPSM,
+				i.BestPSMProbability,
+				i.Spc,
+				i.Intensity,
+			)
+
+			line += labelColumns(i.Labels, len(labels.Channels))
+			line += "\n"
+
+			_, e = io.WriteString(file, line)
+			if e != nil {
+				msg.WriteToFile(errors.New("Cannot print sites to file"), "fatal")
+			}
+		}
+
+		file.Close()
+
+		// copy to work directory
+		sys.CopyFile(output, filepath.Base(output))
+	}
+
+	return
+}
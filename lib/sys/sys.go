@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"philosopher/lib/msg"
@@ -230,6 +232,12 @@ func DBBin() string {
 	return p
 }
 
+// AlignmentBin file
+func AlignmentBin() string {
+	p := fmt.Sprintf("%s%salignment.bin", MetaDir(), string(filepath.Separator))
+	return p
+}
+
 // LFQBin file
 func LFQBin() string {
 	p := fmt.Sprintf("%s%slfq.bin", MetaDir(), string(filepath.Separator))
@@ -248,6 +256,12 @@ func MODBin() string {
 	return p
 }
 
+// SpectraCacheDir is the directory keeping the decoded spectra from each run
+func SpectraCacheDir() string {
+	p := fmt.Sprintf("%s%sspectra", MetaDir(), string(filepath.Separator))
+	return p
+}
+
 // MetaDir dir
 func MetaDir() string {
 	return ".meta"
@@ -303,3 +317,26 @@ func FilePermission() os.FileMode {
 	//return 0644
 	return 0755
 }
+
+// AvailableMemory returns the memory available for new processes in bytes, read from
+// /proc/meminfo. Zero is returned when the value is not known, like on Windows and macOS
+func AvailableMemory() uint64 {
+
+	b, e := ioutil.ReadFile("/proc/meminfo")
+	if e != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, e := strconv.ParseUint(fields[1], 10, 64)
+			if e != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+
+	return 0
+}
@@ -7,116 +7,111 @@ import (
 	"philosopher/lib/msg"
 )
 
-// New builds a new Labelled spectra object
-func New(plex string) iso.Labels {
-
-	var o iso.Labels
-
-	if plex == "6" {
-
-		o.Channel1.Name = "126"
-		o.Channel2.Name = "127N"
-		o.Channel3.Name = "128C"
-		o.Channel4.Name = "129N"
-		o.Channel5.Name = "130C"
-		o.Channel6.Name = "131"
-
-		o.Channel1.Mz = 126.127726
-		o.Channel2.Mz = 127.124761
-		o.Channel3.Mz = 128.134436
-		o.Channel4.Mz = 129.131471
-		o.Channel5.Mz = 130.141145
-		o.Channel6.Mz = 131.138180
-
-	} else if plex == "10" {
-
-		o.Channel1.Name = "126"
-		o.Channel2.Name = "127N"
-		o.Channel3.Name = "127C"
-		o.Channel4.Name = "128N"
-		o.Channel5.Name = "128C"
-		o.Channel6.Name = "129N"
-		o.Channel7.Name = "129C"
-		o.Channel8.Name = "130N"
-		o.Channel9.Name = "130C"
-		o.Channel10.Name = "131N"
-
-		o.Channel1.Mz = 126.127726
-		o.Channel2.Mz = 127.124761
-		o.Channel3.Mz = 127.131081
-		o.Channel4.Mz = 128.128116
-		o.Channel5.Mz = 128.134436
-		o.Channel6.Mz = 129.131471
-		o.Channel7.Mz = 129.137790
-		o.Channel8.Mz = 130.134825
-		o.Channel9.Mz = 130.141145
-		o.Channel10.Mz = 131.138180
+// reporters are the TMT and TMTpro reporter ions, the plexes use them in this order
+var reporters = []iso.Reagent{
+	{Name: "126", Mz: 126.127726},
+	{Name: "127N", Mz: 127.124761},
+	{Name: "127C", Mz: 127.131081},
+	{Name: "128N", Mz: 128.128116},
+	{Name: "128C", Mz: 128.134436},
+	{Name: "129N", Mz: 129.131471},
+	{Name: "129C", Mz: 129.137790},
+	{Name: "130N", Mz: 130.134825},
+	{Name: "130C", Mz: 130.141145},
+	{Name: "131N", Mz: 131.138180},
+	{Name: "131C", Mz: 131.144499},
+	{Name: "132N", Mz: 132.141535},
+	{Name: "132C", Mz: 132.147855},
+	{Name: "133N", Mz: 133.144890},
+	{Name: "133C", Mz: 133.151210},
+	{Name: "134N", Mz: 134.148245},
+	{Name: "134C", Mz: 134.154565},
+	{Name: "135N", Mz: 135.151600},
+}
 
-	} else if plex == "11" {
+// Reagents returns the reporter ions of the given plex
+func Reagents(plex string) []iso.Reagent {
+
+	switch plex {
+	case "6":
+		// the 6-plex reporters 128, 130 and 131 share the masses of 128C, 130C and 131N
+		return []iso.Reagent{
+			reporters[0],
+			reporters[1],
+			{Name: "128C", Mz: reporters[4].Mz},
+			{Name: "129N", Mz: reporters[5].Mz},
+			{Name: "130C", Mz: reporters[8].Mz},
+			{Name: "131", Mz: reporters[9].Mz},
+		}
+	case "10":
+		return reporters[:10]
+	case "11":
+		return reporters[:11]
+	case "16":
+		return reporters[:16]
+	case "18":
+		return reporters[:18]
+	default:
+		msg.Custom(errors.New("Unknown multiplex setting, please define the plex number used in your experiment"), "error")
+	}
 
-		o.Channel1.Name = "126"
-		o.Channel2.Name = "127N"
-		o.Channel3.Name = "127C"
-		o.Channel4.Name = "128N"
-		o.Channel5.Name = "128C"
-		o.Channel6.Name = "129N"
-		o.Channel7.Name = "129C"
-		o.Channel8.Name = "130N"
-		o.Channel9.Name = "130C"
-		o.Channel10.Name = "131N"
-		o.Channel11.Name = "131C"
+	return nil
+}
 
-		o.Channel1.Mz = 126.127726
-		o.Channel2.Mz = 127.124761
-		o.Channel3.Mz = 127.131081
-		o.Channel4.Mz = 128.128116
-		o.Channel5.Mz = 128.134436
-		o.Channel6.Mz = 129.131471
-		o.Channel7.Mz = 129.137790
-		o.Channel8.Mz = 130.134825
-		o.Channel9.Mz = 130.141145
-		o.Channel10.Mz = 131.138180
-		o.Channel11.Mz = 131.144499
+// New builds a new Labelled spectra object
+func New(plex string) iso.Labels {
+	return iso.New(Reagents(plex))
+}
 
-	} else if plex == "16" {
+// tmtImpurities are typical lot impurities of the TMT reagents, as -2, -1, +1 and +2 percentages
+var tmtImpurities = iso.Impurities{
+	"126":  {0, 0, 5.0, 0},
+	"127N": {0, 0.2, 5.8, 0},
+	"127C": {0, 0.3, 4.8, 0},
+	"128N": {0, 0.3, 4.9, 0},
+	"128C": {0, 0.6, 4.2, 0},
+	"129N": {0, 0.8, 3.7, 0},
+	"129C": {0, 1.3, 3.1, 0},
+	"130N": {0, 1.2, 2.9, 0},
+	"130C": {0, 1.6, 2.6, 0},
+	"131":  {0, 2.0, 2.1, 0},
+	"131N": {0, 2.0, 2.1, 0},
+	"131C": {0, 2.4, 1.7, 0},
+}
 
-		o.Channel1.Name = "126"
-		o.Channel2.Name = "127N"
-		o.Channel3.Name = "127C"
-		o.Channel4.Name = "128N"
-		o.Channel5.Name = "128C"
-		o.Channel6.Name = "129N"
-		o.Channel7.Name = "129C"
-		o.Channel8.Name = "130N"
-		o.Channel9.Name = "130C"
-		o.Channel10.Name = "131N"
-		o.Channel11.Name = "131C"
-		o.Channel12.Name = "132N"
-		o.Channel13.Name = "132C"
-		o.Channel14.Name = "133N"
-		o.Channel15.Name = "133C"
-		o.Channel16.Name = "134N"
+// proImpurities are typical lot impurities of the TMTpro reagents, in the -2x13C, -13C-15N, -13C, -15N, +15N,
+// +13C, +15N+13C and +2x13C columns of the TMTpro sheets. The 15N columns change from lot to lot and are left empty
+var proImpurities = iso.Impurities{
+	"126":  {0, 0, 0, 0, 0, 7.6, 0, 0.2},
+	"127N": {0, 0, 0.5, 0, 0, 7.2, 0, 0.2},
+	"127C": {0, 0, 0.7, 0, 0, 6.9, 0, 0.1},
+	"128N": {0, 0, 0.8, 0, 0, 6.7, 0, 0.1},
+	"128C": {0, 0, 1.3, 0, 0, 6.3, 0, 0.1},
+	"129N": {0, 0, 1.5, 0, 0, 6.1, 0, 0.1},
+	"129C": {0, 0, 2.0, 0, 0, 5.6, 0, 0.1},
+	"130N": {0, 0, 2.2, 0, 0, 5.3, 0, 0.1},
+	"130C": {0, 0, 2.7, 0, 0, 4.9, 0, 0.1},
+	"131N": {0.1, 0, 2.9, 0, 0, 4.6, 0, 0},
+	"131C": {0.1, 0, 3.3, 0, 0, 4.2, 0, 0},
+	"132N": {0.1, 0, 3.6, 0, 0, 4.0, 0, 0},
+	"132C": {0.1, 0, 4.0, 0, 0, 3.5, 0, 0},
+	"133N": {0.1, 0, 4.2, 0, 0, 3.3, 0, 0},
+	"133C": {0.2, 0, 4.8, 0, 0, 2.9, 0, 0},
+	"134N": {0.2, 0, 5.1, 0, 0, 2.6, 0, 0},
+	"134C": {0.2, 0, 5.5, 0, 0, 2.2, 0, 0},
+	"135N": {0.2, 0, 5.8, 0, 0, 1.9, 0, 0},
+}
 
-		o.Channel1.Mz = 126.127726
-		o.Channel2.Mz = 127.124761
-		o.Channel3.Mz = 127.131081
-		o.Channel4.Mz = 128.128116
-		o.Channel5.Mz = 128.134436
-		o.Channel6.Mz = 129.131471
-		o.Channel7.Mz = 129.137790
-		o.Channel8.Mz = 130.134825
-		o.Channel9.Mz = 130.141145
-		o.Channel10.Mz = 131.138180
-		o.Channel11.Mz = 131.144499
-		o.Channel12.Mz = 132.141535
-		o.Channel13.Mz = 132.147855
-		o.Channel14.Mz = 133.144890
-		o.Channel15.Mz = 133.151210
-		o.Channel16.Mz = 134.148245
+// Impurities returns the default isotopic impurities of the given plex, the lot sheet should be used
+// for an accurate correction
+func Impurities(plex string) iso.Impurities {
 
-	} else {
-		msg.Custom(errors.New("Unknown multiplex setting, please define the plex number used in your experiment"), "error")
+	switch plex {
+	case "6", "10", "11":
+		return tmtImpurities
+	case "16", "18":
+		return proImpurities
 	}
 
-	return o
+	return nil
 }
@@ -6,46 +6,69 @@ import (
 	"philosopher/lib/msg"
 )
 
+// Reagents returns the reporter ions of the given plex
+func Reagents(plex string) []iso.Reagent {
+
+	if plex == "4" {
+
+		return []iso.Reagent{
+			{Name: "114", Mz: 114.1112},
+			{Name: "115", Mz: 115.1083},
+			{Name: "116", Mz: 116.1116},
+			{Name: "117", Mz: 117.1150},
+		}
+
+	} else if plex == "8" {
+
+		return []iso.Reagent{
+			{Name: "113", Mz: 113.1078},
+			{Name: "114", Mz: 114.1112},
+			{Name: "115", Mz: 115.1082},
+			{Name: "116", Mz: 116.1116},
+			{Name: "117", Mz: 117.1149},
+			{Name: "118", Mz: 118.1120},
+			{Name: "119", Mz: 119.1153},
+			{Name: "121", Mz: 121.1220},
+		}
+
+	}
+
+	msg.Custom(errors.New("Unknown multiplex setting, please define the plex number used in your experiment"), "error")
+
+	return nil
+}
+
 // New builds a new Labelled spectra object
 func New(plex string) iso.Labels {
+	return iso.New(Reagents(plex))
+}
 
-	var o iso.Labels
+// Impurities returns the default isotopic impurities of the given plex, as -2, -1, +1 and +2 percentages
+func Impurities(plex string) iso.Impurities {
 
 	if plex == "4" {
 
-		o.Channel1.Name = "114"
-		o.Channel2.Name = "115"
-		o.Channel3.Name = "116"
-		o.Channel4.Name = "117"
-
-		o.Channel1.Mz = 114.1112
-		o.Channel2.Mz = 115.1083
-		o.Channel3.Mz = 116.1116
-		o.Channel4.Mz = 117.1150
+		return iso.Impurities{
+			"114": {0, 1.0, 5.9, 0.2},
+			"115": {0, 2.0, 5.6, 0.1},
+			"116": {0, 3.0, 4.5, 0.1},
+			"117": {0.1, 4.0, 3.5, 0.1},
+		}
 
 	} else if plex == "8" {
 
-		o.Channel1.Name = "113"
-		o.Channel2.Name = "114"
-		o.Channel3.Name = "115"
-		o.Channel4.Name = "116"
-		o.Channel5.Name = "117"
-		o.Channel6.Name = "118"
-		o.Channel7.Name = "119"
-		o.Channel8.Name = "121"
-
-		o.Channel1.Mz = 113.1078
-		o.Channel2.Mz = 114.1112
-		o.Channel3.Mz = 115.1082
-		o.Channel4.Mz = 116.1116
-		o.Channel5.Mz = 117.1149
-		o.Channel6.Mz = 118.1120
-		o.Channel7.Mz = 119.1153
-		o.Channel8.Mz = 121.1220
-
-	} else {
-		msg.Custom(errors.New("Unknown multiplex setting, please define the plex number used in your experiment"), "error")
+		return iso.Impurities{
+			"113": {0, 0, 6.89, 0.22},
+			"114": {0, 0.94, 5.90, 0.16},
+			"115": {0, 1.88, 4.90, 0.10},
+			"116": {0, 2.82, 3.90, 0.07},
+			"117": {0.06, 3.77, 2.88, 0},
+			"118": {0.09, 4.71, 1.88, 0},
+			"119": {0.14, 5.66, 0.87, 0},
+			"121": {0.27, 7.44, 0.18, 0},
+		}
+
 	}
 
-	return o
+	return nil
 }
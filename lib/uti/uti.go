@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"philosopher/lib/msg"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -45,6 +46,25 @@ func toFixedRound(num float64) int {
 	return int(num + math.Copysign(0.05, num))
 }
 
+// Median returns the median of the values
+func Median(values []float64) float64 {
+
+	if len(values) == 0 {
+		return 0
+	}
+
+	var sorted = make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
 // ParseFloat converts scientific notation values from string format to float64
 func ParseFloat(str string) (float64, error) {
 
@@ -114,6 +134,33 @@ func GetLabelNames(annot string) map[string]string {
 	return labels
 }
 
+// GetLabelRoles reads the optional third column of the annotation file, designating the carrier, empty and
+// reference channels
+func GetLabelRoles(annot string) map[string]string {
+
+	var roles = make(map[string]string)
+
+	file, e := os.Open(annot)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		names := strings.Fields(scanner.Text())
+		if len(names) > 2 {
+			roles[names[0]] = strings.ToLower(names[2])
+		}
+	}
+
+	if e = scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return roles
+}
+
 // FindFile locates a file based on a name pattern
 func FindFile(targetDir string, pattern string) string {
 
@@ -136,7 +136,7 @@ func Backup() {
 	return
 }
 
-// Clean deletes all meta data and the workspace directory
+// Clean deletes all meta data, the spectra cache included, and the workspace directory
 func Clean() {
 
 	// this is a soft verification just to see if there is any existing file